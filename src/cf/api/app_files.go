@@ -2,12 +2,24 @@ package api
 
 import (
 	"cf/configuration"
+	"cf/errors"
+	"cf/models"
 	"cf/net"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
 )
 
+// PartialDownloadSuffix is added to the name of a file while it is being
+// downloaded.
+const PartialDownloadSuffix = ".part"
+
 type AppFilesRepository interface {
 	ListFiles(appGuid, path string) (files string, apiErr error)
+	ListInstanceDir(appGuid string, instance int, dir string) (entries []models.AppInstanceFile, apiErr error)
+	DownloadFile(appGuid string, instance int, path, localPath string, resume bool) (written int64, resumed bool, apiErr error)
 }
 
 type CloudControllerAppFilesRepository struct {
@@ -31,3 +43,117 @@ func (repo CloudControllerAppFilesRepository) ListFiles(appGuid, path string) (f
 	files, _, apiErr = repo.gateway.PerformRequestForTextResponse(request)
 	return
 }
+
+// ListInstanceDir lists the files and directories in dir of an app instance.
+func (repo CloudControllerAppFilesRepository) ListInstanceDir(appGuid string, instance int, dir string) (entries []models.AppInstanceFile, apiErr error) {
+	request, apiErr := repo.gateway.NewRequest("GET", repo.instanceFileUrl(appGuid, instance, dir), repo.config.AccessToken(), nil)
+	if apiErr != nil {
+		return
+	}
+
+	list, _, apiErr := repo.gateway.PerformRequestForTextResponse(request)
+	if apiErr != nil {
+		return
+	}
+
+	entries = parseInstanceDirListing(list)
+	return
+}
+
+// DownloadFile writes the file at path in an app instance to localPath, and
+// returns how many bytes were written. The file is written to localPath with
+// PartialDownloadSuffix, and only renamed to localPath once it is complete,
+// so that a download that was cut off is never taken for a complete file.
+// With resume, an existing partial file is continued from its size. resumed
+// is only true when the server sent the rest of the file rather than all of
+// it, which it does not when a redirect drops the Range header.
+func (repo CloudControllerAppFilesRepository) DownloadFile(appGuid string, instance int, path, localPath string, resume bool) (written int64, resumed bool, apiErr error) {
+	request, apiErr := repo.gateway.NewRequest("GET", repo.instanceFileUrl(appGuid, instance, path), repo.config.AccessToken(), nil)
+	if apiErr != nil {
+		return
+	}
+
+	partPath := localPath + PartialDownloadSuffix
+
+	var offset int64
+	if resume {
+		if fileInfo, err := os.Stat(partPath); err == nil {
+			offset = fileInfo.Size()
+		}
+	}
+	if offset > 0 {
+		request.HttpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, apiErr := repo.gateway.PerformStreamingRequest(request)
+	if httpErr, ok := apiErr.(errors.HttpError); ok && offset > 0 && httpErr.StatusCode() == http.StatusRequestedRangeNotSatisfiable {
+		resumed = true
+		apiErr = completeDownload(partPath, localPath)
+		return
+	}
+	if apiErr != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 && response.StatusCode == http.StatusPartialContent {
+		flags = os.O_WRONLY | os.O_APPEND
+		resumed = true
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		apiErr = errors.NewWithError("Error creating "+partPath, err)
+		return
+	}
+
+	written, err = io.Copy(file, response.Body)
+	closeErr := file.Close()
+	if err != nil {
+		apiErr = errors.NewWithError("Error downloading "+path, err)
+		return
+	}
+	if closeErr != nil {
+		apiErr = errors.NewWithError("Error writing "+partPath, closeErr)
+		return
+	}
+
+	apiErr = completeDownload(partPath, localPath)
+	return
+}
+
+func completeDownload(partPath, localPath string) error {
+	err := os.Rename(partPath, localPath)
+	if err != nil {
+		return errors.NewWithError("Error renaming "+partPath, err)
+	}
+	return nil
+}
+
+func (repo CloudControllerAppFilesRepository) instanceFileUrl(appGuid string, instance int, path string) string {
+	return fmt.Sprintf("%s/v2/apps/%s/instances/%d/files/%s", repo.config.ApiEndpoint(), appGuid, instance, strings.TrimLeft(path, "/"))
+}
+
+// parseInstanceDirListing reads a listing with a line for each entry, such as
+// "app.jar    1.2M" for files and "logs/    -" for directories.
+func parseInstanceDirListing(list string) (entries []models.AppInstanceFile) {
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(line)
+		if len(fields) > 1 {
+			name = strings.TrimSpace(strings.TrimSuffix(name, fields[len(fields)-1]))
+		}
+
+		entry := models.AppInstanceFile{Name: strings.TrimSuffix(name, "/"), IsDir: strings.HasSuffix(name, "/")}
+		if entry.Name == "" || entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
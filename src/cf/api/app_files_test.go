@@ -1,16 +1,23 @@
 package api_test
 
 import (
+	"bytes"
 	. "cf/api"
+	"cf/models"
 	"cf/net"
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	testapi "testhelpers/api"
 	testconfig "testhelpers/configuration"
 	testnet "testhelpers/net"
+	"time"
 )
 
 var _ = Describe("AppFilesRepository", func() {
@@ -61,4 +68,156 @@ var _ = Describe("AppFilesRepository", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(list).To(Equal(expectedResponse))
 	})
+
+	Describe("downloading files from an instance", func() {
+		var (
+			fileServer     *httptest.Server
+			redirectServer *httptest.Server
+			handler        *testnet.TestHandler
+			repo           AppFilesRepository
+			localDir       string
+			contents       []byte
+		)
+
+		BeforeEach(func() {
+			contents = []byte{0, 1, 2, 255, 254, 13, 10, 'h', 'e', 'a', 'p'}
+			fileServer = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				http.ServeContent(writer, request, "heap.hprof", time.Time{}, bytes.NewReader(contents))
+			}))
+
+			var err error
+			localDir, err = ioutil.TempDir("", "download-files")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			fileServer.Close()
+			redirectServer.Close()
+			os.RemoveAll(localDir)
+		})
+
+		setupRepo := func(requests ...testnet.TestRequest) {
+			redirectServer, handler = testnet.NewServer(requests)
+
+			configRepo := testconfig.NewRepositoryWithDefaults()
+			configRepo.SetApiEndpoint(redirectServer.URL)
+			repo = NewCloudControllerAppFilesRepository(configRepo, net.NewCloudControllerGateway(configRepo))
+		}
+
+		redirectToFile := func(rangeHeader string) testnet.TestRequest {
+			return testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+				Method: "GET",
+				Path:   "/v2/apps/my-app-guid/instances/2/files/app/heap.hprof",
+				Matcher: func(request *http.Request) {
+					Expect(request.Header.Get("Range")).To(Equal(rangeHeader))
+				},
+				Response: testnet.TestResponse{
+					Status: http.StatusTemporaryRedirect,
+					Header: http.Header{"Location": {fileServer.URL + "/app/heap.hprof"}},
+				},
+			})
+		}
+
+		It("lists the files and directories in a directory", func() {
+			setupRepo(testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+				Method: "GET",
+				Path:   "/v2/apps/my-app-guid/instances/2/files/app",
+				Response: testnet.TestResponse{
+					Status: http.StatusOK,
+					Body:   "heap.hprof                              1.2M\nlogs/                                      -\nmy notes.txt                             12B\n\n",
+				},
+			}))
+
+			entries, err := repo.ListInstanceDir("my-app-guid", 2, "/app")
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]models.AppInstanceFile{
+				{Name: "heap.hprof"},
+				{Name: "logs", IsDir: true},
+				{Name: "my notes.txt"},
+			}))
+		})
+
+		It("writes binary files exactly as they are", func() {
+			setupRepo(redirectToFile(""))
+			localPath := filepath.Join(localDir, "heap.hprof")
+
+			written, resumed, err := repo.DownloadFile("my-app-guid", 2, "/app/heap.hprof", localPath, false)
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(Equal(int64(len(contents))))
+			Expect(resumed).To(BeFalse())
+			Expect(ioutil.ReadFile(localPath)).To(Equal(contents))
+
+			_, err = os.Stat(localPath + ".part")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("leaves a file that was cut off under its partial name", func() {
+			fileServer.Close()
+			fileServer = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+				writer.Write(contents[:4])
+			}))
+			setupRepo(redirectToFile(""))
+			localPath := filepath.Join(localDir, "heap.hprof")
+
+			_, _, err := repo.DownloadFile("my-app-guid", 2, "app/heap.hprof", localPath, false)
+
+			Expect(err).To(HaveOccurred())
+			_, err = os.Stat(localPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(ioutil.ReadFile(localPath + ".part")).To(Equal(contents[:4]))
+		})
+
+		It("continues a partly downloaded file from where it stopped", func() {
+			setupRepo(redirectToFile("bytes=4-"))
+			localPath := filepath.Join(localDir, "heap.hprof")
+			err := ioutil.WriteFile(localPath+".part", contents[:4], 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			written, resumed, err := repo.DownloadFile("my-app-guid", 2, "app/heap.hprof", localPath, true)
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(Equal(int64(len(contents) - 4)))
+			Expect(resumed).To(BeTrue())
+			Expect(ioutil.ReadFile(localPath)).To(Equal(contents))
+		})
+
+		It("downloads all of the file again when the server sends all of it", func() {
+			fileServer.Close()
+			fileServer = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Write(contents)
+			}))
+			setupRepo(redirectToFile("bytes=4-"))
+			localPath := filepath.Join(localDir, "heap.hprof")
+			err := ioutil.WriteFile(localPath+".part", contents[:4], 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			written, resumed, err := repo.DownloadFile("my-app-guid", 2, "app/heap.hprof", localPath, true)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(Equal(int64(len(contents))))
+			Expect(resumed).To(BeFalse())
+			Expect(ioutil.ReadFile(localPath)).To(Equal(contents))
+		})
+
+		It("completes a partly downloaded file that has all of its contents", func() {
+			setupRepo(redirectToFile(fmt.Sprintf("bytes=%d-", len(contents))))
+			localPath := filepath.Join(localDir, "heap.hprof")
+			err := ioutil.WriteFile(localPath+".part", contents, 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			written, resumed, err := repo.DownloadFile("my-app-guid", 2, "app/heap.hprof", localPath, true)
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(BeZero())
+			Expect(resumed).To(BeTrue())
+			Expect(ioutil.ReadFile(localPath)).To(Equal(contents))
+		})
+	})
 })
@@ -33,6 +33,7 @@ type InstanceStatsApiResponse struct {
 
 type AppInstancesRepository interface {
 	GetInstances(appGuid string) (instances []models.AppInstanceFields, apiErr error)
+	DeleteInstance(appGuid string, index int) (apiErr error)
 }
 
 type CloudControllerAppInstancesRepository struct {
@@ -71,6 +72,12 @@ func (repo CloudControllerAppInstancesRepository) GetInstances(appGuid string) (
 	return repo.updateInstancesWithStats(appGuid, instances)
 }
 
+// DeleteInstance stops one instance of an app, which the platform then
+// replaces with a new one.
+func (repo CloudControllerAppInstancesRepository) DeleteInstance(appGuid string, index int) (apiErr error) {
+	return repo.gateway.DeleteResource(fmt.Sprintf("%s/v2/apps/%s/instances/%d", repo.config.ApiEndpoint(), appGuid, index))
+}
+
 func (repo CloudControllerAppInstancesRepository) updateInstancesWithStats(guid string, instances []models.AppInstanceFields) (updatedInst []models.AppInstanceFields, apiErr error) {
 	path := fmt.Sprintf("%s/v2/apps/%s/stats", repo.config.ApiEndpoint(), guid)
 	statsResponse := StatsApiResponse{}
@@ -40,6 +40,21 @@ var _ = Describe("AppInstancesRepo", func() {
 		Expect(instance0.MemUsage).To(Equal(uint64(19218432)))
 		Expect(instance0.CpuUsage).To(Equal(3.659571249238058e-05))
 	})
+
+	It("deletes an instance of the app, given a guid and index", func() {
+		ts, handler, repo := createAppInstancesRepo([]testnet.TestRequest{
+			testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+				Method:   "DELETE",
+				Path:     "/v2/apps/my-cool-app-guid/instances/1",
+				Response: testnet.TestResponse{Status: http.StatusNoContent},
+			}),
+		})
+		defer ts.Close()
+
+		err := repo.DeleteInstance("my-cool-app-guid", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+	})
 })
 
 var appStatsRequest = testapi.NewCloudControllerTestRequest(testnet.TestRequest{
@@ -20,16 +20,23 @@ func (resource ApplicationSummaries) ToModels() (apps []models.ApplicationFields
 }
 
 type ApplicationFromSummary struct {
-	Guid             string
-	Name             string
-	Routes           []RouteSummary
-	RunningInstances int `json:"running_instances"`
-	Memory           uint64
-	Instances        int
-	DiskQuota        uint64 `json:"disk_quota"`
-	Urls             []string
-	State            string
-	SpaceGuid        string `json:"space_guid"`
+	Guid               string
+	Name               string
+	Routes             []RouteSummary
+	Services           []ServiceInstanceSummary
+	RunningInstances   int `json:"running_instances"`
+	Memory             uint64
+	Instances          int
+	DiskQuota          uint64 `json:"disk_quota"`
+	Urls               []string
+	State              string
+	SpaceGuid          string `json:"space_guid"`
+	StackGuid          string `json:"stack_guid"`
+	Buildpack          string
+	Command            string
+	EnvironmentJson    map[string]string `json:"environment_json"`
+	HealthCheckType    string            `json:"health_check_type"`
+	HealthCheckTimeout int               `json:"health_check_timeout"`
 }
 
 func (resource ApplicationFromSummary) ToFields() (app models.ApplicationFields) {
@@ -42,6 +49,11 @@ func (resource ApplicationFromSummary) ToFields() (app models.ApplicationFields)
 	app.RunningInstances = resource.RunningInstances
 	app.Memory = resource.Memory
 	app.SpaceGuid = resource.SpaceGuid
+	app.BuildpackUrl = resource.Buildpack
+	app.Command = resource.Command
+	app.EnvironmentVars = resource.EnvironmentJson
+	app.HealthCheckType = resource.HealthCheckType
+	app.HealthCheckTimeout = resource.HealthCheckTimeout
 
 	return
 }
@@ -54,6 +66,16 @@ func (resource ApplicationFromSummary) ToModel() (app models.Application) {
 	}
 	app.Routes = routes
 
+	services := []models.ServiceInstanceFields{}
+	for _, service := range resource.Services {
+		services = append(services, service.ToFields())
+	}
+	app.Services = services
+
+	if resource.StackGuid != "" {
+		app.Stack = &models.Stack{Guid: resource.StackGuid}
+	}
+
 	return
 }
 
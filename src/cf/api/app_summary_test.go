@@ -27,6 +27,7 @@ package api_test
 
 import (
 	. "cf/api"
+	"cf/models"
 	"cf/net"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -77,8 +78,68 @@ var _ = Describe("AppSummaryRepository", func() {
 		Expect(app2.RunningInstances).To(Equal(1))
 		Expect(app2.Memory).To(Equal(uint64(512)))
 	})
+
+	It("gets the summary of a single app", func() {
+		getAppSummaryRequest := testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+			Method:   "GET",
+			Path:     "/v2/apps/app-1-guid/summary",
+			Response: testnet.TestResponse{Status: http.StatusOK, Body: getAppSummaryResponseBody},
+		})
+
+		ts, handler, repo := createAppSummaryRepo([]testnet.TestRequest{getAppSummaryRequest})
+		defer ts.Close()
+
+		app, apiErr := repo.GetSummary("app-1-guid")
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+		Expect(apiErr).NotTo(HaveOccurred())
+
+		Expect(app.Name).To(Equal("app1"))
+		Expect(app.Memory).To(Equal(uint64(128)))
+		Expect(app.DiskQuota).To(Equal(uint64(1024)))
+		Expect(app.InstanceCount).To(Equal(2))
+		Expect(app.BuildpackUrl).To(Equal("ruby_buildpack"))
+		Expect(app.Command).To(Equal("bundle exec rackup"))
+		Expect(app.EnvironmentVars).To(Equal(map[string]string{"RACK_ENV": "production"}))
+		Expect(app.Stack.Guid).To(Equal("stack-guid"))
+		Expect(app.Routes[0].URL()).To(Equal("app1.cfapps.io"))
+		Expect(app.Services).To(Equal([]models.ServiceInstanceFields{
+			{Guid: "service-instance-guid", Name: "my-service-instance"},
+		}))
+	})
 })
 
+var getAppSummaryResponseBody = `
+{
+  "guid":"app-1-guid",
+  "name":"app1",
+  "routes":[
+    {
+      "guid":"route-1-guid",
+      "host":"app1",
+      "domain":{
+        "guid":"domain-1-guid",
+        "name":"cfapps.io"
+      }
+    }
+  ],
+  "services":[
+    {
+      "guid":"service-instance-guid",
+      "name":"my-service-instance",
+      "service_plan":{"guid":"plan-guid", "name":"small"}
+    }
+  ],
+  "running_instances":2,
+  "memory":128,
+  "disk_quota":1024,
+  "instances":2,
+  "state":"STARTED",
+  "stack_guid":"stack-guid",
+  "buildpack":"ruby_buildpack",
+  "command":"bundle exec rackup",
+  "environment_json":{"RACK_ENV":"production"}
+}`
+
 var getAppSummariesResponseBody = `
 {
   "apps":[
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
 	"cf/api/resources"
@@ -9,39 +10,100 @@ import (
 	"cf/errors"
 	"cf/models"
 	"cf/net"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry/gofileutils/fileutils"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/textproto"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 )
 
 const (
-	DefaultAppUploadBitsTimeout = 15 * time.Minute
+	DefaultAppUploadBitsTimeout  = 15 * time.Minute
+	DefaultAppUploadRetries      = 3
+	DefaultAppUploadRetryBackoff = 2 * time.Second
 )
 
+type UploadOptions struct {
+	// Retries is how many more times an upload that fails with a network or
+	// server error is attempted.
+	Retries int
+	// Resume uploads the artifact saved by the last failed upload of the app
+	// instead of matching and zipping the app files again, unless the files
+	// have changed since.
+	Resume bool
+	// Progress is told how much of the upload has been sent.
+	Progress net.ProgressCallback
+	// Ignore changes which of the app files are left out.
+	Ignore app_files.IgnoreOptions
+}
+
+type MatchOptions struct {
+	// Ignore changes which of the app files are left out.
+	Ignore app_files.IgnoreOptions
+	// NoSave leaves the fingerprint cache on disk as it was, for dry runs.
+	NoSave bool
+	// AppGuid is the app the files are matched for, whose fingerprint cache
+	// is used. It is empty for apps that do not exist yet.
+	AppGuid string
+}
+
 type ApplicationBitsRepository interface {
-	UploadApp(appGuid, dir string, cb func(path string, zipSize, fileCount uint64)) (apiErr error)
+	UploadApp(appGuid, dir string, opts UploadOptions, cb func(path string, zipSize, fileCount uint64)) (apiErr error)
+	MatchFiles(dir string, opts MatchOptions) (appFilesToUpload []models.AppFileFields, apiErr error)
+	IgnoredFiles(dir string, ignore app_files.IgnoreOptions) (ignoredFiles []app_files.IgnoredFile, apiErr error)
+	CopyBits(sourceAppGuid, targetAppGuid string) (apiErr error)
 }
 
 type CloudControllerApplicationBitsRepository struct {
 	config  configuration.Reader
 	gateway net.Gateway
 	zipper  app_files.Zipper
+
+	ArtifactsDir        string
+	FingerprintCacheDir string
+	RetryBackoff        time.Duration
 }
 
 func NewCloudControllerApplicationBitsRepository(config configuration.Reader, gateway net.Gateway, zipper app_files.Zipper) (repo CloudControllerApplicationBitsRepository) {
 	repo.config = config
 	repo.gateway = gateway
 	repo.zipper = zipper
+	repo.ArtifactsDir = configuration.DefaultUploadArtifactsDir()
+	repo.FingerprintCacheDir = configuration.DefaultFingerprintCacheDir()
+	repo.RetryBackoff = DefaultAppUploadRetryBackoff
+	return
+}
+
+func (repo CloudControllerApplicationBitsRepository) UploadApp(appGuid string, appDir string, opts UploadOptions, fileSizePrinter func(path string, zipSize, fileCount uint64)) (apiErr error) {
+	if opts.Resume && repo.hasArtifact(appGuid, appDir, opts.Ignore) {
+		return repo.resumeUpload(appGuid, appDir, opts, fileSizePrinter)
+	}
+
+	cache := repo.fingerprintCache(appGuid, appDir)
+
+	staleCache, apiErr := repo.matchZipAndUpload(appGuid, appDir, cache, opts, fileSizePrinter)
+	if staleCache {
+		// The server may have cleaned up files that it was cached to have, in
+		// which case uploading without them fails however often it is retried.
+		// The cache is forgotten by now, so all of the files are matched again.
+		_, apiErr = repo.matchZipAndUpload(appGuid, appDir, cache, opts, fileSizePrinter)
+	}
 	return
 }
 
-func (repo CloudControllerApplicationBitsRepository) UploadApp(appGuid string, appDir string, fileSizePrinter func(path string, zipSize, fileCount uint64)) (apiErr error) {
+// matchZipAndUpload zips the app files that the server does not have and
+// uploads them. When the upload fails, staleCache is true if files were left
+// out because the cache said that the server has them; otherwise the zip is
+// saved for --resume.
+func (repo CloudControllerApplicationBitsRepository) matchZipAndUpload(appGuid, appDir string, cache *app_files.FingerprintCache, opts UploadOptions, fileSizePrinter func(path string, zipSize, fileCount uint64)) (staleCache bool, apiErr error) {
 	fileutils.TempDir("apps", func(uploadDir string, err error) {
 		if err != nil {
 			apiErr = err
@@ -49,12 +111,14 @@ func (repo CloudControllerApplicationBitsRepository) UploadApp(appGuid string, a
 		}
 
 		var presentFiles []resources.AppFileResource
+		var presentFromCache bool
+		var fingerprint string
 		repo.sourceDir(appDir, func(sourceDir string, sourceErr error) {
 			if sourceErr != nil {
 				err = sourceErr
 				return
 			}
-			presentFiles, err = repo.copyUploadableFiles(sourceDir, uploadDir)
+			presentFiles, presentFromCache, fingerprint, err = repo.copyUploadableFiles(sourceDir, uploadDir, cache, opts.Ignore)
 		})
 
 		if err != nil {
@@ -91,16 +155,251 @@ func (repo CloudControllerApplicationBitsRepository) UploadApp(appGuid string, a
 
 			fileSizePrinter(appDir, zipFileSize, zipFileCount)
 
-			apiErr = repo.uploadBits(appGuid, zipFile, presentFiles)
+			apiErr = repo.uploadBits(appGuid, zipFile, presentFiles, opts)
 			if apiErr != nil {
+				cache.ForgetServerFiles()
+				cache.Save()
+
+				if presentFromCache {
+					staleCache = true
+					return
+				}
+
+				if saveErr := repo.saveArtifact(appGuid, fingerprint, zipFile, presentFiles); saveErr != nil {
+					apiErr = errors.NewWithFmt("%s\n%s", apiErr.Error(), saveErr.Error())
+				}
 				return
 			}
+
+			repo.removeArtifact(appGuid)
+			cache.Save()
 		})
 	})
 	return
 }
 
-func (repo CloudControllerApplicationBitsRepository) uploadBits(appGuid string, zipFile *os.File, presentFiles []resources.AppFileResource) (apiErr error) {
+// resumeUpload uploads the zip and resource list saved when the last upload
+// of the app failed, so that the files do not have to be matched and zipped
+// again.
+func (repo CloudControllerApplicationBitsRepository) resumeUpload(appGuid, appDir string, opts UploadOptions, fileSizePrinter func(path string, zipSize, fileCount uint64)) (apiErr error) {
+	artifactDir := repo.artifactDir(appGuid)
+
+	resourcesJSON, err := ioutil.ReadFile(filepath.Join(artifactDir, "resources.json"))
+	if err != nil {
+		return errors.NewWithError("Error reading saved upload", err)
+	}
+
+	presentFiles := []resources.AppFileResource{}
+	err = json.Unmarshal(resourcesJSON, &presentFiles)
+	if err != nil {
+		return errors.NewWithError("Error reading saved upload", err)
+	}
+
+	zipPath := filepath.Join(artifactDir, "application.zip")
+	zipFile, err := os.Open(zipPath)
+	if os.IsNotExist(err) {
+		fileSizePrinter(appDir, 0, 0)
+		apiErr = repo.uploadBits(appGuid, nil, presentFiles, opts)
+	} else if err != nil {
+		return errors.NewWithError("Error reading saved upload", err)
+	} else {
+		defer zipFile.Close()
+
+		zipFileSize, zipFileCount, err := zipFileStats(zipPath)
+		if err != nil {
+			return errors.NewWithError("Error reading saved upload", err)
+		}
+
+		fileSizePrinter(appDir, zipFileSize, zipFileCount)
+		apiErr = repo.uploadBits(appGuid, zipFile, presentFiles, opts)
+	}
+
+	if apiErr == nil {
+		repo.removeArtifact(appGuid)
+	}
+	return
+}
+
+func zipFileStats(path string) (size, fileCount uint64, err error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() {
+			fileCount++
+		}
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	size = uint64(stat.Size())
+	return
+}
+
+func (repo CloudControllerApplicationBitsRepository) artifactDir(appGuid string) string {
+	return filepath.Join(repo.ArtifactsDir, appGuid)
+}
+
+// hasArtifact is true when an upload of the app was saved for --resume, and
+// the files in appDir are still the ones it was saved for. Saved uploads of
+// other files are removed, so that they are never uploaded in their place.
+func (repo CloudControllerApplicationBitsRepository) hasArtifact(appGuid, appDir string, ignore app_files.IgnoreOptions) bool {
+	artifactDir := repo.artifactDir(appGuid)
+	if _, err := os.Stat(filepath.Join(artifactDir, "resources.json")); err != nil {
+		return false
+	}
+
+	savedFingerprint, err := ioutil.ReadFile(filepath.Join(artifactDir, "fingerprint"))
+	if err == nil {
+		var fingerprint string
+		fingerprint, err = repo.contentsFingerprint(appGuid, appDir, ignore)
+		if err == nil && fingerprint == string(savedFingerprint) {
+			return true
+		}
+	}
+
+	repo.removeArtifact(appGuid)
+	return false
+}
+
+// contentsFingerprint sums up the paths, sizes and SHA1s of the app files in
+// appDir.
+func (repo CloudControllerApplicationBitsRepository) contentsFingerprint(appGuid, appDir string, ignore app_files.IgnoreOptions) (fingerprint string, err error) {
+	repo.sourceDir(appDir, func(sourceDir string, sourceErr error) {
+		if sourceErr != nil {
+			err = sourceErr
+			return
+		}
+
+		var appFiles []models.AppFileFields
+		appFiles, err = app_files.AppFilesInDirWithCache(sourceDir, repo.fingerprintCache(appGuid, appDir), ignore)
+		fingerprint = appFilesFingerprint(appFiles)
+	})
+	return
+}
+
+func appFilesFingerprint(appFiles []models.AppFileFields) string {
+	hash := sha1.New()
+	for _, file := range appFiles {
+		fmt.Fprintf(hash, "%s\x00%d\x00%s\n", file.Path, file.Size, file.Sha1)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func (repo CloudControllerApplicationBitsRepository) saveArtifact(appGuid, fingerprint string, zipFile *os.File, presentFiles []resources.AppFileResource) (err error) {
+	artifactDir := repo.artifactDir(appGuid)
+	os.RemoveAll(artifactDir)
+
+	err = os.MkdirAll(artifactDir, os.ModeDir|os.ModeTemporary|os.ModePerm)
+	if err != nil {
+		return errors.NewWithError("Error saving upload for --resume", err)
+	}
+
+	if zipFile != nil {
+		_, err = zipFile.Seek(0, 0)
+		if err == nil {
+			err = fileutils.CopyReaderToPath(zipFile, filepath.Join(artifactDir, "application.zip"))
+		}
+		if err != nil {
+			return errors.NewWithError("Error saving upload for --resume", err)
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(artifactDir, "fingerprint"), []byte(fingerprint), 0600)
+	if err != nil {
+		return errors.NewWithError("Error saving upload for --resume", err)
+	}
+
+	presentFilesJSON, err := json.Marshal(presentFiles)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(artifactDir, "resources.json"), presentFilesJSON, 0600)
+	}
+	if err != nil {
+		return errors.NewWithError("Error saving upload for --resume", err)
+	}
+	return
+}
+
+func (repo CloudControllerApplicationBitsRepository) removeArtifact(appGuid string) {
+	os.RemoveAll(repo.artifactDir(appGuid))
+}
+
+func (repo CloudControllerApplicationBitsRepository) MatchFiles(appDir string, opts MatchOptions) (appFilesToUpload []models.AppFileFields, apiErr error) {
+	repo.sourceDir(appDir, func(sourceDir string, err error) {
+		if err != nil {
+			apiErr = err
+			return
+		}
+
+		cache := repo.fingerprintCache(opts.AppGuid, appDir)
+
+		allAppFiles, err := app_files.AppFilesInDirWithCache(sourceDir, cache, opts.Ignore)
+		if err != nil {
+			apiErr = err
+			return
+		}
+
+		appFilesToUpload, _, _, apiErr = repo.getFilesToUpload(allAppFiles, cache)
+		if apiErr == nil && !opts.NoSave {
+			cache.Save()
+		}
+	})
+	return
+}
+
+func (repo CloudControllerApplicationBitsRepository) IgnoredFiles(appDir string, ignore app_files.IgnoreOptions) (ignoredFiles []app_files.IgnoredFile, apiErr error) {
+	repo.sourceDir(appDir, func(sourceDir string, err error) {
+		if err != nil {
+			apiErr = err
+			return
+		}
+
+		ignoredFiles, apiErr = app_files.IgnoredAppFiles(sourceDir, ignore)
+	})
+	return
+}
+
+// CopyBits replaces the package of the target app with the package of the
+// source app and waits for the cloud controller to finish copying it.
+func (repo CloudControllerApplicationBitsRepository) CopyBits(sourceAppGuid, targetAppGuid string) (apiErr error) {
+	url := fmt.Sprintf("%s/v2/apps/%s/copy_bits", repo.config.ApiEndpoint(), targetAppGuid)
+	body := fmt.Sprintf(`{"source_app_guid":"%s"}`, sourceAppGuid)
+
+	request, apiErr := repo.gateway.NewRequest("POST", url, repo.config.AccessToken(), strings.NewReader(body))
+	if apiErr != nil {
+		return
+	}
+
+	response := &resources.Resource{}
+	_, apiErr = repo.gateway.PerformPollingRequestForJSONResponse(request, response, DefaultAppUploadBitsTimeout)
+	return
+}
+
+// fingerprintCache loads the fingerprints saved by the last push of the app
+// in appDir to the targeted API. Archives are extracted to a new directory
+// every time, so their files are not cached. Every app has a cache of its
+// own, so that apps pushed from the same directory at the same time do not
+// save over each other's cache.
+func (repo CloudControllerApplicationBitsRepository) fingerprintCache(appGuid, appDir string) *app_files.FingerprintCache {
+	if repo.FingerprintCacheDir == "" || repo.zipper.IsZipFile(appDir) || repo.zipper.IsTarFile(appDir) {
+		return nil
+	}
+
+	absDir, err := filepath.Abs(appDir)
+	if err != nil {
+		return nil
+	}
+
+	key := sha1.Sum([]byte(repo.config.ApiEndpoint() + "\n" + appGuid + "\n" + absDir))
+	return app_files.LoadFingerprintCache(filepath.Join(repo.FingerprintCacheDir, fmt.Sprintf("%x.json", key)))
+}
+
+func (repo CloudControllerApplicationBitsRepository) uploadBits(appGuid string, zipFile *os.File, presentFiles []resources.AppFileResource, opts UploadOptions) (apiErr error) {
 	url := fmt.Sprintf("%s/v2/apps/%s/bits", repo.config.ApiEndpoint(), appGuid)
 	fileutils.TempFile("requests", func(requestFile *os.File, err error) {
 		if err != nil {
@@ -120,45 +419,76 @@ func (repo CloudControllerApplicationBitsRepository) uploadBits(appGuid string,
 			return
 		}
 
-		var request *net.Request
-		request, apiErr = repo.gateway.NewRequest("PUT", url, repo.config.AccessToken(), requestFile)
-		if apiErr != nil {
-			return
-		}
+		for attempt := 0; ; attempt++ {
+			var request *net.Request
+			request, apiErr = repo.gateway.NewRequest("PUT", url, repo.config.AccessToken(), requestFile)
+			if apiErr != nil {
+				return
+			}
 
-		contentType := fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
-		request.HttpReq.Header.Set("Content-Type", contentType)
+			contentType := fmt.Sprintf("multipart/form-data; boundary=%s", boundary)
+			request.HttpReq.Header.Set("Content-Type", contentType)
+			request.SetProgressCallback(opts.Progress)
 
-		response := &resources.Resource{}
-		_, apiErr = repo.gateway.PerformPollingRequestForJSONResponse(request, response, DefaultAppUploadBitsTimeout)
-		if apiErr != nil {
-			return
+			response := &resources.Resource{}
+			_, apiErr = repo.gateway.PerformPollingRequestForJSONResponse(request, response, DefaultAppUploadBitsTimeout)
+			if apiErr == nil || attempt >= opts.Retries || !isTransientUploadError(apiErr) {
+				return
+			}
+
+			time.Sleep(repo.RetryBackoff * time.Duration(1<<uint(attempt)))
 		}
 	})
 
 	return
 }
 
+// isTransientUploadError is true for errors that the same upload might not
+// run into again: network errors and server errors. The gateway passes on
+// connection errors as the system call errors that they wrap, and other
+// network errors as NetworkErrors.
+func isTransientUploadError(err error) bool {
+	switch err := err.(type) {
+	case errors.HttpError:
+		return err.StatusCode() >= 500
+	case *errors.NetworkError, syscall.Errno, *os.SyscallError:
+		return true
+	}
+	return false
+}
+
 func (repo CloudControllerApplicationBitsRepository) sourceDir(appDir string, cb func(sourceDir string, err error)) {
-	// If appDir is a zip, first extract it to a temporary directory
-	if repo.zipper.IsZipFile(appDir) {
+	// If appDir is an archive, first extract it to a temporary directory
+	switch {
+	case repo.zipper.IsZipFile(appDir):
 		fileutils.TempDir("unzipped-app", func(tmpDir string, err error) {
 			err = repo.extractZip(appDir, tmpDir)
 			cb(tmpDir, err)
 		})
-	} else {
+	case repo.zipper.IsTarFile(appDir):
+		fileutils.TempDir("untarred-app", func(tmpDir string, err error) {
+			err = repo.extractTar(appDir, tmpDir)
+			cb(tmpDir, err)
+		})
+	default:
+		if fileInfo, err := os.Stat(appDir); err == nil && !fileInfo.IsDir() {
+			cb(appDir, errors.NewWithFmt("%s is not a directory or an archive in a supported format.\n"+
+				"Apps can be pushed from a directory, or a zip, jar, war, tar, tar.gz or tgz file.", appDir))
+			return
+		}
 		cb(appDir, nil)
 	}
 }
 
-func (repo CloudControllerApplicationBitsRepository) copyUploadableFiles(appDir string, uploadDir string) (presentFiles []resources.AppFileResource, err error) {
+func (repo CloudControllerApplicationBitsRepository) copyUploadableFiles(appDir string, uploadDir string, cache *app_files.FingerprintCache, ignore app_files.IgnoreOptions) (presentFiles []resources.AppFileResource, presentFromCache bool, fingerprint string, err error) {
 	// Find which files need to be uploaded
-	allAppFiles, err := app_files.AppFilesInDir(appDir)
+	allAppFiles, err := app_files.AppFilesInDirWithCache(appDir, cache, ignore)
 	if err != nil {
 		return
 	}
+	fingerprint = appFilesFingerprint(allAppFiles)
 
-	appFilesToUpload, presentFiles, apiErr := repo.getFilesToUpload(allAppFiles)
+	appFilesToUpload, presentFiles, presentFromCache, apiErr := repo.getFilesToUpload(allAppFiles, cache)
 	if apiErr != nil {
 		err = errors.New(apiErr.Error())
 		return
@@ -187,6 +517,12 @@ func (repo CloudControllerApplicationBitsRepository) extractZip(appDir, destDir
 				return
 			}
 
+			var destFilePath string
+			destFilePath, err = archiveEntryPath(appDir, destDir, f.Name)
+			if err != nil {
+				return
+			}
+
 			var rc io.ReadCloser
 			rc, err = f.Open()
 			if err != nil {
@@ -197,46 +533,207 @@ func (repo CloudControllerApplicationBitsRepository) extractZip(appDir, destDir
 			// otherwise this only closes the last file handle
 			defer rc.Close()
 
-			destFilePath := filepath.Join(destDir, f.Name)
+			if f.Mode()&os.ModeSymlink != 0 {
+				var target []byte
+				target, err = ioutil.ReadAll(rc)
+				if err != nil {
+					return
+				}
+				err = extractSymlink(string(target), destDir, destFilePath)
+				return
+			}
 
 			err = fileutils.CopyReaderToPath(rc, destFilePath)
 			if err != nil {
 				return
 			}
 
-			err = os.Chmod(destFilePath, f.FileInfo().Mode())
+			err = os.Chmod(destFilePath, f.Mode().Perm())
 			if err != nil {
 				return
 			}
 		}()
+
+		if err != nil {
+			return
+		}
 	}
 
 	return
 }
 
-func (repo CloudControllerApplicationBitsRepository) getFilesToUpload(allAppFiles []models.AppFileFields) (appFilesToUpload []models.AppFileFields, presentFiles []resources.AppFileResource, apiErr error) {
+func (repo CloudControllerApplicationBitsRepository) extractTar(appDir, destDir string) (err error) {
+	tarFile, err := os.Open(appDir)
+	if err != nil {
+		return
+	}
+	defer tarFile.Close()
+
+	reader, err := app_files.NewTarReader(tarFile)
+	if err != nil {
+		return
+	}
+
+	for {
+		var header *tar.Header
+		header, err = reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		var destFilePath string
+		destFilePath, err = archiveEntryPath(appDir, destDir, header.Name)
+		if err != nil {
+			return
+		}
+
+		mode := header.FileInfo().Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(destFilePath, os.ModeDir|os.ModePerm)
+		case mode&os.ModeSymlink != 0:
+			err = extractSymlink(header.Linkname, destDir, destFilePath)
+		case header.Typeflag == tar.TypeLink:
+			var linkedPath string
+			linkedPath, err = archiveEntryPath(appDir, destDir, header.Linkname)
+			if err == nil {
+				err = fileutils.CopyPathToPath(linkedPath, destFilePath)
+			}
+		case mode.IsRegular():
+			err = fileutils.CopyReaderToPath(reader, destFilePath)
+			if err == nil {
+				err = os.Chmod(destFilePath, mode.Perm())
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// archiveEntryPath is where the entry called name in the archive at
+// archivePath is extracted to in destDir, with the symlinks extracted before
+// it resolved. Entries that would end up outside destDir, by their name or
+// through those symlinks, are rejected.
+func archiveEntryPath(archivePath, destDir, name string) (string, error) {
+	outsideErr := errors.NewWithFmt("Archive %s contains %s, which is outside the app directory", archivePath, name)
+
+	destPath := filepath.Join(destDir, name)
+	if !isInsideDir(destDir, destPath) {
+		return "", outsideErr
+	}
+
+	realDestDir, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return "", err
+	}
+
+	realPath, err := resolveExistingPath(destPath)
+	if err != nil || !isInsideDir(realDestDir, realPath) {
+		return "", outsideErr
+	}
+	return realPath, nil
+}
+
+// resolveExistingPath resolves the symlinks in the part of path that exists.
+func resolveExistingPath(path string) (string, error) {
+	existing, rest := path, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolved, rest), nil
+}
+
+func isInsideDir(dir, path string) bool {
+	relativePath, err := filepath.Rel(dir, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// extractSymlink makes a symlink at linkPath to target, which has to be
+// inside destDir. linkPath has its parent directories resolved already, so
+// that target is checked from where the symlink really is.
+func extractSymlink(target, destDir, linkPath string) (err error) {
+	realDestDir, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return
+	}
+
+	err = app_files.CheckSymlinkTarget(realDestDir, linkPath, target)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(linkPath), os.ModeDir|os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	return os.Symlink(target, linkPath)
+}
+
+func (repo CloudControllerApplicationBitsRepository) getFilesToUpload(allAppFiles []models.AppFileFields, cache *app_files.FingerprintCache) (appFilesToUpload []models.AppFileFields, presentFiles []resources.AppFileResource, presentFromCache bool, apiErr error) {
+	// Files the server is known to have from earlier pushes are not matched again
+	presentFiles = []resources.AppFileResource{}
 	appFilesRequest := []resources.AppFileResource{}
+	filesToMatch := 0
+
 	for _, file := range allAppFiles {
-		appFilesRequest = append(appFilesRequest, resources.AppFileResource{
+		resource := resources.AppFileResource{
 			Path: file.Path,
 			Sha1: file.Sha1,
 			Size: file.Size,
-		})
-	}
+		}
 
-	allAppFilesJson, err := json.Marshal(appFilesRequest)
-	if err != nil {
-		apiErr = errors.NewWithError("Failed to create json for resource_match request", err)
-		return
+		if cache.ServerHas(file.Sha1) {
+			presentFiles = append(presentFiles, resource)
+			presentFromCache = true
+			continue
+		}
+
+		appFilesRequest = append(appFilesRequest, resource)
+		if file.Sha1 != "0" {
+			filesToMatch++
+		}
 	}
 
-	apiErr = repo.gateway.UpdateResourceSync(
-		repo.config.ApiEndpoint()+"/v2/resource_match",
-		bytes.NewReader(allAppFilesJson),
-		&presentFiles)
+	if filesToMatch > 0 || len(presentFiles) == 0 {
+		allAppFilesJson, err := json.Marshal(appFilesRequest)
+		if err != nil {
+			apiErr = errors.NewWithError("Failed to create json for resource_match request", err)
+			return
+		}
 
-	if apiErr != nil {
-		return
+		matchedFiles := []resources.AppFileResource{}
+		apiErr = repo.gateway.UpdateResourceSync(
+			repo.config.ApiEndpoint()+"/v2/resource_match",
+			bytes.NewReader(allAppFilesJson),
+			&matchedFiles)
+
+		if apiErr != nil {
+			return
+		}
+
+		for _, file := range matchedFiles {
+			cache.RememberServerHas(file.Sha1)
+		}
+		presentFiles = append(presentFiles, matchedFiles...)
 	}
 
 	appFilesToUpload = make([]models.AppFileFields, len(allAppFiles))
@@ -1,13 +1,18 @@
 package api_test
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bytes"
 	. "cf/api"
 	"cf/app_files"
 	"cf/net"
+	"compress/gzip"
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -26,12 +31,26 @@ var _ = Describe("CloudControllerApplicationBitsRepository", func() {
 		reportedFilePath   string
 		reportedUploadSize uint64
 		reportedFileCount  uint64
+		uploadOptions      UploadOptions
+		artifactsDir       string
+		cacheDir           string
 	)
 
 	BeforeEach(func() {
 		cwd, err := os.Getwd()
 		Expect(err).NotTo(HaveOccurred())
 		fixturesDir = filepath.Join(cwd, "../../fixtures/applications")
+
+		uploadOptions = UploadOptions{}
+		artifactsDir, err = ioutil.TempDir("", "upload-artifacts")
+		Expect(err).NotTo(HaveOccurred())
+		cacheDir, err = ioutil.TempDir("", "fingerprints")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(artifactsDir)
+		os.RemoveAll(cacheDir)
 	})
 
 	var testUploadApp = func(dir string, requests ...testnet.TestRequest) (apiErr error) {
@@ -44,8 +63,11 @@ var _ = Describe("CloudControllerApplicationBitsRepository", func() {
 		gateway.PollingThrottle = time.Duration(0)
 		zipper := app_files.ApplicationZipper{}
 		repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, zipper)
+		repo.ArtifactsDir = artifactsDir
+		repo.FingerprintCacheDir = cacheDir
+		repo.RetryBackoff = time.Duration(0)
 
-		apiErr = repo.UploadApp("my-cool-app-guid", dir, func(path string, uploadSize, fileCount uint64) {
+		apiErr = repo.UploadApp("my-cool-app-guid", dir, uploadOptions, func(path string, uploadSize, fileCount uint64) {
 			reportedFilePath = path
 			reportedUploadSize = uploadSize
 			reportedFileCount = fileCount
@@ -72,11 +94,305 @@ var _ = Describe("CloudControllerApplicationBitsRepository", func() {
 
 		repo := NewCloudControllerApplicationBitsRepository(config, gateway, zipper)
 
-		apiErr := repo.UploadApp("app-guid", "/foo/bar", func(_ string, _, _ uint64) {})
+		apiErr := repo.UploadApp("app-guid", "/foo/bar", UploadOptions{}, func(_ string, _, _ uint64) {})
 		Expect(apiErr).To(HaveOccurred())
 		Expect(apiErr.Error()).To(ContainSubstring(filepath.Join("foo", "bar")))
 	})
 
+	It("returns the app files that the cloud controller does not already have", func() {
+		ts, handler := testnet.NewServer([]testnet.TestRequest{matchResourceRequest})
+		defer ts.Close()
+
+		configRepo := testconfig.NewRepositoryWithDefaults()
+		configRepo.SetApiEndpoint(ts.URL)
+		gateway := net.NewCloudControllerGateway(configRepo)
+		repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+		repo.FingerprintCacheDir = cacheDir
+
+		files, apiErr := repo.MatchFiles(filepath.Join(fixturesDir, "example-app"), MatchOptions{})
+		Expect(apiErr).NotTo(HaveOccurred())
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+
+		paths := []string{}
+		for _, file := range files {
+			paths = append(paths, file.Path)
+		}
+		Expect(paths).To(Equal(expectedApplicationContent))
+
+		cacheFiles, err := ioutil.ReadDir(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cacheFiles).To(HaveLen(1))
+	})
+
+	It("leaves the fingerprint cache alone when matching files without saving", func() {
+		ts, handler := testnet.NewServer([]testnet.TestRequest{matchResourceRequest})
+		defer ts.Close()
+
+		configRepo := testconfig.NewRepositoryWithDefaults()
+		configRepo.SetApiEndpoint(ts.URL)
+		gateway := net.NewCloudControllerGateway(configRepo)
+		repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+		repo.FingerprintCacheDir = cacheDir
+
+		_, apiErr := repo.MatchFiles(filepath.Join(fixturesDir, "example-app"), MatchOptions{NoSave: true})
+		Expect(apiErr).NotTo(HaveOccurred())
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+
+		cacheFiles, err := ioutil.ReadDir(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cacheFiles).To(BeEmpty())
+	})
+
+	It("keeps a fingerprint cache for each app, even when they are pushed from the same directory", func() {
+		ts, handler := testnet.NewServer([]testnet.TestRequest{matchResourceRequest, matchResourceRequest})
+		defer ts.Close()
+
+		configRepo := testconfig.NewRepositoryWithDefaults()
+		configRepo.SetApiEndpoint(ts.URL)
+		gateway := net.NewCloudControllerGateway(configRepo)
+		repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+		repo.FingerprintCacheDir = cacheDir
+
+		for _, appGuid := range []string{"web-app-guid", "worker-app-guid"} {
+			_, apiErr := repo.MatchFiles(filepath.Join(fixturesDir, "example-app"), MatchOptions{AppGuid: appGuid})
+			Expect(apiErr).NotTo(HaveOccurred())
+		}
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+
+		cacheFiles, err := ioutil.ReadDir(cacheDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cacheFiles).To(HaveLen(2))
+	})
+
+	Context("when uploading a zip file with symlinks", func() {
+		var zipPath string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("symlinks and executable bits are Unix only")
+			}
+
+			zipFile, err := ioutil.TempFile("", "app-with-symlinks")
+			Expect(err).NotTo(HaveOccurred())
+			zipPath = zipFile.Name() + ".zip"
+			zipFile.Close()
+			os.Remove(zipFile.Name())
+		})
+
+		AfterEach(func() {
+			os.Remove(zipPath)
+		})
+
+		It("keeps the symlinks and executable bits of the files in it", func() {
+			writeTestZip(zipPath, []testArchiveEntry{
+				{name: "bin/run", mode: 0755, contents: "#!/bin/sh"},
+				{name: "run", mode: os.ModeSymlink | 0777, contents: "bin/run"},
+			})
+
+			uploadedModes := map[string]os.FileMode{}
+			uploadedContents := map[string]string{}
+
+			apiErr := testUploadApp(zipPath,
+				testnet.TestRequest{
+					Method: "PUT",
+					Path:   "/v2/resource_match",
+					Response: testnet.TestResponse{
+						Status: http.StatusOK,
+						Body:   "[]",
+					},
+				},
+				testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+					Method: "PUT",
+					Path:   "/v2/apps/my-cool-app-guid/bits",
+					Matcher: func(request *http.Request) {
+						for _, f := range uploadedZipFiles(request) {
+							contents, err := f.Open()
+							Expect(err).NotTo(HaveOccurred())
+							fileData, err := ioutil.ReadAll(contents)
+							Expect(err).NotTo(HaveOccurred())
+
+							uploadedModes[f.Name] = f.Mode()
+							uploadedContents[f.Name] = string(fileData)
+						}
+					},
+					Response: testnet.TestResponse{
+						Status: http.StatusCreated,
+						Body:   `{"metadata":{"guid": "my-job-guid", "url": "/v2/jobs/my-job-guid"}}`,
+					}}),
+				createProgressEndpoint("finished"),
+			)
+
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(uploadedModes["bin/run"].Perm()).To(Equal(os.FileMode(0755)))
+			Expect(uploadedModes["run"] & os.ModeSymlink).NotTo(BeZero())
+			Expect(uploadedContents["run"]).To(Equal("bin/run"))
+		})
+
+		It("rejects symlinks that point outside the app", func() {
+			writeTestZip(zipPath, []testArchiveEntry{
+				{name: "app.rb", mode: 0644, contents: "puts 'hi'"},
+				{name: "secrets", mode: os.ModeSymlink | 0777, contents: "../../../etc/shadow"},
+			})
+
+			apiErr := testUploadApp(zipPath)
+			Expect(apiErr).To(HaveOccurred())
+			Expect(apiErr.Error()).To(ContainSubstring("secrets points outside the app directory"))
+		})
+
+		It("rejects files that would be extracted outside the app", func() {
+			writeTestZip(zipPath, []testArchiveEntry{
+				{name: "../escaped.rb", mode: 0644, contents: "puts 'hi'"},
+			})
+
+			apiErr := testUploadApp(zipPath)
+			Expect(apiErr).To(HaveOccurred())
+			Expect(apiErr.Error()).To(ContainSubstring("outside the app directory"))
+		})
+
+		It("rejects files that would be extracted outside the app through a chain of symlinks", func() {
+			escapedPath := filepath.Join(os.TempDir(), "escaped-through-symlinks.txt")
+			os.Remove(escapedPath)
+
+			writeTestZip(zipPath, []testArchiveEntry{
+				{name: "d", mode: os.ModeSymlink | 0777, contents: "."},
+				{name: "d/l", mode: os.ModeSymlink | 0777, contents: ".."},
+				{name: "l/escaped-through-symlinks.txt", mode: 0644, contents: "escaped"},
+			})
+
+			apiErr := testUploadApp(zipPath)
+			Expect(apiErr).To(HaveOccurred())
+			Expect(apiErr.Error()).To(ContainSubstring("outside the app directory"))
+			_, err := os.Stat(escapedPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("when uploading a tar file", func() {
+		var tarPath string
+
+		BeforeEach(func() {
+			tarFile, err := ioutil.TempFile("", "app-tar")
+			Expect(err).NotTo(HaveOccurred())
+			tarPath = tarFile.Name() + ".tgz"
+			tarFile.Close()
+			os.Remove(tarFile.Name())
+		})
+
+		AfterEach(func() {
+			os.Remove(tarPath)
+		})
+
+		It("uploads the files in it that are not ignored", func() {
+			writeTestTar(tarPath, true, []testArchiveEntry{
+				{name: "./", mode: os.ModeDir | 0755},
+				{name: "./.cfignore", mode: 0644, contents: "*.log\n"},
+				{name: "./Gemfile", mode: 0644, contents: "source 'https://rubygems.org'"},
+				{name: "./bin/", mode: os.ModeDir | 0755},
+				{name: "./bin/run", mode: 0755, contents: "#!/bin/sh"},
+				{name: "./debug.log", mode: 0644, contents: "lots of logging"},
+			})
+
+			uploadedModes := map[string]os.FileMode{}
+			apiErr := testUploadApp(tarPath,
+				testnet.TestRequest{
+					Method: "PUT",
+					Path:   "/v2/resource_match",
+					Response: testnet.TestResponse{
+						Status: http.StatusOK,
+						Body:   "[]",
+					},
+				},
+				testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+					Method: "PUT",
+					Path:   "/v2/apps/my-cool-app-guid/bits",
+					Matcher: func(request *http.Request) {
+						for _, f := range uploadedZipFiles(request) {
+							uploadedModes[f.Name] = f.Mode()
+						}
+					},
+					Response: testnet.TestResponse{
+						Status: http.StatusCreated,
+						Body:   `{"metadata":{"guid": "my-job-guid", "url": "/v2/jobs/my-job-guid"}}`,
+					}}),
+				createProgressEndpoint("finished"),
+			)
+
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(uploadedModes).To(HaveKey("Gemfile"))
+			Expect(uploadedModes).NotTo(HaveKey("debug.log"))
+			Expect(uploadedModes).NotTo(HaveKey(".cfignore"))
+			if runtime.GOOS != "windows" {
+				Expect(uploadedModes["bin/run"].Perm()).To(Equal(os.FileMode(0755)))
+			}
+		})
+
+		It("reads tar files that are not gzipped", func() {
+			writeTestTar(tarPath, false, []testArchiveEntry{
+				{name: "Gemfile", mode: 0644, contents: "source 'https://rubygems.org'"},
+			})
+
+			ts, handler := testnet.NewServer([]testnet.TestRequest{{
+				Method:  "PUT",
+				Path:    "/v2/resource_match",
+				Matcher: testnet.RequestBodyMatcher(`[{"fn":"Gemfile","sha1":"480be6c99925724ea2ad584a1cf6a075724bd29c","size":29}]`),
+				Response: testnet.TestResponse{
+					Status: http.StatusOK,
+					Body:   "[]",
+				},
+			}})
+			defer ts.Close()
+
+			configRepo := testconfig.NewRepositoryWithDefaults()
+			configRepo.SetApiEndpoint(ts.URL)
+			gateway := net.NewCloudControllerGateway(configRepo)
+			repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+
+			files, apiErr := repo.MatchFiles(tarPath, MatchOptions{})
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Path).To(Equal("Gemfile"))
+		})
+
+		It("rejects files that would be extracted outside the app", func() {
+			writeTestTar(tarPath, true, []testArchiveEntry{
+				{name: "../../escaped.rb", mode: 0644, contents: "puts 'hi'"},
+			})
+
+			apiErr := testUploadApp(tarPath)
+			Expect(apiErr).To(HaveOccurred())
+			Expect(apiErr.Error()).To(ContainSubstring("outside the app directory"))
+		})
+
+		It("rejects files that would be extracted outside the app through a chain of symlinks", func() {
+			if runtime.GOOS == "windows" {
+				Skip("symlinks are Unix only")
+			}
+
+			escapedPath := filepath.Join(os.TempDir(), "escaped-through-symlinks.txt")
+			os.Remove(escapedPath)
+
+			writeTestTar(tarPath, true, []testArchiveEntry{
+				{name: "d", mode: os.ModeSymlink | 0777, contents: "."},
+				{name: "d/l", mode: os.ModeSymlink | 0777, contents: ".."},
+				{name: "l/escaped-through-symlinks.txt", mode: 0644, contents: "escaped"},
+			})
+
+			apiErr := testUploadApp(tarPath)
+			Expect(apiErr).To(HaveOccurred())
+			Expect(apiErr.Error()).To(ContainSubstring("outside the app directory"))
+			_, err := os.Stat(escapedPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	It("fails clearly for files that are not a supported archive", func() {
+		apiErr := testUploadApp(filepath.Join(fixturesDir, "example-app", "Gemfile"))
+		Expect(apiErr).To(HaveOccurred())
+		Expect(apiErr.Error()).To(ContainSubstring("not a directory or an archive in a supported format"))
+		Expect(apiErr.Error()).To(ContainSubstring("tar.gz"))
+	})
+
 	Context("when uploading a directory", func() {
 		var appPath string
 
@@ -125,6 +441,218 @@ var _ = Describe("CloudControllerApplicationBitsRepository", func() {
 			Expect(apiErr).To(HaveOccurred())
 		})
 
+		It("reports the progress of the upload", func() {
+			var sent, total int64
+			uploadOptions.Progress = func(s, t int64) {
+				sent, total = s, t
+			}
+
+			apiErr := testUploadApp(appPath, defaultRequests...)
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(total).To(BeNumerically(">", 0))
+			Expect(sent).To(Equal(total))
+		})
+
+		Describe("retrying failed uploads", func() {
+			BeforeEach(func() {
+				uploadOptions.Retries = 2
+			})
+
+			It("uploads the app again after a server error", func() {
+				apiErr := testUploadApp(appPath,
+					matchResourceRequest,
+					failedUploadRequest(http.StatusBadGateway),
+					uploadApplicationRequest,
+					createProgressEndpoint("finished"),
+				)
+
+				Expect(apiErr).NotTo(HaveOccurred())
+			})
+
+			It("gives up after the configured number of retries", func() {
+				apiErr := testUploadApp(appPath,
+					matchResourceRequest,
+					failedUploadRequest(http.StatusBadGateway),
+					failedUploadRequest(http.StatusServiceUnavailable),
+					failedUploadRequest(http.StatusBadGateway),
+				)
+
+				Expect(apiErr).To(HaveOccurred())
+				Expect(apiErr.Error()).To(ContainSubstring("502"))
+			})
+
+			It("does not retry uploads whose job failed", func() {
+				apiErr := testUploadApp(appPath,
+					matchResourceRequest,
+					uploadApplicationRequest,
+					createProgressEndpoint("failed"),
+				)
+
+				Expect(apiErr).To(HaveOccurred())
+			})
+
+			It("does not retry uploads that the server rejected", func() {
+				apiErr := testUploadApp(appPath,
+					matchResourceRequest,
+					failedUploadRequest(http.StatusBadRequest),
+				)
+
+				Expect(apiErr).To(HaveOccurred())
+			})
+		})
+
+		Describe("resuming failed uploads", func() {
+			artifactPath := func(name string) string {
+				return filepath.Join(artifactsDir, "my-cool-app-guid", name)
+			}
+
+			BeforeEach(func() {
+				apiErr := testUploadApp(appPath, matchResourceRequest, failedUploadRequest(http.StatusBadGateway))
+				Expect(apiErr).To(HaveOccurred())
+			})
+
+			It("saves the zip and the list of files the server already has", func() {
+				_, err := os.Stat(artifactPath("application.zip"))
+				Expect(err).NotTo(HaveOccurred())
+
+				resourcesJSON, err := ioutil.ReadFile(artifactPath("resources.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(resourcesJSON)).To(Equal(matchedResources))
+			})
+
+			It("uploads the saved files without matching or zipping the app again", func() {
+				reportedFileCount = 0
+				uploadOptions.Resume = true
+
+				apiErr := testUploadApp(appPath,
+					uploadApplicationRequest,
+					createProgressEndpoint("finished"),
+				)
+
+				Expect(apiErr).NotTo(HaveOccurred())
+				Expect(reportedFileCount).To(Equal(uint64(len(expectedApplicationContent))))
+
+				_, err := os.Stat(artifactPath("resources.json"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			It("saves a fingerprint of the app files with them", func() {
+				fingerprint, err := ioutil.ReadFile(artifactPath("fingerprint"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fingerprint).To(HaveLen(40))
+			})
+
+			It("matches and zips the app as usual when its files have changed since", func() {
+				err := ioutil.WriteFile(artifactPath("fingerprint"), []byte("the files of another version"), 0600)
+				Expect(err).NotTo(HaveOccurred())
+				uploadOptions.Resume = true
+
+				apiErr := testUploadApp(appPath, defaultRequests...)
+				Expect(apiErr).NotTo(HaveOccurred())
+
+				_, err = os.Stat(artifactPath("resources.json"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			It("matches and zips the app as usual without --resume", func() {
+				apiErr := testUploadApp(appPath, defaultRequests...)
+				Expect(apiErr).NotTo(HaveOccurred())
+
+				_, err := os.Stat(artifactPath("resources.json"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		It("only matches the files the server did not have on the last push", func() {
+			ts, handler := testnet.NewServer([]testnet.TestRequest{
+				matchResourceRequest,
+				uploadApplicationRequest,
+				createProgressEndpoint("finished"),
+				testnet.TestRequest{
+					Method: "PUT",
+					Path:   "/v2/resource_match",
+					Matcher: testnet.RequestBodyMatcher(testnet.RemoveWhiteSpaceFromBody(`[
+						{"fn": "Gemfile", "sha1": "d9c3a51de5c89c11331d3b90b972789f1a14699a", "size": 59},
+						{"fn": "Gemfile.lock", "sha1": "345f999aef9070fb9a608e65cf221b7038156b6d", "size": 229}
+					]`)),
+					Response: testnet.TestResponse{Status: http.StatusOK, Body: "[]"},
+				},
+				uploadApplicationRequest,
+				createProgressEndpoint("finished"),
+			})
+			defer ts.Close()
+
+			configRepo := testconfig.NewRepositoryWithDefaults()
+			configRepo.SetApiEndpoint(ts.URL)
+			gateway := net.NewCloudControllerGateway(configRepo)
+			gateway.PollingThrottle = time.Duration(0)
+			repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+			repo.ArtifactsDir = artifactsDir
+			repo.FingerprintCacheDir = cacheDir
+
+			for i := 0; i < 2; i++ {
+				apiErr := repo.UploadApp("my-cool-app-guid", appPath, UploadOptions{}, func(_ string, _, _ uint64) {})
+				Expect(apiErr).NotTo(HaveOccurred())
+			}
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+		})
+
+		Describe("when the server no longer has the files it had on the last push", func() {
+			var uploadTwice = func(secondPushRequests ...testnet.TestRequest) (apiErr error) {
+				requests := []testnet.TestRequest{
+					matchResourceRequest,
+					uploadApplicationRequest,
+					createProgressEndpoint("finished"),
+					testnet.TestRequest{
+						Method:   "PUT",
+						Path:     "/v2/resource_match",
+						Response: testnet.TestResponse{Status: http.StatusOK, Body: "[]"},
+					},
+					failedUploadRequest(http.StatusBadRequest),
+				}
+				ts, handler := testnet.NewServer(append(requests, secondPushRequests...))
+				defer ts.Close()
+
+				configRepo := testconfig.NewRepositoryWithDefaults()
+				configRepo.SetApiEndpoint(ts.URL)
+				gateway := net.NewCloudControllerGateway(configRepo)
+				gateway.PollingThrottle = time.Duration(0)
+				repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+				repo.ArtifactsDir = artifactsDir
+				repo.FingerprintCacheDir = cacheDir
+
+				apiErr = repo.UploadApp("my-cool-app-guid", appPath, UploadOptions{}, func(_ string, _, _ uint64) {})
+				Expect(apiErr).NotTo(HaveOccurred())
+
+				apiErr = repo.UploadApp("my-cool-app-guid", appPath, UploadOptions{}, func(_ string, _, _ uint64) {})
+				Expect(handler).To(testnet.HaveAllRequestsCalled())
+				return
+			}
+
+			It("matches all of the files again and uploads them", func() {
+				apiErr := uploadTwice(
+					matchResourceRequest,
+					uploadApplicationRequest,
+					createProgressEndpoint("finished"),
+				)
+
+				Expect(apiErr).NotTo(HaveOccurred())
+			})
+
+			It("saves the files the server has for --resume only once they are matched again", func() {
+				apiErr := uploadTwice(
+					matchResourceRequest,
+					failedUploadRequest(http.StatusBadRequest),
+				)
+				Expect(apiErr).To(HaveOccurred())
+
+				resourcesJSON, err := ioutil.ReadFile(filepath.Join(artifactsDir, "my-cool-app-guid", "resources.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(resourcesJSON)).To(Equal(matchedResources))
+			})
+		})
+
 		Context("when there are no files to upload", func() {
 			It("makes a request without a zipfile", func() {
 				emptyDir := filepath.Join(fixturesDir, "empty-dir")
@@ -174,6 +702,53 @@ var _ = Describe("CloudControllerApplicationBitsRepository", func() {
 			})
 		})
 	})
+
+	Describe("copying bits", func() {
+		testCopyBits := func(requests ...testnet.TestRequest) (apiErr error) {
+			ts, handler := testnet.NewServer(requests)
+			defer ts.Close()
+
+			configRepo := testconfig.NewRepositoryWithDefaults()
+			configRepo.SetApiEndpoint(ts.URL)
+			gateway := net.NewCloudControllerGateway(configRepo)
+			gateway.PollingThrottle = time.Duration(0)
+			repo := NewCloudControllerApplicationBitsRepository(configRepo, gateway, app_files.ApplicationZipper{})
+
+			apiErr = repo.CopyBits("source-app-guid", "target-app-guid")
+
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			return
+		}
+
+		copyBitsRequest := testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+			Method:  "POST",
+			Path:    "/v2/apps/target-app-guid/copy_bits",
+			Matcher: testnet.RequestBodyMatcher(`{"source_app_guid":"source-app-guid"}`),
+			Response: testnet.TestResponse{
+				Status: http.StatusCreated,
+				Body:   `{"metadata":{"guid": "my-job-guid", "url": "/v2/jobs/my-job-guid"}}`,
+			},
+		})
+
+		It("copies the package of the source app and waits for the job to finish", func() {
+			apiErr := testCopyBits(
+				copyBitsRequest,
+				createProgressEndpoint("running"),
+				createProgressEndpoint("finished"),
+			)
+
+			Expect(apiErr).NotTo(HaveOccurred())
+		})
+
+		It("returns an error when the copy job fails", func() {
+			apiErr := testCopyBits(
+				copyBitsRequest,
+				createProgressEndpoint("failed"),
+			)
+
+			Expect(apiErr).To(HaveOccurred())
+		})
+	})
 })
 
 var matchedResources = testnet.RemoveWhiteSpaceFromBody(`[
@@ -205,6 +780,18 @@ var uploadApplicationRequest = testapi.NewCloudControllerTestRequest(testnet.Tes
 	`},
 })
 
+func failedUploadRequest(status int) testnet.TestRequest {
+	return testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+		Method:  "PUT",
+		Path:    "/v2/apps/my-cool-app-guid/bits",
+		Matcher: uploadBodyMatcher,
+		Response: testnet.TestResponse{
+			Status: status,
+			Body:   `{"code": 10001, "description": "upload failed"}`,
+		},
+	})
+}
+
 var matchResourceRequest = testnet.TestRequest{
 	Method: "PUT",
 	Path:   "/v2/resource_match",
@@ -314,6 +901,86 @@ nextFile:
 	}
 }
 
+type testArchiveEntry struct {
+	name     string
+	mode     os.FileMode
+	contents string
+}
+
+func writeTestZip(path string, entries []testArchiveEntry) {
+	zipFile, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	defer zipFile.Close()
+
+	writer := zip.NewWriter(zipFile)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		header.SetMode(entry.mode)
+
+		part, err := writer.CreateHeader(header)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = part.Write([]byte(entry.contents))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(writer.Close()).To(Succeed())
+}
+
+func writeTestTar(path string, gzipped bool, entries []testArchiveEntry) {
+	tarFile, err := os.Create(path)
+	Expect(err).NotTo(HaveOccurred())
+	defer tarFile.Close()
+
+	var output io.Writer = tarFile
+	if gzipped {
+		gzipWriter := gzip.NewWriter(tarFile)
+		defer gzipWriter.Close()
+		output = gzipWriter
+	}
+
+	writer := tar.NewWriter(output)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Mode:     int64(entry.mode.Perm()),
+			Size:     int64(len(entry.contents)),
+			Typeflag: tar.TypeReg,
+		}
+		if entry.mode.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		if entry.mode&os.ModeSymlink != 0 {
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = entry.contents
+			header.Size = 0
+		}
+
+		err = writer.WriteHeader(header)
+		Expect(err).NotTo(HaveOccurred())
+		if header.Size == 0 {
+			continue
+		}
+		_, err = writer.Write([]byte(entry.contents))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(writer.Close()).To(Succeed())
+}
+
+func uploadedZipFiles(request *http.Request) []*zip.File {
+	err := request.ParseMultipartForm(maxMultipartResponseSizeInBytes)
+	Expect(err).NotTo(HaveOccurred())
+	defer request.MultipartForm.RemoveAll()
+
+	file, err := request.MultipartForm.File["application"][0].Open()
+	Expect(err).NotTo(HaveOccurred())
+	data, err := ioutil.ReadAll(file)
+	Expect(err).NotTo(HaveOccurred())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	Expect(err).NotTo(HaveOccurred())
+	return zipReader.File
+}
+
 func executableBits(mode os.FileMode) os.FileMode {
 	return mode & 0111
 }
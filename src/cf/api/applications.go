@@ -15,7 +15,9 @@ import (
 type ApplicationRepository interface {
 	Create(params models.AppParams) (createdApp models.Application, apiErr error)
 	Read(name string) (app models.Application, apiErr error)
+	ReadFromSpace(name string, spaceGuid string) (app models.Application, apiErr error)
 	Update(appGuid string, params models.AppParams) (updatedApp models.Application, apiErr error)
+	Restage(appGuid string) (restagedApp models.Application, apiErr error)
 	Delete(appGuid string) (apiErr error)
 }
 
@@ -49,7 +51,11 @@ func (repo CloudControllerApplicationRepository) Create(params models.AppParams)
 }
 
 func (repo CloudControllerApplicationRepository) Read(name string) (app models.Application, apiErr error) {
-	path := fmt.Sprintf("%s/v2/spaces/%s/apps?q=%s&inline-relations-depth=1", repo.config.ApiEndpoint(), repo.config.SpaceFields().Guid, url.QueryEscape("name:"+name))
+	return repo.ReadFromSpace(name, repo.config.SpaceFields().Guid)
+}
+
+func (repo CloudControllerApplicationRepository) ReadFromSpace(name string, spaceGuid string) (app models.Application, apiErr error) {
+	path := fmt.Sprintf("%s/v2/spaces/%s/apps?q=%s&inline-relations-depth=1", repo.config.ApiEndpoint(), spaceGuid, url.QueryEscape("name:"+name))
 	appResources := new(resources.PaginatedApplicationResources)
 	apiErr = repo.gateway.GetResource(path, appResources)
 	if apiErr != nil {
@@ -84,6 +90,20 @@ func (repo CloudControllerApplicationRepository) Update(appGuid string, params m
 	return
 }
 
+// Restage stages the app's bits again, with its current buildpack and
+// service bindings, and then starts it.
+func (repo CloudControllerApplicationRepository) Restage(appGuid string) (restagedApp models.Application, apiErr error) {
+	path := fmt.Sprintf("%s/v2/apps/%s/restage", repo.config.ApiEndpoint(), appGuid)
+	resource := new(resources.ApplicationResource)
+	apiErr = repo.gateway.CreateResource(path, strings.NewReader(""), resource)
+	if apiErr != nil {
+		return
+	}
+
+	restagedApp = resource.ToModel()
+	return
+}
+
 func (repo CloudControllerApplicationRepository) formatAppJSON(input models.AppParams) (data string, err error) {
 	appResource := resources.NewApplicationEntityFromAppParams(input)
 	bytes, err := json.Marshal(appResource)
@@ -45,6 +45,19 @@ var _ = Describe("ApplicationsRepository", func() {
 			Expect(handler).To(testnet.HaveAllRequestsCalled())
 			Expect(apiErr.(*errors.ModelNotFoundError)).NotTo(BeNil())
 		})
+
+		It("finds apps in other spaces", func() {
+			request := testapi.NewCloudControllerTestRequest(findAppRequest)
+			request.Path = "/v2/spaces/other-space-guid/apps?q=name%3AMy+App&inline-relations-depth=1"
+
+			ts, handler, repo := createAppRepo([]testnet.TestRequest{request})
+			defer ts.Close()
+
+			app, apiErr := repo.ReadFromSpace("My App", "other-space-guid")
+			Expect(handler).To(testnet.HaveAllRequestsCalled())
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(app.Guid).To(Equal("app1-guid"))
+		})
 	})
 
 	Describe("creating applications", func() {
@@ -153,6 +166,27 @@ var _ = Describe("ApplicationsRepository", func() {
 		})
 	})
 
+	It("restages applications", func() {
+		request := testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+			Method: "POST",
+			Path:   "/v2/apps/my-cool-app-guid/restage",
+			Response: testnet.TestResponse{
+				Status: http.StatusCreated,
+				Body:   createApplicationResponse,
+			},
+		})
+
+		ts, handler, repo := createAppRepo([]testnet.TestRequest{request})
+		defer ts.Close()
+
+		restagedApp, apiErr := repo.Restage("my-cool-app-guid")
+
+		Expect(handler).To(testnet.HaveAllRequestsCalled())
+		Expect(apiErr).NotTo(HaveOccurred())
+		Expect(restagedApp.Guid).To(Equal("my-cool-app-guid"))
+		Expect(restagedApp.Name).To(Equal("my-cool-app"))
+	})
+
 	It("deletes applications", func() {
 		deleteApplicationRequest := testapi.NewCloudControllerTestRequest(testnet.TestRequest{
 			Method:   "DELETE",
@@ -21,7 +21,7 @@ import (
 )
 
 type BuildpackBitsRepository interface {
-	UploadBuildpack(buildpack models.Buildpack, dir string) (apiErr error)
+	UploadBuildpack(buildpack models.Buildpack, dir string, progress net.ProgressCallback) (apiErr error)
 }
 
 type CloudControllerBuildpackBitsRepository struct {
@@ -38,7 +38,7 @@ func NewCloudControllerBuildpackBitsRepository(config configuration.Reader, gate
 	return
 }
 
-func (repo CloudControllerBuildpackBitsRepository) UploadBuildpack(buildpack models.Buildpack, buildpackLocation string) (apiErr error) {
+func (repo CloudControllerBuildpackBitsRepository) UploadBuildpack(buildpack models.Buildpack, buildpackLocation string, progress net.ProgressCallback) (apiErr error) {
 	fileutils.TempFile("buildpack-upload", func(zipFileToUpload *os.File, err error) {
 		if err != nil {
 			apiErr = errors.NewWithError("Couldn't create temp file for upload", err)
@@ -82,7 +82,7 @@ func (repo CloudControllerBuildpackBitsRepository) UploadBuildpack(buildpack mod
 			return
 		}
 
-		apiErr = repo.uploadBits(buildpack, zipFileToUpload, buildpackFileName)
+		apiErr = repo.uploadBits(buildpack, zipFileToUpload, buildpackFileName, progress)
 	})
 
 	return
@@ -202,15 +202,16 @@ func (repo CloudControllerBuildpackBitsRepository) downloadBuildpack(url string,
 	})
 }
 
-func (repo CloudControllerBuildpackBitsRepository) uploadBits(buildpack models.Buildpack, body io.Reader, buildpackName string) error {
+func (repo CloudControllerBuildpackBitsRepository) uploadBits(buildpack models.Buildpack, body io.Reader, buildpackName string, progress net.ProgressCallback) error {
 	return repo.performMultiPartUpload(
 		fmt.Sprintf("%s/v2/buildpacks/%s/bits", repo.config.ApiEndpoint(), buildpack.Guid),
 		"buildpack",
 		buildpackName,
-		body)
+		body,
+		progress)
 }
 
-func (repo CloudControllerBuildpackBitsRepository) performMultiPartUpload(url string, fieldName string, fileName string, body io.Reader) (apiErr error) {
+func (repo CloudControllerBuildpackBitsRepository) performMultiPartUpload(url string, fieldName string, fileName string, body io.Reader, progress net.ProgressCallback) (apiErr error) {
 	fileutils.TempFile("requests", func(requestFile *os.File, err error) {
 		if err != nil {
 			apiErr = err
@@ -235,11 +236,12 @@ func (repo CloudControllerBuildpackBitsRepository) performMultiPartUpload(url st
 
 		var request *net.Request
 		request, apiErr = repo.gateway.NewRequest("PUT", url, repo.config.AccessToken(), requestFile)
-		contentType := fmt.Sprintf("multipart/form-data; boundary=%s", writer.Boundary())
-		request.HttpReq.Header.Set("Content-Type", contentType)
 		if apiErr != nil {
 			return
 		}
+		contentType := fmt.Sprintf("multipart/form-data; boundary=%s", writer.Boundary())
+		request.HttpReq.Header.Set("Content-Type", contentType)
+		request.SetProgressCallback(progress)
 
 		_, apiErr = repo.gateway.PerformRequest(request)
 	})
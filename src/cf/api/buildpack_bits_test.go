@@ -51,7 +51,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 
 	Describe("#UploadBuildpack", func() {
 		It("fails to upload a buildpack with an invalid directory", func() {
-			apiErr := repo.UploadBuildpack(buildpack, "/foo/bar")
+			apiErr := repo.UploadBuildpack(buildpack, "/foo/bar", nil)
 			Expect(apiErr).NotTo(BeNil())
 			Expect(apiErr.Error()).To(ContainSubstring("Error opening buildpack file"))
 		})
@@ -64,15 +64,27 @@ var _ = Describe("BuildpackBitsRepository", func() {
 			err := os.Chmod(filepath.Join(buildpackPath, "bin/release"), 0755)
 			Expect(err).NotTo(HaveOccurred())
 
-			apiErr := repo.UploadBuildpack(buildpack, buildpackPath)
+			apiErr := repo.UploadBuildpack(buildpack, buildpackPath, nil)
 			Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 			Expect(apiErr).NotTo(HaveOccurred())
 		})
 
+		It("reports the progress of the upload", func() {
+			var sent, total int64
+			buildpackPath := filepath.Join(buildpacksDir, "example-buildpack.zip")
+
+			apiErr := repo.UploadBuildpack(buildpack, buildpackPath, func(s, t int64) {
+				sent, total = s, t
+			})
+			Expect(apiErr).NotTo(HaveOccurred())
+			Expect(total).To(BeNumerically(">", 0))
+			Expect(sent).To(Equal(total))
+		})
+
 		It("uploads a valid zipped buildpack", func() {
 			buildpackPath := filepath.Join(buildpacksDir, "example-buildpack.zip")
 
-			apiErr := repo.UploadBuildpack(buildpack, buildpackPath)
+			apiErr := repo.UploadBuildpack(buildpack, buildpackPath, nil)
 			Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 			Expect(apiErr).NotTo(HaveOccurred())
 		})
@@ -81,7 +93,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 			It("uploads a zip file containing only the actual buildpack", func() {
 				buildpackPath := filepath.Join(buildpacksDir, "example-buildpack-in-dir.zip")
 
-				apiErr := repo.UploadBuildpack(buildpack, buildpackPath)
+				apiErr := repo.UploadBuildpack(buildpack, buildpackPath, nil)
 				Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 				Expect(apiErr).NotTo(HaveOccurred())
 			})
@@ -102,7 +114,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 					fileServer := httptest.NewServer(buildpackFileServerHandler("bad-buildpack.zip"))
 					defer fileServer.Close()
 
-					apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/bad-buildpack.zip")
+					apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/bad-buildpack.zip", nil)
 					Expect(testServerHandler).NotTo(testnet.HaveAllRequestsCalled())
 					Expect(apiErr).To(HaveOccurred())
 				})
@@ -112,7 +124,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 				fileServer := httptest.NewServer(buildpackFileServerHandler("example-buildpack.zip"))
 				defer fileServer.Close()
 
-				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip")
+				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip", nil)
 
 				Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 				Expect(apiErr).NotTo(HaveOccurred())
@@ -123,7 +135,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 				defer fileServer.Close()
 
 				repo.TrustedCerts = fileServer.TLS.Certificates
-				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip")
+				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip", nil)
 
 				Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 				Expect(apiErr).NotTo(HaveOccurred())
@@ -133,7 +145,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 				fileServer := httptest.NewTLSServer(buildpackFileServerHandler("example-buildpack.zip"))
 				defer fileServer.Close()
 
-				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip")
+				apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack.zip", nil)
 
 				Expect(testServerHandler).NotTo(testnet.HaveAllRequestsCalled())
 				Expect(apiErr).To(HaveOccurred())
@@ -145,7 +157,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 					defer fileServer.Close()
 
 					repo.TrustedCerts = fileServer.TLS.Certificates
-					apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack-in-dir.zip")
+					apiErr := repo.UploadBuildpack(buildpack, fileServer.URL+"/place/example-buildpack-in-dir.zip", nil)
 
 					Expect(testServerHandler).To(testnet.HaveAllRequestsCalled())
 					Expect(apiErr).NotTo(HaveOccurred())
@@ -153,7 +165,7 @@ var _ = Describe("BuildpackBitsRepository", func() {
 			})
 
 			It("returns an unsuccessful response when the server cannot be reached", func() {
-				apiErr := repo.UploadBuildpack(buildpack, "https://domain.bad-domain:223453/no-place/example-buildpack.zip")
+				apiErr := repo.UploadBuildpack(buildpack, "https://domain.bad-domain:223453/no-place/example-buildpack.zip", nil)
 				Expect(testServerHandler).NotTo(testnet.HaveAllRequestsCalled())
 				Expect(apiErr).To(HaveOccurred())
 			})
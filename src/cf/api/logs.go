@@ -2,6 +2,7 @@ package api
 
 import (
 	"cf/configuration"
+	"cf/net"
 	"crypto/tls"
 	"errors"
 	consumer "github.com/cloudfoundry/loggregator_consumer"
@@ -13,6 +14,9 @@ type LogsRepository interface {
 	RecentLogsFor(appGuid string) ([]*logmessage.LogMessage, error)
 	TailLogsFor(appGuid string, bufferTime time.Duration, onConnect func(), onMessage func(*logmessage.LogMessage)) error
 	Close()
+	// Clone returns a repository with a connection of its own, which can tail
+	// logs while this one does.
+	Clone() LogsRepository
 }
 
 type LoggregatorLogsRepository struct {
@@ -29,6 +33,11 @@ func (repo LoggregatorLogsRepository) Close() {
 	repo.consumer.Close()
 }
 
+func (repo LoggregatorLogsRepository) Clone() LogsRepository {
+	tlsConfig := net.NewTLSConfig(repo.TrustedCerts, repo.config.IsSSLDisabled())
+	return NewLoggregatorLogsRepository(repo.config, consumer.New(repo.config.LoggregatorEndpoint(), tlsConfig, nil))
+}
+
 func (repo LoggregatorLogsRepository) RecentLogsFor(appGuid string) ([]*logmessage.LogMessage, error) {
 	messages, err := repo.consumer.Recent(appGuid, repo.config.AccessToken())
 	consumer.SortRecent(messages)
@@ -49,6 +49,7 @@ type ApplicationEntity struct {
 	Buildpack          *string             `json:"buildpack,omitempty"`
 	EnvironmentJson    *map[string]string  `json:"environment_json,omitempty"`
 	HealthCheckTimeout *int                `json:"health_check_timeout,omitempty"`
+	HealthCheckType    *string             `json:"health_check_type,omitempty"`
 }
 
 func (resource AppRouteResource) ToFields() (route models.RouteSummary) {
@@ -76,6 +77,7 @@ func NewApplicationEntityFromAppParams(app models.AppParams) ApplicationEntity {
 		StackGuid:          app.StackGuid,
 		Command:            app.Command,
 		HealthCheckTimeout: app.HealthCheckTimeout,
+		HealthCheckType:    app.HealthCheckType,
 	}
 	if app.State != nil {
 		state := strings.ToUpper(*app.State)
@@ -112,6 +114,9 @@ func (resource ApplicationResource) ToFields() (app models.ApplicationFields) {
 	if entity.SpaceGuid != nil {
 		app.SpaceGuid = *entity.SpaceGuid
 	}
+	if entity.HealthCheckType != nil {
+		app.HealthCheckType = *entity.HealthCheckType
+	}
 	return
 }
 
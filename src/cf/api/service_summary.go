@@ -57,10 +57,17 @@ type ServiceInstanceSummaryApp struct {
 }
 
 type ServiceInstanceSummary struct {
+	Guid        string
 	Name        string
 	ServicePlan ServicePlanSummary `json:"service_plan"`
 }
 
+func (resource ServiceInstanceSummary) ToFields() (fields models.ServiceInstanceFields) {
+	fields.Guid = resource.Guid
+	fields.Name = resource.Name
+	return
+}
+
 type ServicePlanSummary struct {
 	Name            string
 	Guid            string
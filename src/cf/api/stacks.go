@@ -12,6 +12,7 @@ import (
 
 type StackRepository interface {
 	FindByName(name string) (stack models.Stack, apiErr error)
+	FindByGuid(guid string) (stack models.Stack, apiErr error)
 	FindAll() (stacks []models.Stack, apiErr error)
 }
 
@@ -42,6 +43,18 @@ func (repo CloudControllerStackRepository) FindByName(name string) (stack models
 	return
 }
 
+func (repo CloudControllerStackRepository) FindByGuid(guid string) (stack models.Stack, apiErr error) {
+	resource := new(resources.StackResource)
+	path := fmt.Sprintf("%s/v2/stacks/%s", repo.config.ApiEndpoint(), guid)
+	apiErr = repo.gateway.GetResource(path, resource)
+	if apiErr != nil {
+		return
+	}
+
+	stack = *resource.ToFields()
+	return
+}
+
 func (repo CloudControllerStackRepository) FindAll() (stacks []models.Stack, apiErr error) {
 	return repo.findAllWithPath("/v2/stacks")
 }
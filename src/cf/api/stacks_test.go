@@ -91,6 +91,31 @@ var _ = Describe("StacksRepo", func() {
 		})
 	})
 
+	Describe("FindByGuid", func() {
+		It("finds the stack", func() {
+			setupTestServer(testapi.NewCloudControllerTestRequest(testnet.TestRequest{
+				Method: "GET",
+				Path:   "/v2/stacks/custom-linux-guid",
+				Response: testnet.TestResponse{
+					Status: http.StatusOK,
+					Body: `
+					{
+						"metadata": { "guid": "custom-linux-guid" },
+						"entity": { "name": "custom-linux", "description": "Custom Linux" }
+					}`}}))
+
+			stack, err := repo.FindByGuid("custom-linux-guid")
+
+			Expect(testHandler).To(testnet.HaveAllRequestsCalled())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stack).To(Equal(models.Stack{
+				Name:        "custom-linux",
+				Guid:        "custom-linux-guid",
+				Description: "Custom Linux",
+			}))
+		})
+	})
+
 	Describe("FindAll", func() {
 		BeforeEach(func() {
 			setupTestServer(
@@ -32,6 +32,7 @@ var appHelpTemplate = `{{.Title "NAME:"}}
    CF_HOME=path/to/dir/               Override path to default config directory
    CF_STAGING_TIMEOUT=15              Max wait time for buildpack staging, in minutes
    CF_STARTUP_TIMEOUT=5               Max wait time for app instance startup, in minutes
+   CF_UPLOAD_RETRIES=3                Times to retry an app upload after a network or server error
    CF_TRACE=true                      Print API request diagnostics to stdout
    CF_TRACE=path/to/trace.log         Append API request diagnostics to a log file
    HTTP_PROXY=proxy.example.com:8080  Enable HTTP proxying for API requests
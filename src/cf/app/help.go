@@ -94,15 +94,21 @@ func newAppPresenter(app *cli.App) (presenter appPresenter) {
 				}, {
 					newCmdPresenter(app, maxNameLen, "push"),
 					newCmdPresenter(app, maxNameLen, "scale"),
+					newCmdPresenter(app, maxNameLen, "autoscale"),
 					newCmdPresenter(app, maxNameLen, "delete"),
 					newCmdPresenter(app, maxNameLen, "rename"),
+					newCmdPresenter(app, maxNameLen, "set-health-check"),
 				}, {
 					newCmdPresenter(app, maxNameLen, "start"),
 					newCmdPresenter(app, maxNameLen, "stop"),
 					newCmdPresenter(app, maxNameLen, "restart"),
+					newCmdPresenter(app, maxNameLen, "restart-app-instance"),
+					newCmdPresenter(app, maxNameLen, "restage"),
+					newCmdPresenter(app, maxNameLen, "copy-source"),
 				}, {
 					newCmdPresenter(app, maxNameLen, "events"),
 					newCmdPresenter(app, maxNameLen, "files"),
+					newCmdPresenter(app, maxNameLen, "download-files"),
 					newCmdPresenter(app, maxNameLen, "logs"),
 				}, {
 					newCmdPresenter(app, maxNameLen, "env"),
@@ -110,6 +116,9 @@ func newAppPresenter(app *cli.App) (presenter appPresenter) {
 					newCmdPresenter(app, maxNameLen, "unset-env"),
 				}, {
 					newCmdPresenter(app, maxNameLen, "stacks"),
+				}, {
+					newCmdPresenter(app, maxNameLen, "create-app-manifest"),
+					newCmdPresenter(app, maxNameLen, "validate-manifest"),
 				},
 			},
 		}, {
@@ -1,47 +1,70 @@
 package app_files
 
 import (
+	"cf/errors"
 	"cf/models"
 	"crypto/sha1"
 	cffileutils "fileutils"
 	"fmt"
 	"github.com/cloudfoundry/gofileutils/fileutils"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func AppFilesInDir(dir string) (appFiles []models.AppFileFields, err error) {
+	return AppFilesInDirWithCache(dir, nil, IgnoreOptions{})
+}
+
+// AppFilesInDirWithCache is AppFilesInDir, only taking the SHA1s of files
+// that have not changed from cache rather than hashing them again. Files are
+// hashed in parallel, and listed in the order WalkAppFiles visits them.
+func AppFilesInDirWithCache(dir string, cache *FingerprintCache, opts IgnoreOptions) (appFiles []models.AppFileFields, err error) {
 	dir, err = filepath.Abs(dir)
 	if err != nil {
 		return
 	}
 
-	err = WalkAppFiles(dir, func(fileName string, fullPath string) (err error) {
-		fileInfo, err := os.Lstat(fullPath)
-		if err != nil {
-			return
-		}
-
-		appFile := models.AppFileFields{
-			Path: filepath.ToSlash(fileName),
-			Size: fileInfo.Size(),
-		}
+	files, err := walkAppFilesInOrder(dir, opts)
+	if err != nil {
+		return
+	}
 
-		if fileInfo.IsDir() {
-			appFile.Sha1 = "0"
-		} else {
-			hash := sha1.New()
-			err = fileutils.CopyPathToWriter(fullPath, hash)
-			if err != nil {
-				return
-			}
-			appFile.Sha1 = fmt.Sprintf("%x", hash.Sum(nil))
-		}
+	err = processInOrder(files, func(file walkedFile) (interface{}, error) {
+		return fingerprintFile(file, cache)
+	}, func(_ walkedFile, appFile interface{}) error {
+		appFiles = append(appFiles, appFile.(models.AppFileFields))
+		return nil
+	})
+	return
+}
 
-		appFiles = append(appFiles, appFile)
+func fingerprintFile(file walkedFile, cache *FingerprintCache) (appFile models.AppFileFields, err error) {
+	fileInfo, err := os.Lstat(file.fullPath)
+	if err != nil {
 		return
-	})
+	}
+
+	appFile = models.AppFileFields{
+		Path: filepath.ToSlash(file.fileName),
+		Size: fileInfo.Size(),
+	}
+
+	// The server can only match files by their contents, so symlinks are
+	// always uploaded, like directories
+	if fileInfo.IsDir() || isSymlink(fileInfo) {
+		appFile.Sha1 = "0"
+	} else if cachedSha1, found := cache.Sha1(appFile.Path, fileInfo); found {
+		appFile.Sha1 = cachedSha1
+	} else {
+		hash := sha1.New()
+		err = fileutils.CopyPathToWriter(file.fullPath, hash)
+		if err != nil {
+			return
+		}
+		appFile.Sha1 = fmt.Sprintf("%x", hash.Sum(nil))
+		cache.RememberSha1(appFile.Path, fileInfo, appFile.Sha1)
+	}
 	return
 }
 
@@ -53,7 +76,7 @@ func CopyFiles(appFiles []models.AppFileFields, fromDir, toDir string) (err erro
 	for _, file := range appFiles {
 		fromPath := filepath.Join(fromDir, file.Path)
 		toPath := filepath.Join(toDir, file.Path)
-		err = fileutils.CopyPathToPath(fromPath, toPath)
+		err = copyAppFile(fromPath, toPath)
 		if err != nil {
 			return
 		}
@@ -61,6 +84,51 @@ func CopyFiles(appFiles []models.AppFileFields, fromDir, toDir string) (err erro
 	return
 }
 
+// copyAppFile copies a single file, keeping its mode. Directories are
+// created without their contents, which are copied separately, and symlinks
+// are copied rather than followed.
+func copyAppFile(fromPath, toPath string) (err error) {
+	fileInfo, err := os.Lstat(fromPath)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case fileInfo.IsDir():
+		return os.MkdirAll(toPath, os.ModeDir|os.ModePerm)
+	case isSymlink(fileInfo):
+		target, err := os.Readlink(fromPath)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(toPath), os.ModeDir|os.ModePerm)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, toPath)
+	default:
+		return fileutils.CopyPathToPath(fromPath, toPath)
+	}
+}
+
+// CheckSymlinkTarget fails unless target, where the symlink at linkPath
+// points, is inside dir. Symlinks to anything else would not work once the
+// app is pushed.
+func CheckSymlinkTarget(dir, linkPath, target string) error {
+	resolvedPath := filepath.Join(filepath.Dir(linkPath), target)
+	relativePath, err := filepath.Rel(dir, resolvedPath)
+
+	if filepath.IsAbs(target) || err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		linkName, _ := filepath.Rel(dir, linkPath)
+		return errors.NewWithFmt("Symlink %s points outside the app directory to %s", filepath.ToSlash(linkName), target)
+	}
+	return nil
+}
+
+func isSymlink(fileInfo os.FileInfo) bool {
+	return fileInfo.Mode()&os.ModeSymlink != 0
+}
+
 func CountFiles(directory string) uint64 {
 	var count uint64
 	WalkAppFiles(directory, func(_, _ string) error {
@@ -71,7 +139,30 @@ func CountFiles(directory string) uint64 {
 }
 
 func WalkAppFiles(dir string, onEachFile func(string, string) error) (err error) {
-	cfIgnore := loadIgnoreFile(dir)
+	return WalkAppFilesWithOptions(dir, IgnoreOptions{}, onEachFile)
+}
+
+// WalkAppFilesWithOptions calls onEachFile with the relative and full path of
+// every file and directory of the app in dir that is not ignored by the
+// .cfignore files in dir or its subdirectories.
+func WalkAppFilesWithOptions(dir string, opts IgnoreOptions, onEachFile func(string, string) error) (err error) {
+	return walkAppFiles(dir, opts, onEachFile, func(IgnoredFile) {})
+}
+
+// IgnoredAppFiles lists the files and directories that are left out of the
+// app in dir. The files in an ignored directory are not listed separately.
+func IgnoredAppFiles(dir string, opts IgnoreOptions) (ignoredFiles []IgnoredFile, err error) {
+	err = walkAppFiles(dir, opts, func(_, _ string) error {
+		return nil
+	}, func(ignoredFile IgnoredFile) {
+		ignoredFiles = append(ignoredFiles, ignoredFile)
+	})
+	return
+}
+
+func walkAppFiles(dir string, opts IgnoreOptions, onEachFile func(string, string) error, onIgnoredFile func(IgnoredFile)) (err error) {
+	cfIgnore := newDefaultCfIgnore()
+	ignoredDir := ""
 	walkFunc := func(fullPath string, f os.FileInfo, inErr error) (err error) {
 		err = inErr
 		if err != nil {
@@ -79,32 +170,57 @@ func WalkAppFiles(dir string, onEachFile func(string, string) error) (err error)
 		}
 
 		if fullPath == dir {
+			cfIgnore.loadIgnoreFiles(dir, "", opts)
 			return
 		}
 
-		if !cffileutils.IsRegular(f) && !f.IsDir() {
+		if !cffileutils.IsRegular(f) && !f.IsDir() && !isSymlink(f) {
 			return
 		}
 
 		fileRelativePath, _ := filepath.Rel(dir, fullPath)
 		fileRelativeUnixPath := filepath.ToSlash(fileRelativePath)
+		if f.IsDir() {
+			fileRelativeUnixPath += "/"
+		}
 
-		if !cfIgnore.FileShouldBeIgnored(fileRelativeUnixPath) {
-			err = onEachFile(fileRelativePath, fullPath)
+		if rule, ignored := cfIgnore.IgnoringRule(fileRelativeUnixPath); ignored {
+			inIgnoredDir := ignoredDir != "" && strings.HasPrefix(fileRelativeUnixPath, ignoredDir)
+			if !inIgnoredDir {
+				onIgnoredFile(IgnoredFile{Path: fileRelativeUnixPath, Rule: rule})
+			}
+
+			// Files in an ignored directory can only be included again by
+			// the top-level .cfignore, so the directory is usually skipped
+			if f.IsDir() && !cfIgnore.reincludesFiles() {
+				err = filepath.SkipDir
+			} else if f.IsDir() && !inIgnoredDir {
+				ignoredDir = fileRelativeUnixPath
+			}
+			return
+		}
+
+		if f.IsDir() {
+			cfIgnore.loadIgnoreFiles(fullPath, filepath.ToSlash(fileRelativePath), opts)
+		}
+
+		if isSymlink(f) {
+			var target string
+			target, err = os.Readlink(fullPath)
+			if err != nil {
+				return
+			}
+
+			err = CheckSymlinkTarget(dir, fullPath, target)
+			if err != nil {
+				return
+			}
 		}
 
+		err = onEachFile(fileRelativePath, fullPath)
 		return
 	}
 
 	err = filepath.Walk(dir, walkFunc)
 	return
 }
-
-func loadIgnoreFile(dir string) CfIgnore {
-	fileContents, err := ioutil.ReadFile(filepath.Join(dir, ".cfignore"))
-	if err == nil {
-		return NewCfIgnore(string(fileContents))
-	} else {
-		return NewCfIgnore("")
-	}
-}
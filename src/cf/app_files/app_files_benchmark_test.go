@@ -0,0 +1,88 @@
+package app_files_test
+
+import (
+	. "cf/app_files"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// The benchmarks run against a tree of 20,000 small files, which is created
+// the first time it is needed and left in the temp dir for later runs. Each
+// one is run with a single worker and with a worker for every CPU, and lets
+// the workers use that many CPUs, so comparing the two shows what the
+// workers gain when hashing and compressing.
+var (
+	benchmarkTreeOnce sync.Once
+	benchmarkTreeDir  string
+)
+
+func benchmarkTree(b *testing.B) string {
+	benchmarkTreeOnce.Do(func() {
+		dir, err := ioutil.TempDir("", "app-files-benchmark")
+		if err != nil {
+			b.Fatal(err)
+		}
+		writeAppTree(dir, 100, 200)
+		benchmarkTreeDir = dir
+	})
+	return benchmarkTreeDir
+}
+
+func benchmarkAppFilesInDir(b *testing.B, workers int) {
+	dir := benchmarkTree(b)
+	defer func(original int) { MaxWorkers = original }(MaxWorkers)
+	MaxWorkers = workers
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(workers))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := AppFilesInDir(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkZip(b *testing.B, workers int) {
+	dir := benchmarkTree(b)
+	defer func(original int) { MaxWorkers = original }(MaxWorkers)
+	MaxWorkers = workers
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(workers))
+
+	zipFile, err := ioutil.TempFile("", "app-files-benchmark-zip")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zipFile.Truncate(0)
+		zipFile.Seek(0, os.SEEK_SET)
+
+		err = ApplicationZipper{}.Zip(dir, zipFile)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppFilesInDirOneWorker(b *testing.B) {
+	benchmarkAppFilesInDir(b, 1)
+}
+
+func BenchmarkAppFilesInDirAllCPUs(b *testing.B) {
+	benchmarkAppFilesInDir(b, runtime.NumCPU())
+}
+
+func BenchmarkZipOneWorker(b *testing.B) {
+	benchmarkZip(b, 1)
+}
+
+func BenchmarkZipAllCPUs(b *testing.B) {
+	benchmarkZip(b, runtime.NumCPU())
+}
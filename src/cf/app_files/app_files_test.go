@@ -2,9 +2,15 @@ package app_files_test
 
 import (
 	. "cf/app_files"
+	"cf/models"
+	"crypto/sha1"
+	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"time"
 )
 
 var _ = Describe("AppFiles", func() {
@@ -32,15 +38,194 @@ var _ = Describe("AppFiles", func() {
 
 			Expect(paths).To(Equal([]string{
 				"dir1",
-				"dir1/child-dir",
 				"dir1/child-dir/file3.txt",
 				"dir1/file1.txt",
 				"dir2",
+			}))
+		})
+
+		It("lists the directories it leaves out once, even when it looks through them for files to include again", func() {
+			appPath := filepath.Join(fixturePath, "app-with-cfignore")
+			ignoredFiles, err := IgnoredAppFiles(appPath, IgnoreOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			paths := []string{}
+			for _, ignoredFile := range ignoredFiles {
+				paths = append(paths, ignoredFile.Path)
+			}
 
-				// TODO: this should be excluded.
-				// .cfignore doesn't handle ** patterns right now
-				"dir2/child-dir2",
+			Expect(paths).To(Equal([]string{
+				".cfignore",
+				"dir1/child-dir/",
+				"dir2/child-dir2/",
 			}))
 		})
+
+		Describe("with .cfignore files in subdirectories", func() {
+			var appDir string
+
+			BeforeEach(func() {
+				var err error
+				appDir, err = ioutil.TempDir("", "nested-cfignore")
+				Expect(err).NotTo(HaveOccurred())
+
+				writeFiles(appDir, map[string]string{
+					".cfignore":              "*.log\n",
+					".gitignore":             "*.tmp\n",
+					"app.rb":                 "",
+					"app.log":                "",
+					"app.tmp":                "",
+					"web/.cfignore":          "/assets/\n!keep.log\n!assets/app.js\n",
+					"web/keep.log":           "",
+					"web/assets/app.js":      "",
+					"web/src/assets/app.js":  "",
+					"worker/other.log":       "",
+					"worker/vendor/.keep":    "",
+					"worker/vendor/cache.js": "",
+				})
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(appDir)
+			})
+
+			It("applies the rules of each .cfignore to the files below it", func() {
+				files, err := AppFilesInDir(appDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(appFilePaths(files)).To(Equal([]string{
+					"app.rb",
+					"app.tmp",
+					"web",
+					"web/keep.log",
+					"web/src",
+					"web/src/assets",
+					"web/src/assets/app.js",
+					"worker",
+					"worker/vendor",
+					"worker/vendor/.keep",
+					"worker/vendor/cache.js",
+				}))
+			})
+
+			It("also applies the rules of .gitignore files when asked to", func() {
+				files, err := AppFilesInDirWithCache(appDir, nil, IgnoreOptions{UseGitignore: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(appFilePaths(files)).NotTo(ContainElement("app.tmp"))
+				Expect(appFilePaths(files)).To(ContainElement("app.rb"))
+			})
+
+			It("lists the files it leaves out and the rules that leave them out", func() {
+				ignoredFiles, err := IgnoredAppFiles(appDir, IgnoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ignoredFiles).To(Equal([]IgnoredFile{
+					{Path: ".cfignore", Rule: IgnoreRule{Pattern: ".cfignore"}},
+					{Path: ".gitignore", Rule: IgnoreRule{Pattern: ".gitignore"}},
+					{Path: "app.log", Rule: IgnoreRule{Source: ".cfignore", Line: 1, Pattern: "*.log"}},
+					{Path: "web/.cfignore", Rule: IgnoreRule{Pattern: ".cfignore"}},
+					{Path: "web/assets/", Rule: IgnoreRule{Source: "web/.cfignore", Line: 1, Pattern: "/assets/"}},
+					{Path: "worker/other.log", Rule: IgnoreRule{Source: ".cfignore", Line: 1, Pattern: "*.log"}},
+				}))
+			})
+		})
+
+		Describe("with a fingerprint cache", func() {
+			var (
+				appDir    string
+				cachePath string
+			)
+
+			BeforeEach(func() {
+				var err error
+				appDir, err = ioutil.TempDir("", "fingerprinted-app")
+				Expect(err).NotTo(HaveOccurred())
+				cachePath = filepath.Join(appDir, "cache", "fingerprints.json")
+
+				err = ioutil.WriteFile(filepath.Join(appDir, "app.rb"), []byte("puts 'hello'"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(appDir)
+			})
+
+			cacheAppFiles := func() {
+				cache := LoadFingerprintCache(cachePath)
+				_, err := AppFilesInDirWithCache(appDir, cache, IgnoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cache.Save()).To(Succeed())
+			}
+
+			sha1Of := func(path string) string {
+				files, err := AppFilesInDirWithCache(appDir, LoadFingerprintCache(cachePath), IgnoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				for _, file := range files {
+					if file.Path == path {
+						return file.Sha1
+					}
+				}
+				return ""
+			}
+
+			It("takes the SHA1 of files with the same size and modification time from the cache", func() {
+				cacheAppFiles()
+
+				appFile := filepath.Join(appDir, "app.rb")
+				info, err := os.Stat(appFile)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(appFile, []byte("puts 'HELLO'"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+				err = os.Chtimes(appFile, info.ModTime(), info.ModTime())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(sha1Of("app.rb")).To(Equal(fmt.Sprintf("%x", sha1.Sum([]byte("puts 'hello'")))))
+			})
+
+			It("hashes files again when they have changed", func() {
+				cacheAppFiles()
+
+				appFile := filepath.Join(appDir, "app.rb")
+				err := ioutil.WriteFile(appFile, []byte("puts 'HELLO'"), 0644)
+				Expect(err).NotTo(HaveOccurred())
+				later := time.Now().Add(time.Hour)
+				err = os.Chtimes(appFile, later, later)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(sha1Of("app.rb")).To(Equal(fmt.Sprintf("%x", sha1.Sum([]byte("puts 'HELLO'")))))
+			})
+
+			It("remembers the SHA1s the server has", func() {
+				cache := LoadFingerprintCache(cachePath)
+				cache.RememberServerHas("some-sha1")
+				Expect(cache.Save()).To(Succeed())
+
+				cache = LoadFingerprintCache(cachePath)
+				Expect(cache.ServerHas("some-sha1")).To(BeTrue())
+				Expect(cache.ServerHas("other-sha1")).To(BeFalse())
+
+				cache.ForgetServerFiles()
+				Expect(cache.ServerHas("some-sha1")).To(BeFalse())
+			})
+		})
 	})
 })
+
+func writeFiles(dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		Expect(err).NotTo(HaveOccurred())
+		err = ioutil.WriteFile(path, []byte(contents), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
+
+func appFilePaths(files []models.AppFileFields) (paths []string) {
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return
+}
@@ -1,76 +1,218 @@
 package app_files
 
 import (
+	"fmt"
 	"glob"
+	"io/ioutil"
 	"path"
+	"path/filepath"
 	"strings"
 )
 
+// CfIgnore decides which files are left out of an app, following the rules
+// of .gitignore files:
+//   - blank lines and lines starting with `#` are skipped
+//   - a pattern starting with `!` includes files an earlier pattern excluded
+//   - a pattern ending with `/` only matches directories
+//   - a pattern containing a `/` is matched against the path relative to the
+//     directory of the file it is in, otherwise against the name of the file
+//     at any depth below that directory
+//   - `**` matches any number of directories
+//   - a file cannot be included again once one of its directories is
+//     excluded, unless the `!` pattern is in the .cfignore at the top of the
+//     app. Those have always been able to include files from excluded
+//     directories, and apps rely on it.
+//
+// Paths of directories are given with a trailing `/`.
 type CfIgnore interface {
 	FileShouldBeIgnored(path string) bool
+	IgnoringRule(path string) (rule IgnoreRule, ignored bool)
+}
+
+// IgnoreOptions changes which files are left out of an app.
+type IgnoreOptions struct {
+	// UseGitignore also leaves out the files .gitignore files ignore.
+	UseGitignore bool
+}
+
+// IgnoreRule is a line of an ignore file, or one of the patterns that are
+// ignored by default.
+type IgnoreRule struct {
+	// Source is the path of the ignore file relative to the app, or empty
+	// for the default patterns.
+	Source  string
+	Line    int
+	Pattern string
+}
+
+func (rule IgnoreRule) String() string {
+	if rule.Source == "" {
+		return fmt.Sprintf("%s (default)", rule.Pattern)
+	}
+	return fmt.Sprintf("%s:%d: %s", rule.Source, rule.Line, rule.Pattern)
+}
+
+// IgnoredFile is a file or directory that was left out of an app, and the
+// rule that left it out.
+type IgnoredFile struct {
+	Path string
+	Rule IgnoreRule
 }
 
 func NewCfIgnore(text string) CfIgnore {
-	patterns := []ignorePattern{}
-	lines := strings.Split(text, "\n")
-	lines = append(defaultIgnoreLines, lines...)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	ignore := newDefaultCfIgnore()
+	ignore.addRules("", ".cfignore", text)
+	return ignore
+}
 
-		ignore := true
-		if strings.HasPrefix(line, "!") {
-			line = line[1:]
-			ignore = false
-		}
+func newDefaultCfIgnore() *cfIgnore {
+	ignore := &cfIgnore{}
+	for _, line := range defaultIgnoreLines {
+		ignore.addRule("", IgnoreRule{Pattern: line})
+	}
+	return ignore
+}
 
-		for _, p := range globsForPattern(path.Clean(line)) {
-			patterns = append(patterns, ignorePattern{ignore, p})
+// loadIgnoreFiles adds the rules of the ignore files in dir, which is at
+// relativeDir within the app.
+func (ignore *cfIgnore) loadIgnoreFiles(dir, relativeDir string, opts IgnoreOptions) {
+	fileNames := []string{".cfignore"}
+	if opts.UseGitignore {
+		fileNames = []string{".gitignore", ".cfignore"}
+	}
+
+	for _, fileName := range fileNames {
+		fileContents, err := ioutil.ReadFile(filepath.Join(dir, fileName))
+		if err == nil {
+			ignore.addRules(relativeDir, path.Join(relativeDir, fileName), string(fileContents))
 		}
 	}
+}
 
-	return cfIgnore(patterns)
+func (ignore *cfIgnore) addRules(baseDir, source, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		ignore.addRule(baseDir, IgnoreRule{Source: source, Line: i + 1, Pattern: strings.TrimSpace(line)})
+	}
 }
 
-func (ignore cfIgnore) FileShouldBeIgnored(path string) bool {
-	result := false
+func (ignore *cfIgnore) addRule(baseDir string, rule IgnoreRule) {
+	pattern := rule.Pattern
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+
+	exclude := true
+	if strings.HasPrefix(pattern, "!") {
+		pattern = pattern[1:]
+		exclude = false
+	} else if strings.HasPrefix(pattern, `\#`) || strings.HasPrefix(pattern, `\!`) {
+		pattern = pattern[1:]
+	}
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimRight(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimLeft(pattern, "/")
 
-	for _, pattern := range ignore {
-		if strings.HasPrefix(pattern.glob.String(), "/") && !strings.HasPrefix(path, "/") {
-			path = "/" + path
+	compiled, err := glob.CompileGlob(pattern)
+	if pattern == "" || err != nil {
+		return
+	}
+
+	ignore.rules = append(ignore.rules, ignorePattern{
+		IgnoreRule: rule,
+		baseDir:    baseDir,
+		exclude:    exclude,
+		reincludes: !exclude && rule.Source == ".cfignore",
+		dirOnly:    dirOnly,
+		anchored:   anchored,
+		glob:       compiled,
+	})
+}
+
+// reincludesFiles is true when a pattern can include files from directories
+// that are excluded, so that those directories have to be looked through.
+func (ignore *cfIgnore) reincludesFiles() bool {
+	for _, pattern := range ignore.rules {
+		if pattern.reincludes {
+			return true
 		}
+	}
+	return false
+}
 
-		if pattern.glob.Match(path) {
-			result = pattern.exclude
+func (ignore *cfIgnore) FileShouldBeIgnored(path string) bool {
+	_, ignored := ignore.IgnoringRule(path)
+	return ignored
+}
+
+// IgnoringRule returns the rule that excludes path, or one of the
+// directories path is in.
+func (ignore *cfIgnore) IgnoringRule(filePath string) (rule IgnoreRule, ignored bool) {
+	isDir := strings.HasSuffix(filePath, "/")
+	components := strings.Split(strings.Trim(filePath, "/"), "/")
+
+	for i := range components {
+		pathSoFar := strings.Join(components[:i+1], "/")
+		pattern, found := ignore.lastMatch(pathSoFar, isDir || i < len(components)-1)
+		switch {
+		case !found:
+		case pattern.exclude && !ignored:
+			rule, ignored = pattern.IgnoreRule, true
+		case !pattern.exclude && ignored && pattern.reincludes:
+			rule, ignored = IgnoreRule{}, false
 		}
 	}
+	return
+}
 
-	return result
+func (ignore *cfIgnore) lastMatch(filePath string, isDir bool) (match ignorePattern, found bool) {
+	for _, pattern := range ignore.rules {
+		if pattern.matches(filePath, isDir) {
+			match, found = pattern, true
+		}
+	}
+	return
 }
 
-func globsForPattern(pattern string) (globs []glob.Glob) {
-	globs = append(globs, glob.MustCompileGlob(pattern))
-	globs = append(globs, glob.MustCompileGlob(path.Join(pattern, "*")))
-	globs = append(globs, glob.MustCompileGlob(path.Join(pattern, "**", "*")))
+func (pattern ignorePattern) matches(filePath string, isDir bool) bool {
+	if pattern.dirOnly && !isDir {
+		return false
+	}
 
-	if !strings.HasPrefix(pattern, "/") {
-		globs = append(globs, glob.MustCompileGlob(path.Join("**", pattern)))
-		globs = append(globs, glob.MustCompileGlob(path.Join("**", pattern, "*")))
-		globs = append(globs, glob.MustCompileGlob(path.Join("**", pattern, "**", "*")))
+	if pattern.baseDir != "" {
+		if !strings.HasPrefix(filePath, pattern.baseDir+"/") {
+			return false
+		}
+		filePath = strings.TrimPrefix(filePath, pattern.baseDir+"/")
 	}
 
-	return
+	if !pattern.anchored {
+		filePath = path.Base(filePath)
+	}
+
+	return pattern.glob.Match(filePath)
 }
 
 type ignorePattern struct {
+	IgnoreRule
+
+	// baseDir is the directory of the ignore file the pattern is from,
+	// relative to the app
+	baseDir string
 	exclude bool
-	glob    glob.Glob
+
+	// reincludes is true for `!` patterns of the top-level .cfignore, which
+	// can include files from excluded directories
+	reincludes bool
+	dirOnly    bool
+	anchored   bool
+	glob       glob.Glob
 }
 
-type cfIgnore []ignorePattern
+type cfIgnore struct {
+	rules []ignorePattern
+}
 
 var defaultIgnoreLines = []string{
 	".cfignore",
@@ -58,4 +58,84 @@ stuff/exclude.c`)
 		ignore = NewCfIgnore(`!.git`)
 		Expect(ignore.FileShouldBeIgnored(".git/objects")).To(BeFalse())
 	})
+
+	It("skips blank lines and comments", func() {
+		ignore := NewCfIgnore(`
+# build output
+*.o
+
+\#notes`)
+
+		Expect(ignore.FileShouldBeIgnored("# build output")).To(BeFalse())
+		Expect(ignore.FileShouldBeIgnored("main.o")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("#notes")).To(BeTrue())
+	})
+
+	It("matches patterns without a slash against file names at any depth", func() {
+		ignore := NewCfIgnore(`*.log`)
+		Expect(ignore.FileShouldBeIgnored("server.log")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("logs/2014/server.log")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("server.log.gz")).To(BeFalse())
+	})
+
+	It("anchors patterns with a slash to the directory of the ignore file", func() {
+		ignore := NewCfIgnore(`
+/tmp
+config/local.yml`)
+
+		Expect(ignore.FileShouldBeIgnored("tmp/cache")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("lib/tmp/cache")).To(BeFalse())
+		Expect(ignore.FileShouldBeIgnored("config/local.yml")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("app/config/local.yml")).To(BeFalse())
+	})
+
+	It("matches patterns ending with a slash only against directories", func() {
+		ignore := NewCfIgnore(`build/`)
+		Expect(ignore.FileShouldBeIgnored("build/")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("build/app.jar")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("src/build/")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("build")).To(BeFalse())
+	})
+
+	It("matches double stars against any number of directories", func() {
+		ignore := NewCfIgnore(`
+**/fixtures
+docs/**/*.png`)
+
+		Expect(ignore.FileShouldBeIgnored("fixtures/")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("spec/unit/fixtures/")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("docs/logo.png")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("docs/images/logo.png")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("logo.png")).To(BeFalse())
+	})
+
+	It("includes files again when their directory is excluded, as the top-level .cfignore always has", func() {
+		ignore := NewCfIgnore(`
+vendor/
+!vendor/keep.rb
+!vendor/cache/`)
+
+		Expect(ignore.FileShouldBeIgnored("vendor/")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("vendor/other.rb")).To(BeTrue())
+		Expect(ignore.FileShouldBeIgnored("vendor/keep.rb")).To(BeFalse())
+		Expect(ignore.FileShouldBeIgnored("vendor/cache/gem.rb")).To(BeFalse())
+	})
+
+	It("tells which rule excludes a file", func() {
+		ignore := NewCfIgnore(`
+*.log
+tmp/`)
+
+		rule, ignored := ignore.IgnoringRule("tmp/cache/file")
+		Expect(ignored).To(BeTrue())
+		Expect(rule).To(Equal(IgnoreRule{Source: ".cfignore", Line: 3, Pattern: "tmp/"}))
+		Expect(rule.String()).To(Equal(".cfignore:3: tmp/"))
+
+		rule, ignored = ignore.IgnoringRule(".git/")
+		Expect(ignored).To(BeTrue())
+		Expect(rule.String()).To(Equal(".git (default)"))
+
+		_, ignored = ignore.IgnoringRule("app.rb")
+		Expect(ignored).To(BeFalse())
+	})
 })
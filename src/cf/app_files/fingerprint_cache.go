@@ -0,0 +1,165 @@
+package app_files
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FingerprintCache remembers the SHA1 of each file of an app, keyed by its
+// path, size and modification time, so that files which have not changed
+// since the last push are not hashed again. It also remembers which SHA1s
+// the server already had, so that they do not have to be matched again.
+//
+// All methods can be called on a nil cache, which remembers nothing, and
+// from several goroutines at once.
+type FingerprintCache struct {
+	path string
+	lock sync.Mutex
+
+	previous     map[string]cachedFingerprint
+	files        map[string]cachedFingerprint
+	serverHashes map[string]bool
+}
+
+type cachedFingerprint struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Sha1    string `json:"sha1"`
+}
+
+type fingerprintCacheFile struct {
+	Files        map[string]cachedFingerprint `json:"files"`
+	ServerHashes []string                     `json:"server_sha1s"`
+}
+
+// LoadFingerprintCache reads the cache saved at path. A cache that is
+// missing or cannot be read starts out empty.
+func LoadFingerprintCache(path string) *FingerprintCache {
+	cache := &FingerprintCache{
+		path:         path,
+		previous:     map[string]cachedFingerprint{},
+		files:        map[string]cachedFingerprint{},
+		serverHashes: map[string]bool{},
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	cacheFile := fingerprintCacheFile{}
+	if json.Unmarshal(data, &cacheFile) != nil {
+		return cache
+	}
+
+	if cacheFile.Files != nil {
+		cache.previous = cacheFile.Files
+	}
+	for _, sha1 := range cacheFile.ServerHashes {
+		cache.serverHashes[sha1] = true
+	}
+	return cache
+}
+
+// Sha1 returns the SHA1 saved for the file at path, if the file has the same
+// size and modification time as when it was saved.
+func (cache *FingerprintCache) Sha1(path string, fileInfo os.FileInfo) (sha1 string, found bool) {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	fingerprint, found := cache.previous[path]
+	if !found || fingerprint.Size != fileInfo.Size() || fingerprint.ModTime != fileInfo.ModTime().UnixNano() {
+		return "", false
+	}
+
+	cache.files[path] = fingerprint
+	return fingerprint.Sha1, true
+}
+
+func (cache *FingerprintCache) RememberSha1(path string, fileInfo os.FileInfo, sha1 string) {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.files[path] = cachedFingerprint{
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime().UnixNano(),
+		Sha1:    sha1,
+	}
+}
+
+func (cache *FingerprintCache) ServerHas(sha1 string) bool {
+	if cache == nil {
+		return false
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return cache.serverHashes[sha1]
+}
+
+func (cache *FingerprintCache) RememberServerHas(sha1 string) {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.serverHashes[sha1] = true
+}
+
+// ForgetServerFiles is used when the server turns out not to have the files
+// it was thought to have, e.g. because they were cleaned up since.
+func (cache *FingerprintCache) ForgetServerFiles() {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.serverHashes = map[string]bool{}
+}
+
+// Save writes the fingerprints of the files looked up since the cache was
+// loaded, so that files which were deleted are dropped from it.
+func (cache *FingerprintCache) Save() (err error) {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cacheFile := fingerprintCacheFile{
+		Files:        cache.files,
+		ServerHashes: []string{},
+	}
+	for sha1 := range cache.serverHashes {
+		cacheFile.ServerHashes = append(cacheFile.ServerHashes, sha1)
+	}
+
+	data, err := json.Marshal(cacheFile)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(cache.path), os.ModeDir|os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(cache.path, data, 0600)
+}
@@ -0,0 +1,100 @@
+package app_files
+
+import (
+	"runtime"
+	"sync"
+)
+
+// MaxWorkers is how many files are hashed or compressed for zipping at the
+// same time.
+var MaxWorkers = runtime.NumCPU()
+
+type walkedFile struct {
+	fileName string
+	fullPath string
+}
+
+type workResult struct {
+	value interface{}
+	err   error
+}
+
+// walkAppFilesInOrder lists the files WalkAppFilesWithOptions visits, in the
+// order it visits them.
+func walkAppFilesInOrder(dir string, opts IgnoreOptions) (files []walkedFile, err error) {
+	err = WalkAppFilesWithOptions(dir, opts, func(fileName string, fullPath string) error {
+		files = append(files, walkedFile{fileName: fileName, fullPath: fullPath})
+		return nil
+	})
+	return
+}
+
+// processInOrder runs work on every file using at most MaxWorkers goroutines,
+// and passes the results to collect one at a time in the order of files, so
+// that whatever collect builds comes out the same however the work was
+// scheduled. Workers only get ahead of collect by a few files, which keeps
+// the number of results held in memory small. It stops at the first error.
+func processInOrder(files []walkedFile, work func(walkedFile) (interface{}, error), collect func(walkedFile, interface{}) error) error {
+	workers := MaxWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]chan workResult, len(files))
+	for i := range results {
+		results[i] = make(chan workResult, 1)
+	}
+
+	jobs := make(chan int)
+	window := make(chan bool, 2*workers)
+	done := make(chan bool)
+
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case window <- true:
+			case <-done:
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				value, err := work(files[i])
+				results[i] <- workResult{value: value, err: err}
+			}
+		}()
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	for i, file := range files {
+		result := <-results[i]
+		<-window
+
+		if result.err != nil {
+			return result.err
+		}
+
+		err := collect(file, result.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package app_files
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io"
+)
+
+// Go's archive/zip can only write entries it compresses itself, which would
+// leave all of the deflating to the one goroutine that writes the zip.
+// rawZipWriter writes entries that have already been compressed, along with
+// their CRC and sizes, so that the compression can be spread over workers.
+type rawZipWriter struct {
+	out     *countingWriter
+	entries []rawZipEntry
+}
+
+type rawZipEntry struct {
+	header *zip.FileHeader
+	offset uint64
+}
+
+type countingWriter struct {
+	w     io.Writer
+	count uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.count += uint64(n)
+	return n, err
+}
+
+const (
+	zipLocalHeaderSignature          = 0x04034b50
+	zipCentralHeaderSignature        = 0x02014b50
+	zipEndSignature                  = 0x06054b50
+	zip64EndSignature                = 0x06064b50
+	zip64LocatorSignature            = 0x07064b50
+	zip64ExtraTag                    = 0x0001
+	zipVersion20                     = 20
+	zipVersion45                     = 45
+	zipMax16                         = 0xffff
+	zipMax32                         = 0xffffffff
+	zipLocalHeaderLen                = 30
+	zipCentralHeaderLen              = 46
+	zipEndLen                        = 22
+	zip64EndLen                      = 56
+	zip64LocatorLen                  = 20
+	zipFlagUTF8               uint16 = 0x800
+)
+
+func newRawZipWriter(w io.Writer) *rawZipWriter {
+	return &rawZipWriter{out: &countingWriter{w: w}}
+}
+
+// WriteEntry writes the local header for header, whose CRC32 and sizes must
+// already be set, followed by the contents copied from compressed.
+func (zw *rawZipWriter) WriteEntry(header *zip.FileHeader, compressed io.Reader) error {
+	zw.entries = append(zw.entries, rawZipEntry{header: header, offset: zw.out.count})
+
+	needsZip64 := isZip64(header)
+	extra := []byte{}
+	if needsZip64 {
+		extra = make([]byte, 20)
+		b := zipBuffer(extra)
+		b.uint16(zip64ExtraTag)
+		b.uint16(16)
+		b.uint64(header.UncompressedSize64)
+		b.uint64(header.CompressedSize64)
+	}
+
+	buf := make([]byte, zipLocalHeaderLen)
+	b := zipBuffer(buf)
+	b.uint32(zipLocalHeaderSignature)
+	b.uint16(versionNeeded(needsZip64))
+	b.uint16(flags(header))
+	b.uint16(header.Method)
+	b.uint16(header.ModifiedTime)
+	b.uint16(header.ModifiedDate)
+	b.uint32(header.CRC32)
+	if needsZip64 {
+		b.uint32(zipMax32)
+		b.uint32(zipMax32)
+	} else {
+		b.uint32(uint32(header.CompressedSize64))
+		b.uint32(uint32(header.UncompressedSize64))
+	}
+	b.uint16(uint16(len(header.Name)))
+	b.uint16(uint16(len(extra)))
+
+	for _, part := range [][]byte{buf, []byte(header.Name), extra} {
+		if _, err := zw.out.Write(part); err != nil {
+			return err
+		}
+	}
+
+	if compressed == nil {
+		return nil
+	}
+	_, err := io.Copy(zw.out, compressed)
+	return err
+}
+
+// Close writes the central directory. It does not close the underlying writer.
+func (zw *rawZipWriter) Close() error {
+	start := zw.out.count
+
+	for _, entry := range zw.entries {
+		header := entry.header
+		needsZip64 := isZip64(header) || entry.offset >= zipMax32
+
+		extra := []byte{}
+		if needsZip64 {
+			extra = make([]byte, 28)
+			b := zipBuffer(extra)
+			b.uint16(zip64ExtraTag)
+			b.uint16(24)
+			b.uint64(header.UncompressedSize64)
+			b.uint64(header.CompressedSize64)
+			b.uint64(entry.offset)
+		}
+
+		buf := make([]byte, zipCentralHeaderLen)
+		b := zipBuffer(buf)
+		b.uint32(zipCentralHeaderSignature)
+		b.uint16(header.CreatorVersion&0xff00 | zipVersion20)
+		b.uint16(versionNeeded(needsZip64))
+		b.uint16(flags(header))
+		b.uint16(header.Method)
+		b.uint16(header.ModifiedTime)
+		b.uint16(header.ModifiedDate)
+		b.uint32(header.CRC32)
+		if needsZip64 {
+			b.uint32(zipMax32)
+			b.uint32(zipMax32)
+		} else {
+			b.uint32(uint32(header.CompressedSize64))
+			b.uint32(uint32(header.UncompressedSize64))
+		}
+		b.uint16(uint16(len(header.Name)))
+		b.uint16(uint16(len(extra)))
+		b.uint16(0) // comment length
+		b.uint16(0) // disk number start
+		b.uint16(0) // internal attributes
+		b.uint32(header.ExternalAttrs)
+		if needsZip64 {
+			b.uint32(zipMax32)
+		} else {
+			b.uint32(uint32(entry.offset))
+		}
+
+		for _, part := range [][]byte{buf, []byte(header.Name), extra} {
+			if _, err := zw.out.Write(part); err != nil {
+				return err
+			}
+		}
+	}
+
+	end := zw.out.count
+	records := uint64(len(zw.entries))
+	size := end - start
+	offset := start
+
+	if records >= zipMax16 || size >= zipMax32 || offset >= zipMax32 {
+		buf := make([]byte, zip64EndLen+zip64LocatorLen)
+		b := zipBuffer(buf)
+		b.uint32(zip64EndSignature)
+		b.uint64(zip64EndLen - 12)
+		b.uint16(zipVersion45) // version made by
+		b.uint16(zipVersion45) // version needed to extract
+		b.uint32(0)            // number of this disk
+		b.uint32(0)            // disk with the start of the central directory
+		b.uint64(records)
+		b.uint64(records)
+		b.uint64(size)
+		b.uint64(offset)
+
+		b.uint32(zip64LocatorSignature)
+		b.uint32(0) // disk with the zip64 end record
+		b.uint64(end)
+		b.uint32(1) // total number of disks
+
+		if _, err := zw.out.Write(buf); err != nil {
+			return err
+		}
+
+		records = zipMax16
+		size = zipMax32
+		offset = zipMax32
+	}
+
+	buf := make([]byte, zipEndLen)
+	b := zipBuffer(buf)
+	b.uint32(zipEndSignature)
+	b.uint16(0) // number of this disk
+	b.uint16(0) // disk with the start of the central directory
+	b.uint16(uint16(records))
+	b.uint16(uint16(records))
+	b.uint32(uint32(size))
+	b.uint32(uint32(offset))
+	b.uint16(0) // comment length
+
+	_, err := zw.out.Write(buf)
+	return err
+}
+
+func isZip64(header *zip.FileHeader) bool {
+	return header.CompressedSize64 >= zipMax32 || header.UncompressedSize64 >= zipMax32
+}
+
+func versionNeeded(zip64 bool) uint16 {
+	if zip64 {
+		return zipVersion45
+	}
+	return zipVersion20
+}
+
+func flags(header *zip.FileHeader) uint16 {
+	for _, r := range header.Name {
+		if r >= 0x80 {
+			return header.Flags | zipFlagUTF8
+		}
+	}
+	return header.Flags
+}
+
+type zipBuffer []byte
+
+func (b *zipBuffer) uint16(v uint16) {
+	binary.LittleEndian.PutUint16(*b, v)
+	*b = (*b)[2:]
+}
+
+func (b *zipBuffer) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(*b, v)
+	*b = (*b)[4:]
+}
+
+func (b *zipBuffer) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(*b, v)
+	*b = (*b)[8:]
+}
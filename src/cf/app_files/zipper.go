@@ -1,16 +1,27 @@
 package app_files
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
 	"cf/errors"
+	"compress/flate"
+	"compress/gzip"
 	"github.com/cloudfoundry/gofileutils/fileutils"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 type Zipper interface {
 	Zip(dirToZip string, targetFile *os.File) (err error)
 	IsZipFile(path string) bool
+	IsTarFile(path string) bool
 }
 
 type ApplicationZipper struct{}
@@ -26,10 +37,58 @@ func (zipper ApplicationZipper) Zip(dirOrZipFile string, targetFile *os.File) (e
 }
 
 func (zipper ApplicationZipper) IsZipFile(file string) (result bool) {
-	_, err := zip.OpenReader(file)
+	reader, err := zip.OpenReader(file)
+	if err != nil {
+		return false
+	}
+	reader.Close()
+	return true
+}
+
+// IsTarFile is true for tar files, whether they are gzipped or not. Like zip
+// files, they are recognised by their contents rather than their extension.
+func (zipper ApplicationZipper) IsTarFile(file string) bool {
+	tarFile, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer tarFile.Close()
+
+	reader, err := NewTarReader(tarFile)
+	if err != nil {
+		return false
+	}
+
+	_, err = reader.Next()
 	return err == nil
 }
 
+// NewTarReader reads the tar file in file, ungzipping it first if needed.
+func NewTarReader(file io.Reader) (*tar.Reader, error) {
+	bufferedFile := bufio.NewReader(file)
+
+	magic, err := bufferedFile.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedFile)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(gzipReader), nil
+	}
+
+	return tar.NewReader(bufferedFile), nil
+}
+
+// Files larger than this are compressed into a temp file rather than into
+// memory, so that they are not held in memory while they wait to be written.
+const maxPrereadFileSize = 4 * 1024 * 1024
+
+type zipEntry struct {
+	header         *zip.FileHeader
+	compressed     []byte
+	compressedPath string
+}
+
 func writeZipFile(dir string, targetFile *os.File) error {
 	isEmpty, err := fileutils.IsDirEmpty(dir)
 	if err != nil {
@@ -40,32 +99,170 @@ func writeZipFile(dir string, targetFile *os.File) error {
 		return errors.NewEmptyDirError(dir)
 	}
 
-	writer := zip.NewWriter(targetFile)
-	defer writer.Close()
+	files, err := walkAppFilesInOrder(dir, IgnoreOptions{})
+	if err != nil {
+		return err
+	}
 
-	return WalkAppFiles(dir, func(fileName string, fullPath string) error {
-		fileInfo, err := os.Stat(fullPath)
-		if err != nil {
-			return err
+	compressors := newCompressorPool()
+	writer := newRawZipWriter(targetFile)
+
+	// Entries compressed into temp files are removed once they are written,
+	// but workers can still be ahead of the writer when it stops on an error.
+	tempFiles := []string{}
+	tempFilesMutex := &sync.Mutex{}
+	defer func() {
+		for _, path := range tempFiles {
+			os.Remove(path)
+		}
+	}()
+
+	err = processInOrder(files, func(file walkedFile) (interface{}, error) {
+		entry, err := compressFile(file, compressors)
+		if err == nil && entry.compressedPath != "" {
+			tempFilesMutex.Lock()
+			tempFiles = append(tempFiles, entry.compressedPath)
+			tempFilesMutex.Unlock()
+		}
+		return entry, err
+	}, func(file walkedFile, result interface{}) error {
+		entry := result.(zipEntry)
+
+		if entry.compressedPath == "" {
+			return writer.WriteEntry(entry.header, bytes.NewReader(entry.compressed))
 		}
 
-		header, err := zip.FileInfoHeader(fileInfo)
+		compressedFile, err := os.Open(entry.compressedPath)
 		if err != nil {
 			return err
 		}
+		defer func() {
+			compressedFile.Close()
+			os.Remove(entry.compressedPath)
+		}()
+		return writer.WriteEntry(entry.header, compressedFile)
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
 
-		header.Name = filepath.ToSlash(fileName)
+// compressFile makes the zip entry for file, deflating its contents unless it
+// is a directory. Symlinks are stored as symlinks, with the path they point
+// to as their contents.
+func compressFile(file walkedFile, compressors compressorPool) (entry zipEntry, err error) {
+	fileInfo, err := os.Lstat(file.fullPath)
+	if err != nil {
+		return
+	}
 
-		if fileInfo.IsDir() {
-			header.Name += "/"
+	header, err := zip.FileInfoHeader(fileInfo)
+	if err != nil {
+		return
+	}
+
+	header.Name = filepath.ToSlash(file.fileName)
+	header.UncompressedSize64 = 0
+	entry.header = header
+
+	if fileInfo.IsDir() {
+		header.Name += "/"
+		header.Method = zip.Store
+		return
+	}
+
+	header.Method = zip.Deflate
+
+	if isSymlink(fileInfo) {
+		var target string
+		target, err = os.Readlink(file.fullPath)
+		if err != nil {
+			return
 		}
 
-		zipFilePart, err := writer.CreateHeader(header)
+		buffer := &bytes.Buffer{}
+		err = compressors.deflate(header, strings.NewReader(target), buffer)
+		entry.compressed = buffer.Bytes()
+		return
+	}
+
+	source, err := os.Open(file.fullPath)
+	if err != nil {
+		return
+	}
+	defer source.Close()
+
+	if fileInfo.Size() <= maxPrereadFileSize {
+		buffer := &bytes.Buffer{}
+		err = compressors.deflate(header, source, buffer)
+		entry.compressed = buffer.Bytes()
+		return
+	}
+
+	tempFile, err := ioutil.TempFile("", "cf-zip-entry")
+	if err != nil {
+		return
+	}
+	defer tempFile.Close()
+
+	err = compressors.deflate(header, source, tempFile)
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return
+	}
 
-		if fileInfo.IsDir() {
-			return nil
-		} else {
-			return fileutils.CopyPathToWriter(fullPath, zipFilePart)
+	entry.compressedPath = tempFile.Name()
+	return
+}
+
+// compressorPool keeps one flate writer for each worker, so that a zip with
+// many small files does not allocate a new compressor for every one of them.
+type compressorPool chan *flate.Writer
+
+func newCompressorPool() compressorPool {
+	workers := MaxWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	return make(compressorPool, workers)
+}
+
+// deflate compresses source into dest, and records the CRC and the sizes of
+// the entry in header.
+func (pool compressorPool) deflate(header *zip.FileHeader, source io.Reader, dest io.Writer) (err error) {
+	compressed := &countingWriter{w: dest}
+
+	var compressor *flate.Writer
+	select {
+	case compressor = <-pool:
+		compressor.Reset(compressed)
+	default:
+		compressor, err = flate.NewWriter(compressed, flate.DefaultCompression)
+		if err != nil {
+			return
 		}
-	})
+	}
+
+	checksum := crc32.NewIEEE()
+	uncompressed, err := io.Copy(io.MultiWriter(compressor, checksum), source)
+	if err != nil {
+		return
+	}
+
+	err = compressor.Close()
+	if err != nil {
+		return
+	}
+
+	select {
+	case pool <- compressor:
+	default:
+	}
+
+	header.CRC32 = checksum.Sum32()
+	header.UncompressedSize64 = uint64(uncompressed)
+	header.CompressedSize64 = compressed.count
+	return
 }
@@ -1,9 +1,13 @@
 package app_files_test
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
 	. "cf/app_files"
+	"cf/models"
+	"compress/gzip"
+	"fmt"
 	"github.com/cloudfoundry/gofileutils/fileutils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,6 +15,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 )
 
 func readFile(file *os.File) []byte {
@@ -93,6 +99,39 @@ var _ = Describe("Zipper", func() {
 		})
 	})
 
+	It("recognises tar files by their contents, whether they are gzipped or not", func() {
+		fileutils.TempDir("tar_test", func(dir string, err error) {
+			tarPath := filepath.Join(dir, "app.tar")
+			tgzPath := filepath.Join(dir, "app.bin")
+			writeTar := func(path string, output func(io.Writer) io.WriteCloser) {
+				file, err := os.Create(path)
+				Expect(err).NotTo(HaveOccurred())
+				defer file.Close()
+
+				compressed := output(file)
+				defer compressed.Close()
+
+				writer := tar.NewWriter(compressed)
+				err = writer.WriteHeader(&tar.Header{Name: "app.rb", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+				Expect(err).NotTo(HaveOccurred())
+				_, err = writer.Write([]byte("puts"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writer.Close()).To(Succeed())
+			}
+
+			writeTar(tarPath, func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} })
+			writeTar(tgzPath, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+			zipper := ApplicationZipper{}
+			Expect(zipper.IsTarFile(tarPath)).To(BeTrue())
+			Expect(zipper.IsTarFile(tgzPath)).To(BeTrue())
+			Expect(zipper.IsTarFile(dir)).To(BeFalse())
+			Expect(zipper.IsTarFile("../../fixtures/applications/example-app.zip")).To(BeFalse())
+			Expect(zipper.IsTarFile("../../fixtures/applications/example-app/Gemfile")).To(BeFalse())
+			Expect(zipper.IsZipFile(tgzPath)).To(BeFalse())
+		})
+	})
+
 	It("returns an error when zipping fails", func() {
 		fileutils.TempFile("zip_test", func(zipFile *os.File, err error) {
 			zipper := ApplicationZipper{}
@@ -112,4 +151,229 @@ var _ = Describe("Zipper", func() {
 			})
 		})
 	})
+
+	Describe("zipping with several workers", func() {
+		var (
+			dir             string
+			originalWorkers int
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "zip_test")
+			Expect(err).NotTo(HaveOccurred())
+			writeAppTree(dir, 5, 40)
+
+			originalWorkers = MaxWorkers
+		})
+
+		AfterEach(func() {
+			MaxWorkers = originalWorkers
+			os.RemoveAll(dir)
+		})
+
+		zipEntries := func(workers int) (entries []string) {
+			MaxWorkers = workers
+
+			fileutils.TempFile("zip_test", func(zipFile *os.File, err error) {
+				err = ApplicationZipper{}.Zip(dir, zipFile)
+				Expect(err).NotTo(HaveOccurred())
+
+				stat, err := zipFile.Stat()
+				Expect(err).NotTo(HaveOccurred())
+				reader, err := zip.NewReader(zipFile, stat.Size())
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, file := range reader.File {
+					contents, err := file.Open()
+					Expect(err).NotTo(HaveOccurred())
+					data, err := ioutil.ReadAll(contents)
+					Expect(err).NotTo(HaveOccurred())
+					entries = append(entries, file.Name+":"+string(data))
+				}
+			})
+			return
+		}
+
+		It("writes the same entries in the same order as a single worker", func() {
+			entries := zipEntries(1)
+			Expect(entries).To(HaveLen(5 + 5*40))
+			Expect(zipEntries(8)).To(Equal(entries))
+		})
+
+		It("compresses files too big to keep in memory", func() {
+			bigContents := strings.Repeat("a line of a big file\n", 300000)
+			err := ioutil.WriteFile(filepath.Join(dir, "dir-0", "big.txt"), []byte(bigContents), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			entries := zipEntries(4)
+			Expect(entries).To(ContainElement("dir-0/big.txt:" + bigContents))
+		})
+
+		It("lists the same app files in the same order as a single worker", func() {
+			MaxWorkers = 1
+			files, err := AppFilesInDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			MaxWorkers = 8
+			Expect(AppFilesInDir(dir)).To(Equal(files))
+			Expect(CountFiles(dir)).To(Equal(uint64(len(files))))
+		})
+
+		It("returns the error of a file that cannot be read", func() {
+			err := os.Chmod(filepath.Join(dir, "dir-2", "file-7.txt"), 0)
+			Expect(err).NotTo(HaveOccurred())
+			if os.Getuid() == 0 {
+				Skip("root can read files without permissions")
+			}
+
+			MaxWorkers = 8
+			fileutils.TempFile("zip_test", func(zipFile *os.File, err error) {
+				err = ApplicationZipper{}.Zip(dir, zipFile)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("file-7.txt"))
+			})
+		})
+	})
+
+	Describe("zipping symlinks and file modes", func() {
+		var dir string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("symlinks and executable bits are Unix only")
+			}
+
+			var err error
+			dir, err = ioutil.TempDir("", "zip_test")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = os.MkdirAll(filepath.Join(dir, "node_modules", "mocha", "bin"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(dir, "node_modules", "mocha", "bin", "mocha"), []byte("#!/usr/bin/env node"), 0755)
+			Expect(err).NotTo(HaveOccurred())
+			err = os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+			err = os.Symlink("../mocha/bin/mocha", filepath.Join(dir, "node_modules", ".bin", "mocha"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		zipFiles := func() (files map[string]*zip.File, contents map[string]string) {
+			files = map[string]*zip.File{}
+			contents = map[string]string{}
+
+			fileutils.TempFile("zip_test", func(zipFile *os.File, err error) {
+				err = ApplicationZipper{}.Zip(dir, zipFile)
+				Expect(err).NotTo(HaveOccurred())
+
+				stat, err := zipFile.Stat()
+				Expect(err).NotTo(HaveOccurred())
+				reader, err := zip.NewReader(zipFile, stat.Size())
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, file := range reader.File {
+					fileReader, err := file.Open()
+					Expect(err).NotTo(HaveOccurred())
+					data, err := ioutil.ReadAll(fileReader)
+					Expect(err).NotTo(HaveOccurred())
+
+					files[file.Name] = file
+					contents[file.Name] = string(data)
+				}
+			})
+			return
+		}
+
+		It("stores symlinks as symlinks rather than copies of what they point to", func() {
+			files, contents := zipFiles()
+
+			link := files["node_modules/.bin/mocha"]
+			Expect(link).NotTo(BeNil())
+			Expect(link.Mode() & os.ModeSymlink).NotTo(BeZero())
+			Expect(contents["node_modules/.bin/mocha"]).To(Equal("../mocha/bin/mocha"))
+		})
+
+		It("keeps the executable bits of files", func() {
+			files, contents := zipFiles()
+
+			script := files["node_modules/mocha/bin/mocha"]
+			Expect(script.Mode().Perm()).To(Equal(os.FileMode(0755)))
+			Expect(contents["node_modules/mocha/bin/mocha"]).To(Equal("#!/usr/bin/env node"))
+		})
+
+		It("rejects symlinks that point outside the app directory", func() {
+			err := os.Symlink("../../../etc/passwd", filepath.Join(dir, "node_modules", "passwd"))
+			Expect(err).NotTo(HaveOccurred())
+
+			fileutils.TempFile("zip_test", func(zipFile *os.File, err error) {
+				err = ApplicationZipper{}.Zip(dir, zipFile)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("node_modules/passwd points outside the app directory"))
+			})
+		})
+
+		It("rejects symlinks to absolute paths", func() {
+			err := os.Symlink(filepath.Join(dir, "node_modules"), filepath.Join(dir, "modules"))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = AppFilesInDir(dir)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("modules points outside the app directory"))
+		})
+
+		It("lists symlinks as app files that are always uploaded", func() {
+			files, err := AppFilesInDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(ContainElement(models.AppFileFields{Path: "node_modules/.bin/mocha", Sha1: "0", Size: int64(len("../mocha/bin/mocha"))}))
+		})
+
+		It("copies symlinks as symlinks", func() {
+			files, err := AppFilesInDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			fileutils.TempDir("copy_test", func(copyDir string, err error) {
+				err = CopyFiles(files, dir, copyDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				target, err := os.Readlink(filepath.Join(copyDir, "node_modules", ".bin", "mocha"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(target).To(Equal("../mocha/bin/mocha"))
+
+				stat, err := os.Stat(filepath.Join(copyDir, "node_modules", "mocha", "bin", "mocha"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0755)))
+			})
+		})
+	})
 })
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// writeAppTree fills dir with numDirs directories of filesPerDir small files.
+func writeAppTree(dir string, numDirs, filesPerDir int) {
+	for d := 0; d < numDirs; d++ {
+		subDir := filepath.Join(dir, fmt.Sprintf("dir-%d", d))
+		err := os.MkdirAll(subDir, os.ModePerm)
+		if err != nil {
+			panic(err)
+		}
+
+		for f := 0; f < filesPerDir; f++ {
+			contents := strings.Repeat(fmt.Sprintf("file %d in dir %d\n", f, d), 50)
+			err = ioutil.WriteFile(filepath.Join(subDir, fmt.Sprintf("file-%d.txt", f)), []byte(contents), 0644)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+}
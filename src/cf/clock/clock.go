@@ -0,0 +1,41 @@
+package clock
+
+import (
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Clock is where commands that run until Ctrl-C is pressed, such as
+// app --watch, get their ticks and interrupts from.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Ticker returns a channel that receives the time every interval, and a
+	// func that stops it.
+	Ticker(interval time.Duration) (ticks <-chan time.Time, stop func())
+	// Interrupts returns a channel that receives Ctrl-C, and a func that
+	// stops it.
+	Interrupts() (interrupts <-chan os.Signal, stop func())
+}
+
+type systemClock struct{}
+
+func NewClock() Clock {
+	return systemClock{}
+}
+
+func (clock systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (clock systemClock) Ticker(interval time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+func (clock systemClock) Interrupts() (<-chan os.Signal, func()) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	return interrupts, func() { signal.Stop(interrupts) }
+}
@@ -2,6 +2,7 @@ package command_factory
 
 import (
 	"cf/api"
+	"cf/clock"
 	"cf/command"
 	"cf/command_metadata"
 	"cf/commands"
@@ -17,6 +18,7 @@ import (
 	"cf/commands/space"
 	"cf/commands/user"
 	"cf/configuration"
+	"cf/hooks"
 	"cf/manifest"
 	"cf/terminal"
 	"errors"
@@ -39,6 +41,8 @@ func NewFactory(ui terminal.UI, config configuration.ReadWriter, manifestRepo ma
 	factory.cmdsByName["apps"] = application.NewListApps(ui, config, repoLocator.GetAppSummaryRepository())
 	factory.cmdsByName["auth"] = commands.NewAuthenticate(ui, config, repoLocator.GetAuthenticationRepository())
 	factory.cmdsByName["buildpacks"] = buildpack.NewListBuildpacks(ui, repoLocator.GetBuildpackRepository())
+	factory.cmdsByName["validate-manifest"] = application.NewValidateManifest(ui, manifestRepo)
+	factory.cmdsByName["create-app-manifest"] = application.NewCreateAppManifest(ui, config, repoLocator.GetAppSummaryRepository(), repoLocator.GetStackRepository(), manifestRepo)
 	factory.cmdsByName["create-buildpack"] = buildpack.NewCreateBuildpack(ui, repoLocator.GetBuildpackRepository(), repoLocator.GetBuildpackBitsRepository())
 	factory.cmdsByName["create-domain"] = domain.NewCreateDomain(ui, config, repoLocator.GetDomainRepository())
 	factory.cmdsByName["create-org"] = organization.NewCreateOrg(ui, config, repoLocator.GetOrganizationRepository())
@@ -63,6 +67,7 @@ func NewFactory(ui terminal.UI, config configuration.ReadWriter, manifestRepo ma
 	factory.cmdsByName["env"] = application.NewEnv(ui, config)
 	factory.cmdsByName["events"] = application.NewEvents(ui, config, repoLocator.GetAppEventsRepository())
 	factory.cmdsByName["files"] = application.NewFiles(ui, config, repoLocator.GetAppFilesRepository())
+	factory.cmdsByName["download-files"] = application.NewDownloadFiles(ui, config, repoLocator.GetAppFilesRepository())
 	factory.cmdsByName["login"] = commands.NewLogin(ui, config, repoLocator.GetAuthenticationRepository(), repoLocator.GetEndpointRepository(), repoLocator.GetOrganizationRepository(), repoLocator.GetSpaceRepository())
 	factory.cmdsByName["logout"] = commands.NewLogout(ui, config)
 	factory.cmdsByName["logs"] = application.NewLogs(ui, config, repoLocator.GetLogsRepository())
@@ -90,6 +95,7 @@ func NewFactory(ui terminal.UI, config configuration.ReadWriter, manifestRepo ma
 	factory.cmdsByName["services"] = service.NewListServices(ui, config, repoLocator.GetServiceSummaryRepository())
 	factory.cmdsByName["migrate-service-instances"] = service.NewMigrateServiceInstances(ui, config, repoLocator.GetServiceRepository())
 	factory.cmdsByName["set-env"] = application.NewSetEnv(ui, config, repoLocator.GetApplicationRepository())
+	factory.cmdsByName["set-health-check"] = application.NewSetHealthCheck(ui, config, repoLocator.GetApplicationRepository())
 	factory.cmdsByName["set-org-role"] = user.NewSetOrgRole(ui, config, repoLocator.GetUserRepository())
 	factory.cmdsByName["set-quota"] = organization.NewSetQuota(ui, config, repoLocator.GetQuotaRepository())
 	factory.cmdsByName["create-shared-domain"] = domain.NewCreateSharedDomain(ui, config, repoLocator.GetDomainRepository())
@@ -112,10 +118,10 @@ func NewFactory(ui terminal.UI, config configuration.ReadWriter, manifestRepo ma
 	factory.cmdsByName["map-route"] = route.NewMapRoute(ui, config, repoLocator.GetRouteRepository(), createRoute)
 	factory.cmdsByName["unmap-route"] = route.NewUnmapRoute(ui, config, repoLocator.GetRouteRepository())
 
-	displayApp := application.NewShowApp(ui, config, repoLocator.GetAppSummaryRepository(), repoLocator.GetAppInstancesRepository())
+	displayApp := application.NewShowApp(ui, config, repoLocator.GetAppSummaryRepository(), repoLocator.GetAppInstancesRepository(), clock.NewClock())
 	start := application.NewStart(ui, config, displayApp, repoLocator.GetApplicationRepository(), repoLocator.GetAppInstancesRepository(), repoLocator.GetLogsRepository())
 	stop := application.NewStop(ui, config, repoLocator.GetApplicationRepository())
-	restart := application.NewRestart(ui, start, stop)
+	restart := application.NewRestart(ui, config, start, stop, repoLocator.GetAppInstancesRepository())
 	bind := service.NewBindService(ui, config, repoLocator.GetServiceBindingRepository())
 
 	factory.cmdsByName["app"] = displayApp
@@ -123,18 +129,30 @@ func NewFactory(ui terminal.UI, config configuration.ReadWriter, manifestRepo ma
 	factory.cmdsByName["start"] = start
 	factory.cmdsByName["stop"] = stop
 	factory.cmdsByName["restart"] = restart
+	factory.cmdsByName["restart-app-instance"] = application.NewRestartAppInstance(ui, config, repoLocator.GetAppInstancesRepository())
+	factory.cmdsByName["restage"] = application.NewRestage(ui, config, repoLocator.GetApplicationRepository(), start)
+	factory.cmdsByName["copy-source"] = application.NewCopySource(
+		ui, config,
+		repoLocator.GetApplicationRepository(),
+		repoLocator.GetApplicationBitsRepository(),
+		repoLocator.GetOrganizationRepository(),
+		repoLocator.GetSpaceRepository(),
+		restart)
 	factory.cmdsByName["push"] = application.NewPush(
 		ui, config, manifestRepo, start, stop, bind,
 		repoLocator.GetApplicationRepository(),
+		repoLocator.GetAppSummaryRepository(),
 		repoLocator.GetDomainRepository(),
 		repoLocator.GetRouteRepository(),
 		repoLocator.GetStackRepository(),
 		repoLocator.GetServiceRepository(),
 		repoLocator.GetApplicationBitsRepository(),
 		repoLocator.GetAuthenticationRepository(),
-		words.NewWordGenerator())
+		words.NewWordGenerator(),
+		hooks.NewRunner())
 
 	factory.cmdsByName["scale"] = application.NewScale(ui, config, restart, repoLocator.GetApplicationRepository())
+	factory.cmdsByName["autoscale"] = application.NewAutoscale(ui, config, repoLocator.GetApplicationRepository(), repoLocator.GetAppInstancesRepository(), clock.NewClock())
 
 	spaceRoleSetter := user.NewSetSpaceRole(ui, config, repoLocator.GetSpaceRepository(), repoLocator.GetUserRepository())
 	factory.cmdsByName["set-space-role"] = spaceRoleSetter
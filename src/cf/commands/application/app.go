@@ -2,9 +2,11 @@ package application
 
 import (
 	"cf/api"
+	"cf/clock"
 	"cf/command_metadata"
 	"cf/configuration"
 	"cf/errors"
+	"cf/flag_helpers"
 	"cf/formatters"
 	"cf/models"
 	"cf/requirements"
@@ -13,6 +15,12 @@ import (
 	"fmt"
 	"github.com/codegangsta/cli"
 	"strings"
+	"time"
+)
+
+const (
+	DefaultWatchInterval = 2 * time.Second
+	watchHistoryLength   = 20
 )
 
 type ShowApp struct {
@@ -21,18 +29,20 @@ type ShowApp struct {
 	appSummaryRepo   api.AppSummaryRepository
 	appInstancesRepo api.AppInstancesRepository
 	appReq           requirements.ApplicationRequirement
+	clock            clock.Clock
 }
 
 type ApplicationDisplayer interface {
 	ShowApp(app models.Application)
 }
 
-func NewShowApp(ui terminal.UI, config configuration.Reader, appSummaryRepo api.AppSummaryRepository, appInstancesRepo api.AppInstancesRepository) (cmd *ShowApp) {
+func NewShowApp(ui terminal.UI, config configuration.Reader, appSummaryRepo api.AppSummaryRepository, appInstancesRepo api.AppInstancesRepository, clock clock.Clock) (cmd *ShowApp) {
 	cmd = new(ShowApp)
 	cmd.ui = ui
 	cmd.config = config
 	cmd.appSummaryRepo = appSummaryRepo
 	cmd.appInstancesRepo = appInstancesRepo
+	cmd.clock = clock
 	return
 }
 
@@ -40,7 +50,11 @@ func (cmd *ShowApp) Metadata() command_metadata.CommandMetadata {
 	return command_metadata.CommandMetadata{
 		Name:        "app",
 		Description: "Display health and status for app",
-		Usage:       "CF_NAME app APP",
+		Usage: "CF_NAME app APP [--watch[=INTERVAL]]\n\n" +
+			"   INTERVAL is a number of seconds or a duration such as 500ms (default 2s)",
+		Flags: []cli.Flag{
+			flag_helpers.NewOptionalDurationFlag("watch", "Refresh the instance stats in place until Ctrl-C is pressed", DefaultWatchInterval),
+		},
 	}
 }
 
@@ -63,6 +77,13 @@ func (cmd *ShowApp) GetRequirements(requirementsFactory requirements.Factory, c
 
 func (cmd *ShowApp) Run(c *cli.Context) {
 	app := cmd.appReq.GetApplication()
+
+	if watch, ok := c.Generic("watch").(*flag_helpers.OptionalDuration); ok && watch.IsSet {
+		cmd.watch(app, watch.Value)
+		cmd.ui.Say("\nStopped watching app %s", terminal.EntityNameColor(app.Name))
+		return
+	}
+
 	cmd.ShowApp(app)
 }
 
@@ -75,9 +96,49 @@ func (cmd *ShowApp) ShowApp(app models.Application) {
 		terminal.EntityNameColor(cmd.config.Username()),
 	)
 
-	application, apiErr := cmd.appSummaryRepo.GetSummary(app.Guid)
+	application, instances, appIsStopped, apiErr := cmd.getSummaryAndInstances(app)
+	if apiErr != nil {
+		cmd.ui.Failed(apiErr.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+	sayAppSummary(cmd.ui, application)
+
+	var urls []string
+	for _, route := range application.Routes {
+		urls = append(urls, route.URL())
+	}
+
+	cmd.ui.Say("%s %s\n", terminal.HeaderColor("urls:"), strings.Join(urls, ", "))
+
+	if appIsStopped {
+		cmd.ui.Say("There are no running instances of this app.")
+		return
+	}
+
+	table := terminal.NewTable(cmd.ui, []string{"", "state", "since", "cpu", "memory", "disk"})
+	rows := [][]string{}
 
-	appIsStopped := (application.State == "stopped")
+	for index, instance := range instances {
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", index),
+			ui_helpers.ColoredInstanceState(instance),
+			instance.Since.Format("2006-01-02 03:04:05 PM"),
+			fmt.Sprintf("%.1f%%", instance.CpuUsage*100),
+			fmt.Sprintf("%s of %s", formatters.ByteSize(instance.MemUsage), formatters.ByteSize(instance.MemQuota)),
+			fmt.Sprintf("%s of %s", formatters.ByteSize(instance.DiskUsage), formatters.ByteSize(instance.DiskQuota)),
+		})
+	}
+
+	table.Print(rows)
+}
+
+func (cmd *ShowApp) getSummaryAndInstances(app models.Application) (application models.Application, instances []models.AppInstanceFields, appIsStopped bool, apiErr error) {
+	application, apiErr = cmd.appSummaryRepo.GetSummary(app.Guid)
+
+	appIsStopped = (application.State == "stopped")
 	if err, ok := apiErr.(errors.HttpError); ok {
 		if err.ErrorCode() == errors.APP_STOPPED || err.ErrorCode() == errors.APP_NOT_STAGED {
 			appIsStopped = true
@@ -85,47 +146,188 @@ func (cmd *ShowApp) ShowApp(app models.Application) {
 	}
 
 	if apiErr != nil && !appIsStopped {
-		cmd.ui.Failed(apiErr.Error())
 		return
 	}
 
-	var instances []models.AppInstanceFields
 	instances, apiErr = cmd.appInstancesRepo.GetInstances(app.Guid)
-	if apiErr != nil && !appIsStopped {
-		cmd.ui.Failed(apiErr.Error())
-		return
+	if appIsStopped {
+		apiErr = nil
 	}
+	return
+}
 
-	cmd.ui.Ok()
-	cmd.ui.Say("\n%s %s", terminal.HeaderColor("requested state:"), ui_helpers.ColoredAppState(application.ApplicationFields))
-	cmd.ui.Say("%s %s", terminal.HeaderColor("instances:"), ui_helpers.ColoredAppInstances(application.ApplicationFields))
-	cmd.ui.Say("%s %s x %d instances", terminal.HeaderColor("usage:"), formatters.ByteSize(application.Memory*formatters.MEGABYTE), application.InstanceCount)
+func sayAppSummary(ui terminal.Sayer, application models.Application) {
+	ui.Say("%s %s", terminal.HeaderColor("requested state:"), ui_helpers.ColoredAppState(application.ApplicationFields))
+	ui.Say("%s %s", terminal.HeaderColor("instances:"), ui_helpers.ColoredAppInstances(application.ApplicationFields))
+	ui.Say("%s %s x %d instances", terminal.HeaderColor("usage:"), formatters.ByteSize(application.Memory*formatters.MEGABYTE), application.InstanceCount)
+}
 
-	var urls []string
-	for _, route := range application.Routes {
-		urls = append(urls, route.URL())
+// watch redraws the stats of each instance of app every interval, until
+// it is interrupted.
+func (cmd *ShowApp) watch(app models.Application, interval time.Duration) {
+	cmd.ui.Say("Watching health and status for app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+	cmd.ui.Say("Refreshing every %s, press Ctrl-C to stop\n", interval)
+
+	interrupts, stopInterrupts := cmd.clock.Interrupts()
+	defer stopInterrupts()
+
+	ticks, stopTicker := cmd.clock.Ticker(interval)
+	defer stopTicker()
+
+	view := cmd.ui.LiveView()
+	defer view.Close()
+
+	history := instancesHistory{}
+	for {
+		cmd.drawInstances(view, app, history)
+
+		select {
+		case <-interrupts:
+			return
+		case _, ok := <-ticks:
+			if !ok {
+				return
+			}
+		}
 	}
+}
 
-	cmd.ui.Say("%s %s\n", terminal.HeaderColor("urls:"), strings.Join(urls, ", "))
+func (cmd *ShowApp) drawInstances(view terminal.LiveView, app models.Application, history instancesHistory) {
+	defer view.Draw()
+
+	view.Say("%s %s", terminal.HeaderColor("updated:"), time.Now().Format("03:04:05 PM"))
+
+	application, instances, appIsStopped, apiErr := cmd.getSummaryAndInstances(app)
+	if apiErr != nil {
+		view.Say(terminal.FailureColor(apiErr.Error()))
+		return
+	}
+
+	sayAppSummary(view, application)
+	view.Say("")
 
 	if appIsStopped {
-		cmd.ui.Say("There are no running instances of this app.")
+		history.record(nil)
+		view.Say("There are no running instances of this app.")
 		return
 	}
 
-	table := terminal.NewTable(cmd.ui, []string{"", "state", "since", "cpu", "memory", "disk"})
+	history.record(instances)
+
+	table := view.Table([]string{"", "state", "since", "restarts", "cpu", "cpu trend", "memory", "memory trend", "disk"})
 	rows := [][]string{}
+	problems := []string{}
 
 	for index, instance := range instances {
+		stats := history[index]
+
+		restarts := fmt.Sprintf("%d", stats.restarts)
+		if stats.restarts > 0 {
+			restarts = terminal.WarningColor(restarts)
+		}
+
 		rows = append(rows, []string{
 			fmt.Sprintf("#%d", index),
 			ui_helpers.ColoredInstanceState(instance),
 			instance.Since.Format("2006-01-02 03:04:05 PM"),
-			fmt.Sprintf("%.1f%%", instance.CpuUsage*100),
-			fmt.Sprintf("%s of %s", formatters.ByteSize(instance.MemUsage), formatters.ByteSize(instance.MemQuota)),
+			restarts,
+			fmt.Sprintf("%.1f%%%s", instance.CpuUsage*100, stats.cpuDelta()),
+			ui_helpers.Sparkline(stats.cpu),
+			fmt.Sprintf("%s of %s%s", formatters.ByteSize(instance.MemUsage), formatters.ByteSize(instance.MemQuota), stats.memoryDelta()),
+			ui_helpers.Sparkline(stats.memory),
 			fmt.Sprintf("%s of %s", formatters.ByteSize(instance.DiskUsage), formatters.ByteSize(instance.DiskQuota)),
 		})
+
+		switch instance.State {
+		case models.InstanceFlapping, models.InstanceDown:
+			problems = append(problems, terminal.CrashedColor(fmt.Sprintf("#%d is %s", index, decoloredInstanceState(instance))))
+		default:
+			if stats.restarts > 0 {
+				problems = append(problems, terminal.WarningColor(fmt.Sprintf("#%d has restarted %d times while watching", index, stats.restarts)))
+			}
+		}
 	}
 
 	table.Print(rows)
+
+	if len(problems) > 0 {
+		view.Say("")
+		view.Say(strings.Join(problems, "\n"))
+	}
+}
+
+func decoloredInstanceState(instance models.AppInstanceFields) string {
+	if instance.State == models.InstanceFlapping {
+		return "crashing"
+	}
+	return string(instance.State)
+}
+
+// instancesHistory keeps the recent stats of each instance, by index.
+type instancesHistory map[int]*instanceStats
+
+type instanceStats struct {
+	current  models.AppInstanceFields
+	previous *models.AppInstanceFields
+	cpu      []float64
+	memory   []float64
+	restarts int
+}
+
+func (history instancesHistory) record(instances []models.AppInstanceFields) {
+	for index := range history {
+		if index >= len(instances) {
+			delete(history, index)
+		}
+	}
+
+	for index, instance := range instances {
+		stats, found := history[index]
+		if !found {
+			stats = &instanceStats{}
+			history[index] = stats
+		} else {
+			previous := stats.current
+			stats.previous = &previous
+			if !previous.Since.IsZero() && !instance.Since.IsZero() && !instance.Since.Equal(previous.Since) {
+				stats.restarts++
+			}
+		}
+
+		stats.current = instance
+		stats.cpu = appendSample(stats.cpu, instance.CpuUsage*100)
+		stats.memory = appendSample(stats.memory, float64(instance.MemUsage))
+	}
+}
+
+func appendSample(samples []float64, sample float64) []float64 {
+	samples = append(samples, sample)
+	if len(samples) > watchHistoryLength {
+		samples = samples[len(samples)-watchHistoryLength:]
+	}
+	return samples
+}
+
+func (stats *instanceStats) cpuDelta() string {
+	if stats.previous == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.1f)", (stats.current.CpuUsage-stats.previous.CpuUsage)*100)
+}
+
+func (stats *instanceStats) memoryDelta() string {
+	if stats.previous == nil {
+		return ""
+	}
+
+	current, previous := stats.current.MemUsage, stats.previous.MemUsage
+	if current < previous {
+		return fmt.Sprintf(" (-%s)", formatters.ByteSize(previous-current))
+	}
+	return fmt.Sprintf(" (+%s)", formatters.ByteSize(current-previous))
 }
@@ -6,13 +6,16 @@ import (
 	"cf/errors"
 	"cf/formatters"
 	"cf/models"
+	"os"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
+	testclock "testhelpers/clock"
 	testcmd "testhelpers/commands"
 	testconfig "testhelpers/configuration"
 	testreq "testhelpers/requirements"
 	testterm "testhelpers/terminal"
 	testtime "testhelpers/time"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -38,9 +41,16 @@ var _ = Describe("app Command", func() {
 		}
 	})
 
+	var (
+		interrupts chan os.Signal
+		watchTicks chan time.Time
+		clock      *testclock.FakeClock
+	)
+
 	runCommand := func(args ...string) {
-		cmd := NewShowApp(ui, configRepo, appSummaryRepo, appInstancesRepo)
-		testcmd.RunCommand(cmd, testcmd.NewContext("apps", args), requirementsFactory)
+		clock = &testclock.FakeClock{Ticks: watchTicks, Signals: interrupts}
+		cmd := NewShowApp(ui, configRepo, appSummaryRepo, appInstancesRepo, clock)
+		testcmd.RunCommand(cmd, testcmd.NewContext("app", args), requirementsFactory)
 	}
 
 	Describe("requirements", func() {
@@ -152,6 +162,135 @@ var _ = Describe("app Command", func() {
 			})
 		})
 	})
+
+	Describe("--watch", func() {
+		var instance models.AppInstanceFields
+
+		BeforeEach(func() {
+			app := makeAppWithRoute("my-app")
+			appSummaryRepo.GetSummarySummary = app
+			requirementsFactory.Application = app
+
+			instance = models.AppInstanceFields{
+				State:     models.InstanceRunning,
+				Since:     testtime.MustParse("Mon Jan 2 15:04:05 -0700 MST 2006", "Mon Jan 2 15:04:05 -0700 MST 2012"),
+				CpuUsage:  0.1,
+				DiskQuota: 1 * formatters.GIGABYTE,
+				DiskUsage: 32 * formatters.MEGABYTE,
+				MemQuota:  1 * formatters.GIGABYTE,
+				MemUsage:  100 * formatters.MEGABYTE,
+			}
+
+			interrupts = make(chan os.Signal, 1)
+			watchTicks = make(chan time.Time, 10)
+		})
+
+		AfterEach(func() {
+			interrupts = nil
+			watchTicks = nil
+		})
+
+		withStats := func(cpu float64, memory uint64) models.AppInstanceFields {
+			stats := instance
+			stats.CpuUsage = cpu
+			stats.MemUsage = memory * formatters.MEGABYTE
+			return stats
+		}
+
+		It("redraws the instances on each tick, with deltas and trends of cpu and memory", func() {
+			appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+				{withStats(0.1, 100)},
+				{withStats(0.25, 150)},
+				{withStats(0.2, 200)},
+			}
+			watchTicks <- time.Now()
+			watchTicks <- time.Now()
+			close(watchTicks)
+
+			runCommand("--watch", "my-app")
+
+			Expect(clock.TickerInterval).To(Equal(2 * time.Second))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Watching health and status", "my-app", "my-org", "my-space", "my-user"},
+				{"Refreshing every 2s", "Ctrl-C"},
+			})
+
+			Expect(ui.LiveViews).To(HaveLen(1))
+			frames := ui.LiveViews[0].Frames
+			Expect(frames).To(HaveLen(3))
+
+			testassert.SliceContains(frames[0], testassert.Lines{
+				{"updated:"},
+				{"requested state:", "started"},
+				{"restarts", "cpu", "cpu trend", "memory", "memory trend"},
+				{"#0", "running", "2012-01-02 03:04:05 PM", "0", "10.0%", "▁", "100M of 1G", "▁", "32M of 1G"},
+			})
+			testassert.SliceContains(frames[1], testassert.Lines{
+				{"#0", "running", "25.0% (+15.0)", "▁█", "150M of 1G (+50M)", "▁█"},
+			})
+			testassert.SliceContains(frames[2], testassert.Lines{
+				{"#0", "running", "20.0% (-5.0)", "▁█▅", "200M of 1G (+50M)", "▁▄█"},
+			})
+
+			Expect(ui.LiveViews[0].Closed).To(BeTrue())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Stopped watching app", "my-app"},
+			})
+		})
+
+		It("stops when interrupted", func() {
+			appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{{instance}}
+			interrupts <- os.Interrupt
+
+			runCommand("--watch=5", "my-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Refreshing every 5s"},
+				{"Stopped watching app", "my-app"},
+			})
+			Expect(ui.LiveViews[0].Frames).To(HaveLen(1))
+			Expect(ui.LiveViews[0].Closed).To(BeTrue())
+		})
+
+		It("points out instances that are down, crashing or have restarted", func() {
+			restarted := instance
+			restarted.Since = instance.Since.Add(time.Minute)
+			crashing := instance
+			crashing.State = models.InstanceFlapping
+			down := models.AppInstanceFields{State: models.InstanceDown}
+
+			appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+				{instance, instance, instance},
+				{restarted, crashing, down},
+			}
+			watchTicks <- time.Now()
+			close(watchTicks)
+
+			runCommand("--watch", "my-app")
+
+			frames := ui.LiveViews[0].Frames
+			testassert.SliceContains(frames[1], testassert.Lines{
+				{"#0", "running", "1"},
+				{"#1", "crashing"},
+				{"#2", "down"},
+				{"#0 has restarted 1 times while watching"},
+				{"#1 is crashing"},
+				{"#2 is down"},
+			})
+		})
+
+		It("keeps watching when the app is stopped", func() {
+			appSummaryRepo.GetSummaryErrorCode = errors.APP_STOPPED
+			close(watchTicks)
+
+			runCommand("--watch", "my-app")
+
+			Expect(ui.FailedWithUsage).To(BeFalse())
+			testassert.SliceContains(ui.LiveViews[0].Frames[0], testassert.Lines{
+				{"no running instances"},
+			})
+		})
+	})
 })
 
 func makeAppWithRoute(appName string) models.Application {
@@ -0,0 +1,335 @@
+package application
+
+import (
+	"cf"
+	"cf/api"
+	"cf/clock"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/errors"
+	"cf/flag_helpers"
+	"cf/models"
+	"cf/requirements"
+	"cf/terminal"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAutoscaleInterval = 30 * time.Second
+	DefaultAutoscaleCooldown = 2 * time.Minute
+
+	// Instances are only removed when the rest would stay under this much
+	// of the target, so that the app does not flap between two sizes.
+	autoscaleScaleDownHeadroom = 0.8
+)
+
+type Autoscale struct {
+	ui               terminal.UI
+	config           configuration.Reader
+	appRepo          api.ApplicationRepository
+	appInstancesRepo api.AppInstancesRepository
+	appReq           requirements.ApplicationRequirement
+	clock            clock.Clock
+}
+
+type autoscalePolicy struct {
+	min, max             int
+	cpuTarget, memTarget float64
+	cooldown             time.Duration
+	dryRun               bool
+}
+
+func NewAutoscale(ui terminal.UI, config configuration.Reader, appRepo api.ApplicationRepository, appInstancesRepo api.AppInstancesRepository, clock clock.Clock) (cmd *Autoscale) {
+	cmd = new(Autoscale)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appRepo = appRepo
+	cmd.appInstancesRepo = appInstancesRepo
+	cmd.clock = clock
+	return
+}
+
+func (cmd *Autoscale) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "autoscale",
+		Description: "Scale the instance count of an app to keep its cpu and memory use near a target, until Ctrl-C is pressed",
+		Usage: "CF_NAME autoscale APP --max M [--min N] [--cpu-target PERCENT] [--mem-target PERCENT] [--interval SECONDS] [--cooldown SECONDS] [--dry-run]\n\n" +
+			"   Instances are added when the average use of running instances is over a target, and removed\n" +
+			"   when every instance is running and the remaining instances would stay well under every\n" +
+			"   target. Nothing is changed for the cooldown after each change.",
+		Flags: []cli.Flag{
+			flag_helpers.NewIntFlag("min", "Minimum number of instances (default 1)"),
+			flag_helpers.NewIntFlag("max", "Maximum number of instances"),
+			flag_helpers.NewIntFlag("cpu-target", "Target average cpu use of the instances, in percent"),
+			flag_helpers.NewIntFlag("mem-target", "Target average memory use of the instances, in percent of their quota"),
+			flag_helpers.NewIntFlag("interval", "Seconds between checks of the instance stats (default 30)"),
+			flag_helpers.NewIntFlag("cooldown", "Seconds to wait after scaling before scaling again (default 120)"),
+			cli.BoolFlag{Name: "dry-run", Usage: "Report what would be scaled without changing the app"},
+		},
+	}
+}
+
+func (cmd *Autoscale) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 1 || !c.IsSet("max") || !(c.IsSet("cpu-target") || c.IsSet("mem-target")) {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "autoscale")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *Autoscale) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+	policy, interval := cmd.parsePolicy(c)
+
+	if strings.ToLower(app.State) != "started" {
+		cmd.ui.Failed("App %s is not started, so it cannot be autoscaled\n\nTIP: use '%s' to start it",
+			app.Name, terminal.CommandColor(fmt.Sprintf("%s start %s", cf.Name(), app.Name)))
+		return
+	}
+
+	cmd.ui.Say("Autoscaling app %s between %d and %d instances in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		policy.min,
+		policy.max,
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+	cmd.ui.Say("%s %s", terminal.HeaderColor("targets:"), policy.describeTargets())
+	cmd.ui.Say("Checking every %s with a cooldown of %s, press Ctrl-C to stop", interval, policy.cooldown)
+	if policy.dryRun {
+		cmd.ui.Say(terminal.WarningColor("Dry run: the app will not be changed"))
+	}
+	cmd.ui.Say("")
+
+	cmd.controlLoop(app, policy, interval)
+	cmd.ui.Say("\nStopped autoscaling app %s", terminal.EntityNameColor(app.Name))
+}
+
+func (cmd *Autoscale) parsePolicy(c *cli.Context) (policy autoscalePolicy, interval time.Duration) {
+	policy.min = 1
+	if c.IsSet("min") {
+		policy.min = c.Int("min")
+	}
+	policy.max = c.Int("max")
+
+	if policy.min < 1 || policy.max < policy.min {
+		cmd.ui.Failed("Invalid instance limits: --min %d --max %d\nThe minimum must be at least 1, and no more than the maximum", policy.min, policy.max)
+	}
+
+	for _, name := range []string{"cpu-target", "mem-target"} {
+		if c.IsSet(name) && (c.Int(name) < 1 || c.Int(name) > 100) {
+			cmd.ui.Failed("Invalid %s: %d\nThe target must be a percentage from 1 to 100", name, c.Int(name))
+		}
+	}
+	policy.cpuTarget = float64(c.Int("cpu-target"))
+	policy.memTarget = float64(c.Int("mem-target"))
+
+	interval = DefaultAutoscaleInterval
+	if c.IsSet("interval") {
+		if c.Int("interval") < 1 {
+			cmd.ui.Failed("Invalid interval: %d\nThe interval must be at least 1 second", c.Int("interval"))
+		}
+		interval = time.Duration(c.Int("interval")) * time.Second
+	}
+
+	policy.cooldown = DefaultAutoscaleCooldown
+	if c.IsSet("cooldown") {
+		if c.Int("cooldown") < 0 {
+			cmd.ui.Failed("Invalid cooldown: %d\nThe cooldown cannot be negative", c.Int("cooldown"))
+		}
+		policy.cooldown = time.Duration(c.Int("cooldown")) * time.Second
+	}
+
+	policy.dryRun = c.Bool("dry-run")
+	return
+}
+
+func (policy autoscalePolicy) describeTargets() string {
+	targets := []string{}
+	if policy.cpuTarget > 0 {
+		targets = append(targets, fmt.Sprintf("cpu %.0f%%", policy.cpuTarget))
+	}
+	if policy.memTarget > 0 {
+		targets = append(targets, fmt.Sprintf("memory %.0f%%", policy.memTarget))
+	}
+	return strings.Join(targets, ", ")
+}
+
+// controlLoop checks the instances of app every interval, scaling them when
+// the policy says so, until it is interrupted.
+func (cmd *Autoscale) controlLoop(app models.Application, policy autoscalePolicy, interval time.Duration) {
+	interrupts, stopInterrupts := cmd.clock.Interrupts()
+	defer stopInterrupts()
+
+	ticks, stopTicker := cmd.clock.Ticker(interval)
+	defer stopTicker()
+
+	instanceCount := app.InstanceCount
+	var lastScaled time.Time
+
+	now := cmd.clock.Now()
+	for {
+		instanceCount, lastScaled = cmd.check(app, policy, instanceCount, lastScaled, now)
+
+		select {
+		case <-interrupts:
+			return
+		case tick, ok := <-ticks:
+			if !ok {
+				return
+			}
+			now = tick
+		}
+	}
+}
+
+// check logs the stats of the instances of app and what is done about them,
+// returning the instance count and when the app was last scaled.
+func (cmd *Autoscale) check(app models.Application, policy autoscalePolicy, instanceCount int, lastScaled, now time.Time) (int, time.Time) {
+	logLine := func(message string, args ...interface{}) {
+		cmd.ui.Say("%s  %s", now.Format("03:04:05 PM"), fmt.Sprintf(message, args...))
+	}
+
+	instances, err := cmd.appInstancesRepo.GetInstances(app.Guid)
+	if err != nil {
+		logLine(terminal.WarningColor("could not get instance stats: %s"), err.Error())
+		return instanceCount, lastScaled
+	}
+
+	usage, running := averageUsage(instances)
+	desired, reason := policy.decide(instanceCount, usage, running)
+
+	stats := fmt.Sprintf("%d of %d instances running", running, instanceCount)
+	if running > 0 {
+		stats += fmt.Sprintf(", cpu %.1f%%, memory %.1f%%", usage.cpu, usage.mem)
+	}
+
+	if desired == instanceCount {
+		logLine("%s: no change, %s", stats, reason)
+		return instanceCount, lastScaled
+	}
+
+	if !lastScaled.IsZero() && now.Sub(lastScaled) < policy.cooldown {
+		remaining := (policy.cooldown - now.Sub(lastScaled) + time.Second/2) / time.Second * time.Second
+		logLine("%s: would scale to %d instances (%s), but cooling down for %s more", stats, desired, reason, remaining)
+		return instanceCount, lastScaled
+	}
+
+	if policy.dryRun {
+		logLine("%s: would scale to %d instances, %s %s", stats, desired, reason, terminal.WarningColor("(dry run)"))
+		return instanceCount, now
+	}
+
+	_, err = cmd.appRepo.Update(app.Guid, models.AppParams{InstanceCount: &desired})
+	if err != nil {
+		logLine(terminal.FailureColor("failed to scale to %d instances: %s"), desired, err.Error())
+		return instanceCount, lastScaled
+	}
+
+	logLine("%s: scaled to %s instances, %s", stats, terminal.EntityNameColor(fmt.Sprintf("%d", desired)), reason)
+	return desired, now
+}
+
+type instanceUsage struct {
+	cpu, mem float64 // percent
+}
+
+// averageUsage returns the average use of the running instances.
+func averageUsage(instances []models.AppInstanceFields) (usage instanceUsage, running int) {
+	for _, instance := range instances {
+		if instance.State != models.InstanceRunning {
+			continue
+		}
+
+		running++
+		usage.cpu += instance.CpuUsage * 100
+		if instance.MemQuota > 0 {
+			usage.mem += float64(instance.MemUsage) / float64(instance.MemQuota) * 100
+		}
+	}
+
+	if running > 0 {
+		usage.cpu /= float64(running)
+		usage.mem /= float64(running)
+	}
+	return
+}
+
+// decide returns how many instances the app should have, and why. The load
+// of the running instances is spread over the new count: enough instances
+// are added to bring the average under every target, and instances are
+// removed only while the rest would stay under the scale down headroom and
+// every instance is running, as instances that are still starting carry none
+// of the load yet.
+func (policy autoscalePolicy) decide(instanceCount int, usage instanceUsage, running int) (desired int, reason string) {
+	desired = instanceCount
+
+	switch {
+	case instanceCount < policy.min:
+		return policy.min, fmt.Sprintf("below the minimum of %d", policy.min)
+	case instanceCount > policy.max:
+		return policy.max, fmt.Sprintf("above the maximum of %d", policy.max)
+	case running == 0:
+		return instanceCount, "waiting for running instances"
+	}
+
+	over := []string{}
+	needed := 0.0
+	neededWithHeadroom := 0.0
+
+	for _, metric := range []struct {
+		name          string
+		usage, target float64
+	}{
+		{"cpu", usage.cpu, policy.cpuTarget},
+		{"memory", usage.mem, policy.memTarget},
+	} {
+		if metric.target <= 0 {
+			continue
+		}
+
+		load := metric.usage * float64(running)
+		needed = math.Max(needed, load/metric.target)
+		neededWithHeadroom = math.Max(neededWithHeadroom, load/(metric.target*autoscaleScaleDownHeadroom))
+
+		if metric.usage > metric.target {
+			over = append(over, fmt.Sprintf("%s is over its target of %.0f%%", metric.name, metric.target))
+		}
+	}
+
+	if len(over) > 0 {
+		desired = int(math.Max(math.Ceil(needed), float64(instanceCount+1)))
+		reason = strings.Join(over, " and ")
+		if desired > policy.max {
+			desired = policy.max
+			reason += fmt.Sprintf(", limited to the maximum of %d", policy.max)
+		}
+		return
+	}
+
+	fewer := int(math.Ceil(neededWithHeadroom))
+	if fewer < policy.min {
+		fewer = policy.min
+	}
+	if fewer >= instanceCount {
+		return instanceCount, "within targets"
+	}
+	if running < instanceCount {
+		return instanceCount, "waiting for every instance to be running before removing any"
+	}
+
+	return fewer, fmt.Sprintf("%d instances would stay under %.0f%% of the targets", fewer, autoscaleScaleDownHeadroom*100)
+}
@@ -0,0 +1,294 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/formatters"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testclock "testhelpers/clock"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+	"time"
+)
+
+var _ = Describe("autoscale command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		appRepo             *testapi.FakeApplicationRepository
+		appInstancesRepo    *testapi.FakeAppInstancesRepo
+		requirementsFactory *testreq.FakeReqFactory
+		interrupts          chan os.Signal
+		ticks               chan time.Time
+		clock               *testclock.FakeClock
+		start               time.Time
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		app.State = "started"
+		app.InstanceCount = 2
+
+		ui = new(testterm.FakeUI)
+		appRepo = &testapi.FakeApplicationRepository{}
+		appInstancesRepo = &testapi.FakeAppInstancesRepo{}
+		requirementsFactory = &testreq.FakeReqFactory{Application: app, LoginSuccess: true, TargetedSpaceSuccess: true}
+		interrupts = make(chan os.Signal, 1)
+		ticks = make(chan time.Time, 10)
+		start = time.Now()
+	})
+
+	runCommand := func(args ...string) {
+		clock = &testclock.FakeClock{Time: start, Ticks: ticks, Signals: interrupts}
+		cmd := NewAutoscale(ui, testconfig.NewRepositoryWithDefaults(), appRepo, appInstancesRepo, clock)
+		testcmd.RunCommand(cmd, testcmd.NewContext("autoscale", args), requirementsFactory)
+	}
+
+	// tickAfter sends ticks at the given times after the command starts,
+	// then stops the loop.
+	tickAfter := func(offsets ...time.Duration) {
+		for _, offset := range offsets {
+			ticks <- start.Add(offset)
+		}
+		close(ticks)
+	}
+
+	running := func(cpu float64, memoryPercent uint64) models.AppInstanceFields {
+		return models.AppInstanceFields{
+			State:    models.InstanceRunning,
+			CpuUsage: cpu,
+			MemQuota: 100 * formatters.MEGABYTE,
+			MemUsage: memoryPercent * formatters.MEGABYTE,
+		}
+	}
+
+	instanceCounts := func() (counts []int) {
+		for _, params := range appRepo.AllUpdateParams {
+			counts = append(counts, *params.InstanceCount)
+		}
+		return
+	}
+
+	Describe("requirements", func() {
+		It("fails with usage without a maximum or a target", func() {
+			runCommand("--cpu-target", "70", "my-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+
+			ui = new(testterm.FakeUI)
+			runCommand("--max", "5", "my-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when not logged in", func() {
+			requirementsFactory.LoginSuccess = false
+			runCommand("--max", "5", "--cpu-target", "70", "my-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when a space is not targeted", func() {
+			requirementsFactory.TargetedSpaceSuccess = false
+			runCommand("--max", "5", "--cpu-target", "70", "my-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+	})
+
+	It("fails when the limits are inconsistent", func() {
+		runCommand("--min", "4", "--max", "2", "--cpu-target", "70", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid instance limits: --min 4 --max 2"},
+		})
+	})
+
+	It("fails when a target is not a percentage", func() {
+		runCommand("--max", "5", "--mem-target", "120", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid mem-target: 120"},
+		})
+	})
+
+	It("fails when the app is not started", func() {
+		requirementsFactory.Application.State = "stopped"
+
+		runCommand("--max", "5", "--cpu-target", "70", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App my-app is not started"},
+		})
+	})
+
+	It("adds enough instances to bring the average under the target", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.9, 40), running(0.9, 40)},
+		}
+		tickAfter()
+
+		runCommand("--max", "5", "--cpu-target", "60", "--mem-target", "80", "my-app")
+
+		Expect(clock.TickerInterval).To(Equal(30 * time.Second))
+		Expect(appRepo.UpdateAppGuid).To(Equal("my-app-guid"))
+		Expect(instanceCounts()).To(Equal([]int{3}))
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Autoscaling app my-app between 1 and 5 instances in org my-org / space my-space as my-user"},
+			{"targets:", "cpu 60%, memory 80%"},
+			{"Checking every 30s with a cooldown of 2m0s"},
+			{"2 of 2 instances running, cpu 90.0%, memory 40.0%: scaled to 3 instances, cpu is over its target of 60%"},
+			{"Stopped autoscaling app my-app"},
+		})
+	})
+
+	It("does not scale above the maximum", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.2, 95), running(0.2, 95)},
+		}
+		tickAfter()
+
+		runCommand("--max", "2", "--mem-target", "80", "my-app")
+
+		Expect(appRepo.AllUpdateParams).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"no change, memory is over its target of 80%, limited to the maximum of 2"},
+		})
+	})
+
+	It("removes instances only when the rest would stay well under the targets", func() {
+		requirementsFactory.Application.InstanceCount = 4
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.5, 10), running(0.5, 10), running(0.5, 10), running(0.5, 10)},
+			{running(0.3, 10), running(0.3, 10), running(0.3, 10), running(0.3, 10)},
+		}
+		tickAfter(time.Minute)
+
+		runCommand("--min", "2", "--max", "5", "--cpu-target", "70", "my-app")
+
+		Expect(instanceCounts()).To(Equal([]int{3}))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"4 of 4 instances running, cpu 50.0%", "no change, within targets"},
+			{"4 of 4 instances running, cpu 30.0%", "scaled to 3 instances, 3 instances would stay under 80% of the targets"},
+		})
+	})
+
+	It("waits for the cooldown before scaling again", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.9, 10), running(0.9, 10)},
+			{running(0.9, 10), running(0.9, 10), running(0.9, 10)},
+			{running(0.9, 10), running(0.9, 10), running(0.9, 10)},
+		}
+		tickAfter(30*time.Second, 3*time.Minute)
+
+		runCommand("--max", "10", "--cpu-target", "70", "--cooldown", "60", "my-app")
+
+		Expect(instanceCounts()).To(Equal([]int{3, 4}))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"scaled to 3 instances"},
+			{"3 of 3 instances running", "would scale to 4 instances", "but cooling down for 30s more"},
+			{"3 of 3 instances running", "scaled to 4 instances"},
+		})
+	})
+
+	It("only reports what it would do in a dry run", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.9, 10), running(0.9, 10)},
+		}
+		tickAfter()
+
+		runCommand("--max", "5", "--cpu-target", "70", "--dry-run", "my-app")
+
+		Expect(appRepo.AllUpdateParams).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Dry run: the app will not be changed"},
+			{"would scale to 3 instances, cpu is over its target of 70% (dry run)"},
+		})
+	})
+
+	It("scales up to the minimum and waits for instances to be running", func() {
+		requirementsFactory.Application.InstanceCount = 1
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.1, 10)},
+			{running(0.1, 10), {State: models.InstanceStarting}},
+		}
+		tickAfter(5 * time.Minute)
+
+		runCommand("--min", "2", "--max", "5", "--cpu-target", "70", "my-app")
+
+		Expect(instanceCounts()).To(Equal([]int{2}))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"1 of 1 instances running", "scaled to 2 instances, below the minimum of 2"},
+			{"1 of 2 instances running", "no change, within targets"},
+		})
+	})
+
+	It("does not remove instances while some are not running", func() {
+		requirementsFactory.Application.InstanceCount = 4
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.1, 10), running(0.1, 10), {State: models.InstanceStarting}, {State: models.InstanceStarting}},
+		}
+		tickAfter()
+
+		runCommand("--max", "5", "--cpu-target", "70", "my-app")
+
+		Expect(appRepo.AllUpdateParams).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"2 of 4 instances running", "no change, waiting for every instance to be running before removing any"},
+		})
+	})
+
+	It("takes the time of the first check from the clock", func() {
+		start = time.Date(2014, 5, 1, 13, 4, 5, 0, time.UTC)
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.1, 10), running(0.1, 10)},
+		}
+		tickAfter()
+
+		runCommand("--max", "5", "--cpu-target", "70", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"01:04:05 PM", "2 of 2 instances running"},
+		})
+	})
+
+	It("keeps going when the stats cannot be read or the app cannot be scaled", func() {
+		appInstancesRepo.GetInstancesErrorCodes = []string{"170002"}
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			nil,
+			{running(0.9, 10), running(0.9, 10)},
+		}
+		appRepo.UpdateErr = true
+		tickAfter(time.Minute)
+
+		runCommand("--max", "5", "--cpu-target", "70", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"could not get instance stats"},
+			{"failed to scale to 3 instances", "Error updating app."},
+			{"Stopped autoscaling app my-app"},
+		})
+	})
+
+	It("stops when interrupted", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{running(0.1, 10), running(0.1, 10)},
+		}
+		interrupts <- os.Interrupt
+
+		runCommand("--max", "5", "--cpu-target", "70", "my-app")
+
+		Expect(appInstancesRepo.GetInstancesResponses).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Stopped autoscaling app my-app"},
+		})
+	})
+})
@@ -0,0 +1,135 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/flag_helpers"
+	"cf/models"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+)
+
+type CopySource struct {
+	ui          terminal.UI
+	config      configuration.Reader
+	appRepo     api.ApplicationRepository
+	appBitsRepo api.ApplicationBitsRepository
+	orgRepo     api.OrganizationRepository
+	spaceRepo   api.SpaceRepository
+	restarter   ApplicationRestarter
+	appReq      requirements.ApplicationRequirement
+}
+
+func NewCopySource(ui terminal.UI, config configuration.Reader, appRepo api.ApplicationRepository, appBitsRepo api.ApplicationBitsRepository, orgRepo api.OrganizationRepository, spaceRepo api.SpaceRepository, restarter ApplicationRestarter) (cmd *CopySource) {
+	cmd = new(CopySource)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appRepo = appRepo
+	cmd.appBitsRepo = appBitsRepo
+	cmd.orgRepo = orgRepo
+	cmd.spaceRepo = spaceRepo
+	cmd.restarter = restarter
+	return
+}
+
+func (command *CopySource) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "copy-source",
+		Description: "Copy the source code of an app to another app and restart it",
+		Usage: "CF_NAME copy-source SOURCE_APP TARGET_APP [-o ORG -s SPACE] [--no-restart]\n\n" +
+			"   TARGET_APP is found in the targeted space, in SPACE of the targeted org with -s,\n" +
+			"   or in SPACE of ORG with -o and -s.",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("o", "Org that contains the target app"),
+			flag_helpers.NewStringFlag("s", "Space that contains the target app"),
+			cli.BoolFlag{Name: "no-restart", Usage: "Do not restart the target app after copying"},
+		},
+	}
+}
+
+func (cmd *CopySource) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 2 || (c.String("o") != "" && c.String("s") == "") {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "copy-source")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *CopySource) Run(c *cli.Context) {
+	sourceApp := cmd.appReq.GetApplication()
+	targetAppName := c.Args()[1]
+
+	org, space, err := cmd.findTargetSpace(c.String("o"), c.String("s"))
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	targetApp, err := cmd.appRepo.ReadFromSpace(targetAppName, space.Guid)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Say("Copying source from app %s to target app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(sourceApp.Name),
+		terminal.EntityNameColor(targetApp.Name),
+		terminal.EntityNameColor(org.Name),
+		terminal.EntityNameColor(space.Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	err = cmd.appBitsRepo.CopyBits(sourceApp.Guid, targetApp.Guid)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+
+	if c.Bool("no-restart") {
+		cmd.ui.Say("TIP: Restart app %s to run the copied source", terminal.EntityNameColor(targetApp.Name))
+		return
+	}
+
+	cmd.ui.Say("")
+	cmd.restarter.ApplicationRestart(targetApp)
+}
+
+// findTargetSpace finds the space named with -s in the org named with -o,
+// defaulting to the targeted org and space.
+func (cmd *CopySource) findTargetSpace(orgName, spaceName string) (org models.OrganizationFields, space models.SpaceFields, err error) {
+	org = cmd.config.OrganizationFields()
+	space = cmd.config.SpaceFields()
+
+	if orgName != "" {
+		var foundOrg models.Organization
+		foundOrg, err = cmd.orgRepo.FindByName(orgName)
+		if err != nil {
+			return
+		}
+		org = foundOrg.OrganizationFields
+	}
+
+	if spaceName != "" {
+		var foundSpace models.Space
+		foundSpace, err = cmd.spaceRepo.FindByNameInOrg(spaceName, org.Guid)
+		if err != nil {
+			return
+		}
+		space = foundSpace.SpaceFields
+	}
+	return
+}
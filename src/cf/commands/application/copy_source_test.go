@@ -0,0 +1,175 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/errors"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("copy-source command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		appRepo             *testapi.FakeApplicationRepository
+		appBitsRepo         *testapi.FakeApplicationBitsRepository
+		orgRepo             *testapi.FakeOrgRepository
+		spaceRepo           *testapi.FakeSpaceRepository
+		restarter           *testcmd.FakeAppRestarter
+		requirementsFactory *testreq.FakeReqFactory
+	)
+
+	BeforeEach(func() {
+		sourceApp := models.Application{}
+		sourceApp.Name = "source-app"
+		sourceApp.Guid = "source-app-guid"
+
+		targetApp := models.Application{}
+		targetApp.Name = "target-app"
+		targetApp.Guid = "target-app-guid"
+
+		ui = &testterm.FakeUI{}
+		appRepo = &testapi.FakeApplicationRepository{}
+		appRepo.ReadFromSpaceReturns.App = targetApp
+		appBitsRepo = &testapi.FakeApplicationBitsRepository{}
+		orgRepo = &testapi.FakeOrgRepository{}
+		spaceRepo = &testapi.FakeSpaceRepository{}
+		restarter = &testcmd.FakeAppRestarter{}
+		requirementsFactory = &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true, Application: sourceApp}
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewCopySource(ui, testconfig.NewRepositoryWithDefaults(), appRepo, appBitsRepo, orgRepo, spaceRepo, restarter)
+		testcmd.RunCommand(cmd, testcmd.NewContext("copy-source", args), requirementsFactory)
+	}
+
+	Describe("requirements", func() {
+		It("fails with usage when not given a source and a target app", func() {
+			runCommand("source-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails with usage when given an org without a space", func() {
+			runCommand("-o", "other-org", "source-app", "target-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when not logged in", func() {
+			requirementsFactory.LoginSuccess = false
+			runCommand("source-app", "target-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when a space is not targeted", func() {
+			requirementsFactory.TargetedSpaceSuccess = false
+			runCommand("source-app", "target-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+	})
+
+	It("copies the source to an app in the targeted space and restarts it", func() {
+		runCommand("source-app", "target-app")
+
+		Expect(requirementsFactory.ApplicationName).To(Equal("source-app"))
+		Expect(appRepo.ReadFromSpaceArgs.Name).To(Equal("target-app"))
+		Expect(appRepo.ReadFromSpaceArgs.SpaceGuid).To(Equal("my-space-guid"))
+		Expect(appBitsRepo.CopiedSourceAppGuid).To(Equal("source-app-guid"))
+		Expect(appBitsRepo.CopiedTargetAppGuid).To(Equal("target-app-guid"))
+		Expect(restarter.AppToRestart.Guid).To(Equal("target-app-guid"))
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Copying source from app", "source-app", "to target app", "target-app", "in org my-org / space my-space as my-user"},
+			{"OK"},
+		})
+	})
+
+	It("finds the target app in another space of the targeted org", func() {
+		space := models.Space{}
+		space.Name = "other-space"
+		space.Guid = "other-space-guid"
+		spaceRepo.FindByNameInOrgSpace = space
+
+		runCommand("-s", "other-space", "source-app", "target-app")
+
+		Expect(spaceRepo.FindByNameInOrgName).To(Equal("other-space"))
+		Expect(spaceRepo.FindByNameInOrgOrgGuid).To(Equal("my-org-guid"))
+		Expect(appRepo.ReadFromSpaceArgs.SpaceGuid).To(Equal("other-space-guid"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Copying source from app", "in org my-org / space other-space as my-user"},
+		})
+	})
+
+	It("finds the target app in a space of another org", func() {
+		org := models.Organization{}
+		org.Name = "other-org"
+		org.Guid = "other-org-guid"
+		orgRepo.Organizations = []models.Organization{org}
+
+		space := models.Space{}
+		space.Name = "other-space"
+		space.Guid = "other-space-guid"
+		spaceRepo.FindByNameInOrgSpace = space
+
+		runCommand("-o", "other-org", "-s", "other-space", "source-app", "target-app")
+
+		Expect(orgRepo.FindByNameName).To(Equal("other-org"))
+		Expect(spaceRepo.FindByNameInOrgOrgGuid).To(Equal("other-org-guid"))
+		Expect(appRepo.ReadFromSpaceArgs.SpaceGuid).To(Equal("other-space-guid"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Copying source from app", "in org other-org / space other-space as my-user"},
+		})
+	})
+
+	It("does not restart the target app with --no-restart", func() {
+		runCommand("--no-restart", "source-app", "target-app")
+
+		Expect(appBitsRepo.CopiedTargetAppGuid).To(Equal("target-app-guid"))
+		Expect(restarter.AppToRestart.Guid).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"OK"},
+			{"TIP: Restart app target-app to run the copied source"},
+		})
+	})
+
+	It("fails when the org cannot be found", func() {
+		runCommand("-o", "missing-org", "-s", "other-space", "source-app", "target-app")
+
+		Expect(appBitsRepo.CopiedTargetAppGuid).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Error finding organization by name."},
+		})
+	})
+
+	It("fails when the target app cannot be found", func() {
+		appRepo.ReadFromSpaceReturns.Error = errors.NewModelNotFoundError("App", "target-app")
+
+		runCommand("source-app", "target-app")
+
+		Expect(appBitsRepo.CopiedTargetAppGuid).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App target-app not found"},
+		})
+	})
+
+	It("fails without restarting when the source cannot be copied", func() {
+		appBitsRepo.CopyBitsErr = errors.New("copy job failed")
+
+		runCommand("source-app", "target-app")
+
+		Expect(restarter.AppToRestart.Guid).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"copy job failed"},
+		})
+	})
+})
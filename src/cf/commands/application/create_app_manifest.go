@@ -0,0 +1,100 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/flag_helpers"
+	"cf/manifest"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+)
+
+type CreateAppManifest struct {
+	ui             terminal.UI
+	config         configuration.Reader
+	appSummaryRepo api.AppSummaryRepository
+	stackRepo      api.StackRepository
+	manifestRepo   manifest.ManifestRepository
+	appReq         requirements.ApplicationRequirement
+}
+
+func NewCreateAppManifest(ui terminal.UI, config configuration.Reader, appSummaryRepo api.AppSummaryRepository, stackRepo api.StackRepository, manifestRepo manifest.ManifestRepository) (cmd *CreateAppManifest) {
+	cmd = new(CreateAppManifest)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appSummaryRepo = appSummaryRepo
+	cmd.stackRepo = stackRepo
+	cmd.manifestRepo = manifestRepo
+	return
+}
+
+func (command *CreateAppManifest) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "create-app-manifest",
+		Description: "Create an app manifest for an app that has been pushed successfully",
+		Usage:       "CF_NAME create-app-manifest APP [-p /path/to/<app-name>_manifest.yml]",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("p", "Path to write the manifest to, defaults to <app-name>_manifest.yml in the current directory"),
+		},
+	}
+}
+
+func (cmd *CreateAppManifest) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "create-app-manifest")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *CreateAppManifest) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+
+	cmd.ui.Say("Creating an app manifest from app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	summary, apiErr := cmd.appSummaryRepo.GetSummary(app.Guid)
+	if apiErr != nil {
+		cmd.ui.Failed("Error getting application summary: %s", apiErr.Error())
+		return
+	}
+
+	if summary.Stack != nil {
+		stack, apiErr := cmd.stackRepo.FindByGuid(summary.Stack.Guid)
+		if apiErr != nil {
+			cmd.ui.Failed("Error retrieving stack: %s", apiErr.Error())
+			return
+		}
+		summary.Stack = &stack
+	}
+
+	path := c.String("p")
+	if path == "" {
+		path = app.Name + "_manifest.yml"
+	}
+
+	err := cmd.manifestRepo.WriteManifest(manifest.NewManifestFromApp(path, summary))
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("Manifest file created successfully at %s", terminal.EntityNameColor(path))
+}
@@ -0,0 +1,126 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/configuration"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testmanifest "testhelpers/manifest"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("create-app-manifest command", func() {
+	var (
+		cmd                 *CreateAppManifest
+		ui                  *testterm.FakeUI
+		configRepo          configuration.ReadWriter
+		appSummaryRepo      *testapi.FakeAppSummaryRepo
+		stackRepo           *testapi.FakeStackRepository
+		manifestRepo        *testmanifest.FakeManifestRepository
+		requirementsFactory *testreq.FakeReqFactory
+	)
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+		configRepo = testconfig.NewRepositoryWithDefaults()
+		appSummaryRepo = &testapi.FakeAppSummaryRepo{}
+		stackRepo = &testapi.FakeStackRepository{}
+		manifestRepo = &testmanifest.FakeManifestRepository{}
+
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		requirementsFactory = &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true, Application: app}
+
+		cmd = NewCreateAppManifest(ui, configRepo, appSummaryRepo, stackRepo, manifestRepo)
+	})
+
+	runCommand := func(args ...string) {
+		testcmd.RunCommand(cmd, testcmd.NewContext("create-app-manifest", args), requirementsFactory)
+	}
+
+	Describe("requirements", func() {
+		It("fails with usage when no app name is given", func() {
+			runCommand()
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when not logged in", func() {
+			requirementsFactory.LoginSuccess = false
+			runCommand("my-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when a space is not targeted", func() {
+			requirementsFactory.TargetedSpaceSuccess = false
+			runCommand("my-app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+	})
+
+	Context("when the app summary can be retrieved", func() {
+		BeforeEach(func() {
+			summary := models.Application{}
+			summary.Name = "my-app"
+			summary.Guid = "my-app-guid"
+			summary.Memory = 256
+			summary.DiskQuota = 1024
+			summary.InstanceCount = 2
+			summary.Stack = &models.Stack{Guid: "my-stack-guid"}
+			summary.Services = []models.ServiceInstanceFields{{Name: "my-db"}}
+			appSummaryRepo.GetSummarySummary = summary
+
+			stackRepo.FindByGuidStack = models.Stack{Guid: "my-stack-guid", Name: "lucid64"}
+		})
+
+		It("writes a manifest for the app named after the app", func() {
+			runCommand("my-app")
+
+			Expect(appSummaryRepo.GetSummaryAppGuid).To(Equal("my-app-guid"))
+			Expect(stackRepo.FindByGuidGuid).To(Equal("my-stack-guid"))
+
+			m := manifestRepo.WriteManifestArgs.Manifest
+			Expect(m.Path).To(Equal("my-app_manifest.yml"))
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*apps[0].Name).To(Equal("my-app"))
+			Expect(*apps[0].Memory).To(Equal(uint64(256)))
+			Expect(*apps[0].DiskQuota).To(Equal(uint64(1024)))
+			Expect(*apps[0].InstanceCount).To(Equal(2))
+			Expect(*apps[0].StackName).To(Equal("lucid64"))
+			Expect(*apps[0].ServicesToBind).To(Equal([]string{"my-db"}))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Creating an app manifest from app", "my-app", "my-org", "my-space", "my-user"},
+				{"OK"},
+				{"Manifest file created successfully at", "my-app_manifest.yml"},
+			})
+		})
+
+		It("writes the manifest to the path given with -p", func() {
+			runCommand("-p", "manifests/production.yml", "my-app")
+
+			Expect(manifestRepo.WriteManifestArgs.Manifest.Path).To(Equal("manifests/production.yml"))
+		})
+	})
+
+	It("fails when the app summary cannot be retrieved", func() {
+		appSummaryRepo.GetSummaryErrorCode = "some-error-code"
+
+		runCommand("my-app")
+
+		Expect(manifestRepo.WriteManifestArgs.Manifest).To(BeNil())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Error getting application summary"},
+		})
+	})
+})
@@ -0,0 +1,227 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/flag_helpers"
+	"cf/formatters"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type DownloadFiles struct {
+	ui           terminal.UI
+	config       configuration.Reader
+	appFilesRepo api.AppFilesRepository
+	appReq       requirements.ApplicationRequirement
+}
+
+// instanceDownload is where files are downloaded from, and how many were.
+type instanceDownload struct {
+	appGuid  string
+	instance int
+	resume   bool
+
+	files   int
+	skipped int
+	bytes   int64
+}
+
+func NewDownloadFiles(ui terminal.UI, config configuration.Reader, appFilesRepo api.AppFilesRepository) (cmd *DownloadFiles) {
+	cmd = new(DownloadFiles)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appFilesRepo = appFilesRepo
+	return
+}
+
+func (command *DownloadFiles) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "download-files",
+		Description: "Download a file or a directory with everything in it from an app instance",
+		Usage: "CF_NAME download-files APP PATH --to LOCAL_DIR [--instance INDEX] [--resume]\n\n" +
+			"   The files under PATH are written to LOCAL_DIR with the same relative paths.\n" +
+			"   Files that are already in LOCAL_DIR are skipped. Files whose download was cut off are\n" +
+			"   downloaded again, or continued with --resume.",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("to", "Local directory to write the files to"),
+			flag_helpers.NewIntFlag("instance", "Index of the instance to download from (default 0)"),
+			cli.BoolFlag{Name: "resume", Usage: "Continue files whose download was cut off instead of downloading them again"},
+		},
+	}
+}
+
+func (cmd *DownloadFiles) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 2 || c.String("to") == "" {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "download-files")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *DownloadFiles) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+	remotePath := c.Args()[1]
+	localDir := c.String("to")
+
+	download := &instanceDownload{
+		appGuid:  app.Guid,
+		instance: c.Int("instance"),
+		resume:   c.Bool("resume"),
+	}
+
+	if download.instance < 0 || download.instance >= app.InstanceCount {
+		cmd.ui.Failed("Invalid instance index: %d\nApp %s has %d instances, numbered from 0", download.instance, app.Name, app.InstanceCount)
+		return
+	}
+
+	cmd.ui.Say("Downloading %s from instance #%d of app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(remotePath),
+		download.instance,
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	isDir, found, err := cmd.isDir(download, remotePath)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+	if !found {
+		cmd.ui.Failed("%s was not found in instance #%d of app %s", remotePath, download.instance, app.Name)
+		return
+	}
+
+	if isDir {
+		err = cmd.downloadDir(download, remotePath, localDir)
+	} else {
+		err = os.MkdirAll(localDir, os.ModePerm)
+		if err == nil {
+			err = cmd.downloadFile(download, remotePath, filepath.Join(localDir, path.Base(remotePath)))
+		}
+	}
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("")
+	cmd.ui.Say("Downloaded %d files, %s in total, to %s",
+		download.files,
+		formatters.ByteSize(uint64(download.bytes)),
+		terminal.EntityNameColor(localDir),
+	)
+	if download.skipped > 0 {
+		cmd.ui.Say("Skipped %d files that were already in %s.",
+			download.skipped,
+			terminal.EntityNameColor(localDir),
+		)
+	}
+}
+
+// isDir looks for remotePath in the listing of its parent directory, since
+// the files endpoint returns the contents of files rather than their type.
+func (cmd *DownloadFiles) isDir(download *instanceDownload, remotePath string) (isDir, found bool, err error) {
+	remotePath = strings.Trim(remotePath, "/")
+	if remotePath == "" {
+		return true, true, nil
+	}
+
+	parent := path.Dir(remotePath)
+	if parent == "." {
+		parent = "/"
+	}
+
+	entries, err := cmd.appFilesRepo.ListInstanceDir(download.appGuid, download.instance, parent)
+	if err != nil {
+		return
+	}
+
+	name := path.Base(remotePath)
+	for _, entry := range entries {
+		if entry.Name == name {
+			return entry.IsDir, true, nil
+		}
+	}
+	return
+}
+
+func (cmd *DownloadFiles) downloadDir(download *instanceDownload, remoteDir, localDir string) (err error) {
+	err = os.MkdirAll(localDir, os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	entries, err := cmd.appFilesRepo.ListInstanceDir(download.appGuid, download.instance, remoteDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		// Entries are single path elements; anything else would be written
+		// outside of localDir.
+		if strings.ContainsAny(entry.Name, `/\`) || entry.Name == ".." {
+			continue
+		}
+
+		remotePath := path.Join(remoteDir, entry.Name)
+		localPath := filepath.Join(localDir, entry.Name)
+
+		if entry.IsDir {
+			err = cmd.downloadDir(download, remotePath, localPath)
+		} else {
+			err = cmd.downloadFile(download, remotePath, localPath)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (cmd *DownloadFiles) downloadFile(download *instanceDownload, remotePath, localPath string) (err error) {
+	_, statErr := os.Stat(localPath)
+	exists := statErr == nil
+
+	// files are only given their name once they are completely downloaded
+	if exists {
+		download.skipped++
+		cmd.ui.Say("  %s (skipped, already downloaded)", remotePath)
+		return
+	}
+
+	written, resumed, err := cmd.appFilesRepo.DownloadFile(download.appGuid, download.instance, remotePath, localPath, download.resume)
+	if err != nil {
+		err = errors.New("Error downloading " + remotePath + "\n" + err.Error())
+		return
+	}
+
+	download.files++
+	download.bytes += written
+
+	if resumed {
+		cmd.ui.Say("  %s (%s, resumed)", remotePath, formatters.ByteSize(uint64(written)))
+	} else {
+		cmd.ui.Say("  %s (%s)", remotePath, formatters.ByteSize(uint64(written)))
+	}
+	return
+}
@@ -0,0 +1,209 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/errors"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("download-files command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		appFilesRepo        *testapi.FakeAppFilesRepo
+		requirementsFactory *testreq.FakeReqFactory
+		localDir            string
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		app.InstanceCount = 2
+
+		ui = &testterm.FakeUI{}
+		appFilesRepo = &testapi.FakeAppFilesRepo{
+			InstanceFiles: map[string]string{
+				"app/heap.hprof":     "\x00\x01heap\xff",
+				"app/logs/app.log":   "started\n",
+				"app/logs/old/1.log": "stopped\n",
+				"other/file.txt":     "other",
+			},
+		}
+		requirementsFactory = &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true, Application: app}
+
+		var err error
+		localDir, err = ioutil.TempDir("", "download-files")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(localDir)
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewDownloadFiles(ui, testconfig.NewRepositoryWithDefaults(), appFilesRepo)
+		testcmd.RunCommand(cmd, testcmd.NewContext("download-files", args), requirementsFactory)
+	}
+
+	readLocalFile := func(path string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(localDir, path))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("requirements", func() {
+		It("fails with usage without an app, a path and a local dir", func() {
+			runCommand("my-app", "app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+
+			ui = &testterm.FakeUI{}
+			runCommand("--to", localDir, "my-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when not logged in", func() {
+			requirementsFactory.LoginSuccess = false
+			runCommand("--to", localDir, "my-app", "app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when a space is not targeted", func() {
+			requirementsFactory.TargetedSpaceSuccess = false
+			runCommand("--to", localDir, "my-app", "app")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+	})
+
+	It("downloads a directory and everything in it", func() {
+		runCommand("--to", localDir, "my-app", "app")
+
+		Expect(requirementsFactory.ApplicationName).To(Equal("my-app"))
+		Expect(appFilesRepo.AppGuid).To(Equal("my-app-guid"))
+		Expect(appFilesRepo.DownloadedPaths).To(Equal([]string{"app/heap.hprof", "app/logs/app.log", "app/logs/old/1.log"}))
+		Expect(readLocalFile("heap.hprof")).To(Equal("\x00\x01heap\xff"))
+		Expect(readLocalFile("logs/app.log")).To(Equal("started\n"))
+		Expect(readLocalFile("logs/old/1.log")).To(Equal("stopped\n"))
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Downloading app from instance #0 of app my-app in org my-org / space my-space as my-user..."},
+			{"app/heap.hprof (7)"},
+			{"app/logs/app.log (8)"},
+			{"app/logs/old/1.log (8)"},
+			{"OK"},
+			{"Downloaded 3 files, 23 in total, to " + localDir},
+		})
+	})
+
+	It("downloads everything from the root of the instance", func() {
+		runCommand("--to", localDir, "my-app", "/")
+
+		Expect(appFilesRepo.DownloadedPaths).To(HaveLen(4))
+		Expect(readLocalFile("other/file.txt")).To(Equal("other"))
+	})
+
+	It("downloads a single file into the local dir", func() {
+		runCommand("--to", localDir, "my-app", "/app/logs/app.log")
+
+		Expect(appFilesRepo.ListedDirs).To(Equal([]string{"app/logs"}))
+		Expect(appFilesRepo.DownloadedPaths).To(Equal([]string{"/app/logs/app.log"}))
+		Expect(readLocalFile("app.log")).To(Equal("started\n"))
+	})
+
+	It("downloads from the given instance", func() {
+		runCommand("--instance", "1", "--to", localDir, "my-app", "app/logs")
+
+		Expect(appFilesRepo.Instances).NotTo(BeEmpty())
+		for _, instance := range appFilesRepo.Instances {
+			Expect(instance).To(Equal(1))
+		}
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Downloading app/logs from instance #1 of app my-app"},
+		})
+	})
+
+	It("fails when the instance does not exist", func() {
+		runCommand("--instance", "2", "--to", localDir, "my-app", "app")
+
+		Expect(appFilesRepo.DownloadedPaths).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App my-app has 2 instances, numbered from 0"},
+		})
+	})
+
+	It("fails when the path does not exist", func() {
+		runCommand("--to", localDir, "my-app", "app/missing")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"app/missing was not found in instance #0 of app my-app"},
+		})
+	})
+
+	It("skips files that are already in the local dir", func() {
+		err := ioutil.WriteFile(filepath.Join(localDir, "heap.hprof"), []byte("\x00\x01"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		runCommand("--to", localDir, "my-app", "app")
+
+		Expect(appFilesRepo.DownloadedPaths).To(Equal([]string{"app/logs/app.log", "app/logs/old/1.log"}))
+		Expect(readLocalFile("heap.hprof")).To(Equal("\x00\x01"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"app/heap.hprof (skipped, already downloaded)"},
+			{"Downloaded 2 files, 16 in total"},
+			{"Skipped 1 files that were already in"},
+		})
+	})
+
+	It("downloads files that were cut off again", func() {
+		err := ioutil.WriteFile(filepath.Join(localDir, "heap.hprof.part"), []byte("\x00\x01"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		runCommand("--to", localDir, "my-app", "app")
+
+		Expect(appFilesRepo.DownloadResumes).To(Equal([]bool{false, false, false}))
+		Expect(readLocalFile("heap.hprof")).To(Equal("\x00\x01heap\xff"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"app/heap.hprof (7)"},
+			{"Downloaded 3 files, 23 in total"},
+		})
+	})
+
+	It("continues files that were cut off with --resume", func() {
+		err := ioutil.WriteFile(filepath.Join(localDir, "heap.hprof.part"), []byte("\x00\x01"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		runCommand("--resume", "--to", localDir, "my-app", "app")
+
+		Expect(appFilesRepo.DownloadResumes).To(Equal([]bool{true, true, true}))
+		Expect(readLocalFile("heap.hprof")).To(Equal("\x00\x01heap\xff"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"app/heap.hprof (5, resumed)"},
+			{"Downloaded 3 files, 21 in total"},
+		})
+	})
+
+	It("fails when a file cannot be downloaded", func() {
+		appFilesRepo.DownloadFileErrs = map[string]error{"app/logs/app.log": errors.New("connection reset")}
+
+		runCommand("--to", localDir, "my-app", "app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Error downloading app/logs/app.log"},
+			{"connection reset"},
+		})
+	})
+})
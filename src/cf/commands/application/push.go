@@ -1,63 +1,120 @@
 package application
 
 import (
+	"cf"
 	"cf/api"
+	"cf/app_files"
 	"cf/command_metadata"
 	"cf/commands/service"
 	"cf/configuration"
 	"cf/errors"
 	"cf/flag_helpers"
 	"cf/formatters"
+	"cf/hooks"
 	"cf/manifest"
 	"cf/models"
 	"cf/requirements"
 	"cf/terminal"
+	"encoding/json"
 	"fmt"
 	"github.com/codegangsta/cli"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"words"
 )
 
+const (
+	BlueGreenStrategy     = "blue-green"
+	BlueGreenNewAppSuffix = "-new"
+	BlueGreenOldAppSuffix = "-old"
+)
+
+const (
+	PlanActionCreate        = "create"
+	PlanActionUpdate        = "update"
+	PlanActionBind          = "bind"
+	PlanActionCreateAndBind = "create and bind"
+	PlanActionUnbind        = "unbind"
+	PlanActionUnchanged     = "unchanged"
+)
+
+type PushPlan struct {
+	Apps []AppPlan `json:"apps"`
+}
+
+type AppPlan struct {
+	Name          string        `json:"name"`
+	Action        string        `json:"action"`
+	Changes       []FieldChange `json:"changes"`
+	Routes        []RoutePlan   `json:"routes"`
+	Services      []ServicePlan `json:"services"`
+	FilesToUpload int           `json:"files_to_upload"`
+	BytesToUpload int64         `json:"bytes_to_upload"`
+}
+
+type FieldChange struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+type RoutePlan struct {
+	URL    string `json:"url"`
+	Action string `json:"action"`
+}
+
+type ServicePlan struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
 type Push struct {
-	ui            terminal.UI
-	config        configuration.Reader
-	manifestRepo  manifest.ManifestRepository
-	appStarter    ApplicationStarter
-	appStopper    ApplicationStopper
-	serviceBinder service.ServiceBinder
-	appRepo       api.ApplicationRepository
-	domainRepo    api.DomainRepository
-	routeRepo     api.RouteRepository
-	serviceRepo   api.ServiceRepository
-	stackRepo     api.StackRepository
-	appBitsRepo   api.ApplicationBitsRepository
-	authRepo      api.AuthenticationRepository
-	wordGenerator words.WordGenerator
+	ui             terminal.UI
+	config         configuration.Reader
+	manifestRepo   manifest.ManifestRepository
+	appStarter     ApplicationStarter
+	appStopper     ApplicationStopper
+	serviceBinder  service.ServiceBinder
+	appRepo        api.ApplicationRepository
+	appSummaryRepo api.AppSummaryRepository
+	domainRepo     api.DomainRepository
+	routeRepo      api.RouteRepository
+	serviceRepo    api.ServiceRepository
+	stackRepo      api.StackRepository
+	appBitsRepo    api.ApplicationBitsRepository
+	authRepo       api.AuthenticationRepository
+	wordGenerator  words.WordGenerator
+	hookRunner     hooks.Runner
 }
 
 func NewPush(ui terminal.UI, config configuration.Reader, manifestRepo manifest.ManifestRepository,
 	starter ApplicationStarter, stopper ApplicationStopper, binder service.ServiceBinder,
-	appRepo api.ApplicationRepository, domainRepo api.DomainRepository, routeRepo api.RouteRepository,
+	appRepo api.ApplicationRepository, appSummaryRepo api.AppSummaryRepository,
+	domainRepo api.DomainRepository, routeRepo api.RouteRepository,
 	stackRepo api.StackRepository, serviceRepo api.ServiceRepository, appBitsRepo api.ApplicationBitsRepository,
-	authRepo api.AuthenticationRepository, wordGenerator words.WordGenerator) *Push {
+	authRepo api.AuthenticationRepository, wordGenerator words.WordGenerator, hookRunner hooks.Runner) *Push {
 	return &Push{
-		ui:            ui,
-		config:        config,
-		manifestRepo:  manifestRepo,
-		appStarter:    starter,
-		appStopper:    stopper,
-		serviceBinder: binder,
-		appRepo:       appRepo,
-		domainRepo:    domainRepo,
-		routeRepo:     routeRepo,
-		serviceRepo:   serviceRepo,
-		stackRepo:     stackRepo,
-		appBitsRepo:   appBitsRepo,
-		authRepo:      authRepo,
-		wordGenerator: wordGenerator,
+		ui:             ui,
+		config:         config,
+		manifestRepo:   manifestRepo,
+		appStarter:     starter,
+		appStopper:     stopper,
+		serviceBinder:  binder,
+		appRepo:        appRepo,
+		appSummaryRepo: appSummaryRepo,
+		domainRepo:     domainRepo,
+		routeRepo:      routeRepo,
+		serviceRepo:    serviceRepo,
+		stackRepo:      stackRepo,
+		appBitsRepo:    appBitsRepo,
+		authRepo:       authRepo,
+		wordGenerator:  wordGenerator,
+		hookRunner:     hookRunner,
 	}
 }
 
@@ -69,26 +126,45 @@ func (command *Push) Metadata() command_metadata.CommandMetadata {
 		Usage: "Push a single app (with or without a manifest):\n" +
 			"   CF_NAME push APP [-b BUILDPACK_NAME] [-c COMMAND] [-d DOMAIN] [-f MANIFEST_PATH]\n" +
 			"   [-i NUM_INSTANCES] [-k DISK] [-m MEMORY] [-n HOST] [-p PATH] [-s STACK] [-t TIMEOUT]\n" +
-			"   [--no-hostname] [--no-manifest] [--no-route] [--no-start]" +
+			"   [--health-check-type TYPE] [--staging-log FILE] [--skip-hooks]\n" +
+			"   [--no-hostname] [--no-manifest] [--no-route] [--no-start] [--strategy blue-green]\n" +
+			"   [--dry-run [--dry-run-format FORMAT]] [--use-gitignore] [--show-ignored]" +
 			"\n\n   Push multiple apps with a manifest:\n" +
-			"   CF_NAME push [-f MANIFEST_PATH]\n",
+			"   CF_NAME push [-f MANIFEST_PATH] [--parallel NUM_APPS]\n" +
+			"\n   Set ((variables)) in the manifest:\n" +
+			"   CF_NAME push [-f MANIFEST_PATH] [--vars-file VARS_FILE] [--var NAME=VALUE] [--vars-from-env]\n",
 		Flags: []cli.Flag{
 			flag_helpers.NewStringFlag("b", "Custom buildpack by name (e.g. my-buildpack) or GIT URL (e.g. https://github.com/heroku/heroku-buildpack-play.git)"),
 			flag_helpers.NewStringFlag("c", "Startup command, set to null to reset to default start command"),
 			flag_helpers.NewStringFlag("d", "Domain (e.g. example.com)"),
 			flag_helpers.NewStringFlag("f", "Path to manifest"),
+			flag_helpers.NewStringFlag("health-check-type", "How to tell that the app has started, 'port' (default) to wait for it to listen on its port, or 'none'"),
 			flag_helpers.NewIntFlag("i", "Number of instances"),
 			flag_helpers.NewStringFlag("k", "Disk limit (e.g. 256M, 1024M, 1G)"),
 			flag_helpers.NewStringFlag("m", "Memory limit (e.g. 256M, 1024M, 1G)"),
 			flag_helpers.NewStringFlag("n", "Hostname (e.g. my-subdomain)"),
-			flag_helpers.NewStringFlag("p", "Path of app directory or zip file"),
+			flag_helpers.NewStringFlag("p", "Path of app directory, or zip, jar, war, tar, tar.gz or tgz file"),
+			flag_helpers.NewIntFlag("parallel", "Number of apps from the manifest to push at once, waiting for the apps each one depends on"),
 			flag_helpers.NewStringFlag("s", "Stack to use (a stack is a pre-built file system, including an operating system, that can run apps)"),
+			flag_helpers.NewStringFlag("staging-log", "Append the staging logs of the apps to FILE"),
 			flag_helpers.NewStringFlag("t", "Start timeout in seconds"),
+			flag_helpers.NewIntFlag("upload-retries", fmt.Sprintf("Number of times to retry uploading the app after a network or server error (Default: %d, or CF_UPLOAD_RETRIES)", api.DefaultAppUploadRetries)),
+			flag_helpers.NewStringSliceFlag("var", "Value for a ((variable)) in the manifest, as NAME=VALUE (can be repeated)"),
+			flag_helpers.NewStringFlag("vars-file", "Path to a YAML file of values for ((variables)) in the manifest"),
+			cli.BoolFlag{Name: "dry-run", Usage: "Show the changes push would make without making them"},
+			flag_helpers.NewStringFlag("dry-run-format", "Format of the --dry-run plan, 'table' (default) or 'json'"),
 			cli.BoolFlag{Name: "no-hostname", Usage: "Map the root domain to this app"},
 			cli.BoolFlag{Name: "no-manifest", Usage: "Ignore manifest file"},
 			cli.BoolFlag{Name: "no-route", Usage: "Do not map a route to this app"},
 			cli.BoolFlag{Name: "no-start", Usage: "Do not start an app after pushing"},
 			cli.BoolFlag{Name: "random-route", Usage: "Create a random route for this app"},
+			cli.BoolFlag{Name: "skip-hooks", Usage: "Do not run the before_push and after_push commands from the manifest"},
+			cli.BoolFlag{Name: "show-ignored", Usage: "List the files left out of each app and the rules that leave them out, without pushing"},
+			cli.BoolFlag{Name: "use-gitignore", Usage: "Also leave out the files that .gitignore files ignore"},
+			cli.BoolFlag{Name: "resume", Usage: "Upload the files saved by the last failed upload of the app instead of matching and zipping them again, unless they have changed since"},
+			cli.BoolFlag{Name: "unbind-unlisted-routes", Usage: "Unbind routes that are not in the manifest's routes, hosts or domains"},
+			cli.BoolFlag{Name: "vars-from-env", Usage: "Use environment variables for ((variables)) in the manifest that have no other value"},
+			flag_helpers.NewStringFlag("strategy", "Deployment strategy, 'blue-green' starts the new version alongside the old one and then moves its routes"),
 		},
 	}
 }
@@ -108,66 +184,562 @@ func (cmd *Push) GetRequirements(requirementsFactory requirements.Factory, c *cl
 }
 
 func (cmd *Push) Run(c *cli.Context) {
+	blueGreen := cmd.isBlueGreen(c)
 	appSet := cmd.findAndValidateAppsToPush(c)
+
+	if c.Bool("show-ignored") {
+		cmd.showIgnoredFiles(appSet, c)
+		return
+	}
+
 	cmd.authRepo.RefreshAuthToken()
 
+	if c.Bool("dry-run") {
+		cmd.printPushPlan(appSet, c)
+		return
+	}
+
+	cmd.appStarter.SetStagingLogPath(c.String("staging-log"))
+
+	parallel := c.Int("parallel")
+	if parallel < 0 {
+		cmd.ui.Failed("Incorrect Usage. The number of apps to push in parallel must be positive.")
+	}
+
+	if parallel > 1 && len(appSet) > 1 {
+		cmd.pushAppsInParallel(appSet, parallel, blueGreen, c)
+		return
+	}
+
 	for _, appParams := range appSet {
-		cmd.fetchStackGuid(&appParams)
+		cmd.pushApp(appParams, blueGreen, c)
+	}
+}
+
+func (cmd *Push) pushApp(appParams models.AppParams, blueGreen bool, c *cli.Context) {
+	cmd.runHooks("before_push", appParams.BeforePush, appParams, c)
 
-		app := cmd.createOrUpdateApp(appParams)
+	cmd.fetchStackGuid(&appParams)
 
-		cmd.bindAppToRoute(app, appParams, c)
+	if blueGreen {
+		existingApp, found := cmd.findExistingApp(appParams)
+		if found {
+			cmd.blueGreenPush(existingApp, appParams, c)
+			cmd.runHooks("after_push", appParams.AfterPush, appParams, c)
+			return
+		}
+	}
 
-		cmd.ui.Say("Uploading %s...", terminal.EntityNameColor(app.Name))
+	app, snapshot := cmd.createOrUpdateApp(appParams)
 
-		apiErr := cmd.appBitsRepo.UploadApp(app.Guid, *appParams.Path, cmd.describeUploadOperation)
-		if apiErr != nil {
-			cmd.ui.Failed(fmt.Sprintf("Error uploading application.\n%s", apiErr.Error()))
+	cmd.bindAppToRoute(app, appParams, c)
+
+	cmd.ui.Say("Uploading %s...", terminal.EntityNameColor(app.Name))
+
+	apiErr := cmd.uploadApp(app.Guid, *appParams.Path, c)
+	if apiErr != nil {
+		cmd.ui.Failed(fmt.Sprintf("Error uploading application.\n%s\n\nTIP: use '%s' to retry the upload without zipping the app again",
+			apiErr.Error(), terminal.CommandColor(cf.Name()+" push "+app.Name+" --resume")))
+		return
+	}
+	cmd.ui.Ok()
+
+	if appParams.ServicesToBind != nil {
+		cmd.bindAppToServices(*appParams.ServicesToBind, app)
+	}
+
+	cmd.restart(app, appParams, snapshot, c)
+
+	cmd.runHooks("after_push", appParams.AfterPush, appParams, c)
+}
+
+// runHooks runs the local commands the manifest gives for an app before or
+// after it is pushed, in the app's directory, or the directory its path is in
+// when it is a file or has not been built yet. Any command failing stops the
+// push.
+func (cmd *Push) runHooks(hookName string, commands *[]string, appParams models.AppParams, c *cli.Context) {
+	if commands == nil || len(*commands) == 0 || c.Bool("skip-hooks") {
+		return
+	}
+
+	dir := *appParams.Path
+	if fileInfo, err := os.Stat(dir); err != nil || !fileInfo.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	env := map[string]string{
+		"CF_ORG":   cmd.config.OrganizationFields().Name,
+		"CF_SPACE": cmd.config.SpaceFields().Name,
+		"CF_APP":   *appParams.Name,
+	}
+
+	for _, command := range *commands {
+		cmd.ui.Say("Running %s command for %s: %s", hookName, terminal.EntityNameColor(*appParams.Name), terminal.CommandColor(command))
+
+		err := cmd.hookRunner.Run(command, dir, env, func(line string) {
+			cmd.ui.Say("  %s", line)
+		})
+		if err != nil {
+			cmd.ui.Failed("The %s command '%s' failed for app %s: %s", hookName, command, *appParams.Name, err.Error())
 			return
 		}
 		cmd.ui.Ok()
+	}
+	cmd.ui.Say("")
+}
+
+type parallelPushResult struct {
+	name string
+	err  error
+}
 
-		if appParams.ServicesToBind != nil {
-			cmd.bindAppToServices(*appParams.ServicesToBind, app)
+// failureRecordingUI remembers the message an app's push failed with, so that
+// it can be shown again in the summary once all the apps are done.
+type failureRecordingUI struct {
+	terminal.UI
+	failure string
+}
+
+func (ui *failureRecordingUI) Failed(message string, args ...interface{}) {
+	ui.failure = fmt.Sprintf(message, args...)
+	ui.UI.Failed("%s", ui.failure)
+}
+
+func (cmd *Push) pushAppsInParallel(appSet []models.AppParams, parallel int, blueGreen bool, c *cli.Context) {
+	for _, appParams := range appSet {
+		if appParams.Name == nil {
+			cmd.ui.Failed("Error: No name found for app")
 		}
+	}
+
+	outputLock := &sync.Mutex{}
+	slots := make(chan bool, parallel)
 
-		cmd.restart(app, appParams, c)
+	finished := map[string]chan bool{}
+	results := map[string]*parallelPushResult{}
+	for _, appParams := range appSet {
+		finished[*appParams.Name] = make(chan bool)
+		results[*appParams.Name] = &parallelPushResult{name: *appParams.Name}
 	}
+
+	for _, appParams := range appSet {
+		go func(appParams models.AppParams) {
+			name := *appParams.Name
+			result := results[name]
+			defer close(finished[name])
+
+			for _, dependency := range dependenciesOf(appParams) {
+				<-finished[dependency]
+				if results[dependency].err != nil {
+					result.err = errors.NewWithFmt("Not pushed because %s failed to push", dependency)
+					return
+				}
+			}
+
+			slots <- true
+			defer func() { <-slots }()
+
+			appUI := &failureRecordingUI{
+				UI: terminal.NewPrefixedUI(cmd.ui, fmt.Sprintf("[%s] ", name), outputLock),
+			}
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered != terminal.FailedWasCalled {
+					panic(recovered)
+				}
+				result.err = errors.New(appUI.failure)
+			}()
+
+			cmd.withUI(appUI).pushApp(appParams, blueGreen, c)
+		}(appParams)
+	}
+
+	failures := []*parallelPushResult{}
+	for _, appParams := range appSet {
+		<-finished[*appParams.Name]
+		if result := results[*appParams.Name]; result.err != nil {
+			failures = append(failures, result)
+		}
+	}
+
+	cmd.ui.Say("")
+	if len(failures) == 0 {
+		cmd.ui.Say("Pushed %d apps", len(appSet))
+		cmd.ui.Ok()
+		return
+	}
+
+	cmd.ui.Say("Pushed %d of %d apps", len(appSet)-len(failures), len(appSet))
+	cmd.ui.Say("")
+
+	table := cmd.ui.Table([]string{"app", "error"})
+	rows := [][]string{}
+	for _, failure := range failures {
+		rows = append(rows, []string{failure.name, strings.Replace(failure.err.Error(), "\n", " ", -1)})
+	}
+	table.Print(rows)
+
+	cmd.ui.Failed("%d of %d apps failed to push", len(failures), len(appSet))
 }
 
-func (cmd *Push) bindAppToServices(services []string, app models.Application) {
-	for _, serviceName := range services {
-		serviceInstance, err := cmd.serviceRepo.FindInstanceByName(serviceName)
+// withUI returns a copy of the command, and of the commands it uses to start
+// and stop apps, that writes all of its output to ui.
+func (cmd *Push) withUI(ui terminal.UI) *Push {
+	pushCmd := *cmd
+	pushCmd.ui = ui
+
+	if starter, ok := cmd.appStarter.(interface {
+		WithUI(terminal.UI) ApplicationStarter
+	}); ok {
+		pushCmd.appStarter = starter.WithUI(ui)
+	}
+
+	if stopper, ok := cmd.appStopper.(interface {
+		WithUI(terminal.UI) ApplicationStopper
+	}); ok {
+		pushCmd.appStopper = stopper.WithUI(ui)
+	}
+
+	return &pushCmd
+}
+
+func dependenciesOf(appParams models.AppParams) []string {
+	if appParams.DependsOn == nil {
+		return []string{}
+	}
+	return *appParams.DependsOn
+}
+
+// orderAppsByDependencies sorts the apps so that every app comes after the
+// apps it depends on, keeping the manifest order wherever it can.
+func orderAppsByDependencies(apps []models.AppParams) (orderedApps []models.AppParams, err error) {
+	appsByName := map[string]models.AppParams{}
+	for _, app := range apps {
+		if app.Name != nil {
+			appsByName[*app.Name] = app
+		}
+	}
+
+	for _, app := range apps {
+		if app.Name == nil {
+			continue
+		}
+		for _, dependency := range dependenciesOf(app) {
+			if _, found := appsByName[dependency]; !found {
+				err = errors.NewWithFmt("App %s depends on %s, which is not being pushed", *app.Name, dependency)
+				return
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := map[string]int{}
+
+	var visit func(app models.AppParams, path []string) error
+	visit = func(app models.AppParams, path []string) error {
+		if app.Name == nil {
+			orderedApps = append(orderedApps, app)
+			return nil
+		}
+
+		name := *app.Name
+		path = append(path, name)
+
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.NewWithFmt("Apps have circular dependencies: %s", strings.Join(path, " -> "))
+		}
+
+		states[name] = visiting
+		for _, dependency := range dependenciesOf(app) {
+			if err := visit(appsByName[dependency], path); err != nil {
+				return err
+			}
+		}
+		states[name] = visited
 
+		orderedApps = append(orderedApps, app)
+		return nil
+	}
+
+	for _, app := range apps {
+		err = visit(app, []string{})
 		if err != nil {
-			cmd.ui.Failed("Could not find service %s to bind to %s", serviceName, app.Name)
 			return
 		}
+	}
 
-		cmd.ui.Say("Binding service %s to app %s in org %s / space %s as %s...",
-			terminal.EntityNameColor(serviceInstance.Name),
-			terminal.EntityNameColor(app.Name),
-			terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
-			terminal.EntityNameColor(cmd.config.SpaceFields().Name),
-			terminal.EntityNameColor(cmd.config.Username()),
-		)
-		err = cmd.serviceBinder.BindApplication(app, serviceInstance)
+	return
+}
+
+func (cmd *Push) isBlueGreen(c *cli.Context) bool {
+	switch c.String("strategy") {
+	case "":
+		return false
+	case BlueGreenStrategy:
+		if c.Bool("no-start") {
+			cmd.ui.Failed("Incorrect Usage. The %s strategy cannot be combined with --no-start.", BlueGreenStrategy)
+		}
+		return true
+	default:
+		cmd.ui.Failed("Invalid deployment strategy: %s\nSupported strategies: %s", c.String("strategy"), BlueGreenStrategy)
+		return false
+	}
+}
+
+func (cmd *Push) findExistingApp(appParams models.AppParams) (app models.Application, found bool) {
+	if appParams.Name == nil {
+		cmd.ui.Failed("Error: No name found for app")
+	}
 
-		switch httpErr := err.(type) {
-		case errors.HttpError:
-			if httpErr.ErrorCode() == errors.APP_ALREADY_BOUND {
-				err = nil
+	app, apiErr := cmd.appRepo.Read(*appParams.Name)
+	switch apiErr.(type) {
+	case nil:
+		found = true
+	case *errors.ModelNotFoundError:
+	default:
+		cmd.ui.Failed(apiErr.Error())
+	}
+	return
+}
+
+func (cmd *Push) blueGreenPush(oldApp models.Application, appParams models.AppParams, c *cli.Context) {
+	newAppName := oldApp.Name + BlueGreenNewAppSuffix
+	oldAppNewName := oldApp.Name + BlueGreenOldAppSuffix
+
+	// The new app keeps the settings and services of the old one that the
+	// manifest and flags do not change, since the old one is deleted.
+	oldAppSummary, apiErr := cmd.appSummaryRepo.GetSummary(oldApp.Guid)
+	if apiErr != nil {
+		cmd.ui.Failed(apiErr.Error())
+		return
+	}
+
+	newAppParams := oldAppSummary.ToParams()
+	newAppParams.Guid = nil
+	newAppParams.State = nil
+	newAppParams.Merge(&appParams)
+	newAppParams.Name = &newAppName
+	newAppParams.NoRoute = true
+	newAppParams.UseRandomHostname = false
+
+	envVars := map[string]string{}
+	for key, val := range oldAppSummary.EnvironmentVars {
+		envVars[key] = val
+	}
+	if appParams.EnvironmentVars != nil {
+		for key, val := range *appParams.EnvironmentVars {
+			envVars[key] = val
+		}
+	}
+	newAppParams.EnvironmentVars = &envVars
+
+	services := []string{}
+	for _, service := range oldAppSummary.Services {
+		services = append(services, service.Name)
+	}
+	if appParams.ServicesToBind != nil {
+		for _, service := range *appParams.ServicesToBind {
+			if !oldAppSummary.HasService(service) {
+				services = append(services, service)
 			}
 		}
+	}
+
+	if !cmd.deleteLeftoverApp(newAppName) || !cmd.deleteLeftoverApp(oldAppNewName) {
+		return
+	}
+
+	newApp, apiErr := cmd.createApp(newAppParams)
+	if apiErr != nil {
+		return
+	}
+
+	cmd.ui.Say("Uploading %s...", terminal.EntityNameColor(newApp.Name))
+
+	apiErr = cmd.uploadApp(newApp.Guid, *appParams.Path, c)
+	if apiErr != nil {
+		cmd.rollBackBlueGreen(oldApp, newApp, nil, fmt.Sprintf("Error uploading application.\n%s", apiErr.Error()))
+		return
+	}
+	cmd.ui.Ok()
+
+	for _, serviceName := range services {
+		apiErr = cmd.bindAppToService(serviceName, newApp)
+		if apiErr != nil {
+			cmd.rollBackBlueGreen(oldApp, newApp, nil, apiErr.Error())
+			return
+		}
+		cmd.ui.Ok()
+	}
+
+	cmd.ui.Say("")
+
+	if newAppParams.HealthCheckTimeout != nil {
+		cmd.appStarter.SetStartTimeoutInSeconds(*newAppParams.HealthCheckTimeout)
+	}
+
+	_, apiErr = cmd.appStarter.ApplicationStart(newApp)
+	if apiErr != nil {
+		cmd.rollBackBlueGreen(oldApp, newApp, nil, apiErr.Error())
+		return
+	}
+
+	movedRoutes := []models.RouteSummary{}
+	for _, route := range oldApp.Routes {
+		cmd.ui.Say("Moving route %s from %s to %s...",
+			terminal.EntityNameColor(route.URL()),
+			terminal.EntityNameColor(oldApp.Name),
+			terminal.EntityNameColor(newApp.Name),
+		)
+
+		apiErr = cmd.routeRepo.Bind(route.Guid, newApp.Guid)
+		if apiErr != nil {
+			cmd.rollBackBlueGreen(oldApp, newApp, movedRoutes, apiErr.Error())
+			return
+		}
+		movedRoutes = append(movedRoutes, route)
+
+		apiErr = cmd.routeRepo.Unbind(route.Guid, oldApp.Guid)
+		if apiErr != nil {
+			cmd.rollBackBlueGreen(oldApp, newApp, movedRoutes, apiErr.Error())
+			return
+		}
+
+		cmd.ui.Ok()
+	}
+
+	// The old app is only deleted once the new one has its name, so that
+	// there is always an app by that name to roll back to.
+	cmd.ui.Say("Renaming app %s to %s...", terminal.EntityNameColor(oldApp.Name), terminal.EntityNameColor(oldAppNewName))
+	_, apiErr = cmd.appRepo.Update(oldApp.Guid, models.AppParams{Name: &oldAppNewName})
+	if apiErr != nil {
+		cmd.rollBackBlueGreen(oldApp, newApp, movedRoutes, apiErr.Error())
+		return
+	}
+	cmd.ui.Ok()
+
+	cmd.ui.Say("Renaming app %s to %s...", terminal.EntityNameColor(newApp.Name), terminal.EntityNameColor(oldApp.Name))
+	renamedApp, apiErr := cmd.appRepo.Update(newApp.Guid, models.AppParams{Name: &oldApp.Name})
+	if apiErr != nil {
+		cmd.ui.Say("Renaming app %s back to %s...", terminal.EntityNameColor(oldAppNewName), terminal.EntityNameColor(oldApp.Name))
+		_, renameErr := cmd.appRepo.Update(oldApp.Guid, models.AppParams{Name: &oldApp.Name})
+		if renameErr != nil {
+			cmd.ui.Warn("Could not rename app %s back to %s: %s", oldAppNewName, oldApp.Name, renameErr.Error())
+		}
+		cmd.rollBackBlueGreen(oldApp, newApp, movedRoutes, apiErr.Error())
+		return
+	}
+	cmd.ui.Ok()
+
+	cmd.ui.Say("Deleting old app %s...", terminal.EntityNameColor(oldAppNewName))
+	apiErr = cmd.appRepo.Delete(oldApp.Guid)
+	if apiErr != nil {
+		cmd.ui.Warn("Could not delete old app %s: %s", oldAppNewName, apiErr.Error())
+	} else {
+		cmd.ui.Ok()
+	}
+	cmd.ui.Say("")
+
+	newApp = renamedApp
+
+	newApp.Routes = movedRoutes
+	cmd.bindAppToRoute(newApp, appParams, c)
+}
+
+// deleteLeftoverApp deletes the app called name when a blue-green push that
+// failed part way left it behind, so that the name can be used again. It is
+// false when the app could not be read or deleted.
+func (cmd *Push) deleteLeftoverApp(name string) bool {
+	app, apiErr := cmd.appRepo.Read(name)
+	switch apiErr.(type) {
+	case nil:
+	case *errors.ModelNotFoundError:
+		return true
+	default:
+		cmd.ui.Failed(apiErr.Error())
+		return false
+	}
+
+	cmd.ui.Say("Deleting app %s left over from an earlier blue-green push...", terminal.EntityNameColor(name))
+	apiErr = cmd.appRepo.Delete(app.Guid)
+	if apiErr != nil {
+		cmd.ui.Failed(apiErr.Error())
+		return false
+	}
+	cmd.ui.Ok()
+	return true
+}
+
+func (cmd *Push) rollBackBlueGreen(oldApp, newApp models.Application, movedRoutes []models.RouteSummary, reason string) {
+	cmd.ui.Say("")
+	cmd.ui.Warn("Blue-green deployment of %s failed, rolling back...", oldApp.Name)
+
+	for _, route := range movedRoutes {
+		cmd.ui.Say("Moving route %s back to %s...", terminal.EntityNameColor(route.URL()), terminal.EntityNameColor(oldApp.Name))
+		cmd.routeRepo.Bind(route.Guid, oldApp.Guid)
+		cmd.routeRepo.Unbind(route.Guid, newApp.Guid)
+	}
+
+	cmd.ui.Say("Deleting app %s...", terminal.EntityNameColor(newApp.Name))
+	apiErr := cmd.appRepo.Delete(newApp.Guid)
+	if apiErr != nil {
+		cmd.ui.Warn("Could not delete app %s: %s", newApp.Name, apiErr.Error())
+	}
+
+	cmd.ui.Failed("%s\n\nApp %s is still running the previous version.", reason, oldApp.Name)
+}
 
+func (cmd *Push) bindAppToServices(services []string, app models.Application) {
+	for _, serviceName := range services {
+		err := cmd.bindAppToService(serviceName, app)
 		if err != nil {
-			cmd.ui.Failed("Could not bind to service %s\nError: %s", serviceName, err)
+			cmd.ui.Failed(err.Error())
+			return
 		}
 
 		cmd.ui.Ok()
 	}
 }
 
+func (cmd *Push) bindAppToService(serviceName string, app models.Application) error {
+	serviceInstance, err := cmd.serviceRepo.FindInstanceByName(serviceName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not find service %s to bind to %s", serviceName, app.Name))
+	}
+
+	cmd.ui.Say("Binding service %s to app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(serviceInstance.Name),
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+	err = cmd.serviceBinder.BindApplication(app, serviceInstance)
+
+	switch httpErr := err.(type) {
+	case errors.HttpError:
+		if httpErr.ErrorCode() == errors.APP_ALREADY_BOUND {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not bind to service %s\nError: %s", serviceName, err))
+	}
+	return nil
+}
+
 func (cmd *Push) describeUploadOperation(path string, zipFileBytes, fileCount uint64) {
 	if fileCount > 0 {
 		cmd.ui.Say("Uploading app files from: %s", path)
@@ -177,6 +749,63 @@ func (cmd *Push) describeUploadOperation(path string, zipFileBytes, fileCount ui
 	}
 }
 
+func (cmd *Push) uploadApp(appGuid, appDir string, c *cli.Context) (apiErr error) {
+	progress := cmd.ui.ProgressBar()
+
+	opts := cmd.uploadOptions(c)
+	opts.Progress = progress.Update
+
+	apiErr = cmd.appBitsRepo.UploadApp(appGuid, appDir, opts, cmd.describeUploadOperation)
+	progress.Finish(apiErr)
+	return
+}
+
+func (cmd *Push) uploadOptions(c *cli.Context) (opts api.UploadOptions) {
+	opts.Resume = c.Bool("resume")
+	opts.Ignore = ignoreOptions(c)
+	opts.Retries = api.DefaultAppUploadRetries
+
+	if os.Getenv("CF_UPLOAD_RETRIES") != "" {
+		retries, err := strconv.Atoi(os.Getenv("CF_UPLOAD_RETRIES"))
+		if err != nil {
+			cmd.ui.Failed("invalid value for env var CF_UPLOAD_RETRIES\n%s", err)
+		}
+		opts.Retries = retries
+	}
+
+	if c.IsSet("upload-retries") {
+		opts.Retries = c.Int("upload-retries")
+	}
+	return
+}
+
+func ignoreOptions(c *cli.Context) app_files.IgnoreOptions {
+	return app_files.IgnoreOptions{UseGitignore: c.Bool("use-gitignore")}
+}
+
+func (cmd *Push) showIgnoredFiles(appSet []models.AppParams, c *cli.Context) {
+	for _, appParams := range appSet {
+		ignoredFiles, err := cmd.appBitsRepo.IgnoredFiles(*appParams.Path, ignoreOptions(c))
+		if err != nil {
+			cmd.ui.Failed("Error listing ignored files.\n%s", err.Error())
+			return
+		}
+
+		cmd.ui.Say("Files left out of app %s from %s:", terminal.EntityNameColor(*appParams.Name), *appParams.Path)
+		if len(ignoredFiles) == 0 {
+			cmd.ui.Say("none\n")
+			continue
+		}
+
+		rows := [][]string{}
+		for _, ignoredFile := range ignoredFiles {
+			rows = append(rows, []string{ignoredFile.Path, ignoredFile.Rule.String()})
+		}
+		cmd.ui.Table([]string{"file", "ignored by"}).Print(rows)
+		cmd.ui.Say("")
+	}
+}
+
 func (cmd *Push) fetchStackGuid(appParams *models.AppParams) {
 	if appParams.StackName == nil {
 		return
@@ -209,14 +838,65 @@ func (cmd *Push) bindAppToRoute(app models.Application, params models.AppParams,
 		return
 	}
 
-	routeFlagsPresent := c.String("n") != "" || c.String("d") != "" || c.Bool("no-hostname")
-	if len(app.Routes) > 0 && !routeFlagsPresent {
+	if hasRouteLists(params) {
+		cmd.bindAppToRoutes(app, params, c)
+		return
+	}
+
+	routeFlagsPresent := c.String("n") != "" || c.String("d") != "" || c.Bool("no-hostname")
+	if len(app.Routes) > 0 && !routeFlagsPresent {
+		return
+	}
+
+	domain := cmd.findDomain(params)
+	hostname := cmd.hostnameForApp(params, c)
+
+	cmd.bindRouteToApp(app, hostname, domain)
+}
+
+func (cmd *Push) bindAppToRoutes(app models.Application, params models.AppParams, c *cli.Context) {
+	listedRoutes := map[string]bool{}
+	for _, spec := range cmd.listedRoutes(params, c) {
+		route := cmd.bindRouteToApp(app, spec.host, spec.domain)
+		listedRoutes[route.Guid] = true
+	}
+
+	if !c.Bool("unbind-unlisted-routes") {
+		return
+	}
+
+	for _, route := range app.Routes {
+		if !listedRoutes[route.Guid] {
+			cmd.ui.Say("Removing route %s...", terminal.EntityNameColor(route.URL()))
+			cmd.routeRepo.Unbind(route.Guid, app.Guid)
+		}
+	}
+}
+
+func (cmd *Push) bindRouteToApp(app models.Application, hostname string, domain models.DomainFields) (route models.Route) {
+	route = cmd.findOrCreateRoute(hostname, domain)
+	if app.HasRoute(route) {
+		return
+	}
+
+	cmd.ui.Say("Binding %s to %s...", terminal.EntityNameColor(domain.UrlForHost(hostname)), terminal.EntityNameColor(app.Name))
+
+	apiErr := cmd.routeRepo.Bind(route.Guid, app.Guid)
+	switch apiErr := apiErr.(type) {
+	case nil:
+		cmd.ui.Ok()
+		cmd.ui.Say("")
 		return
+	case errors.HttpError:
+		if apiErr.ErrorCode() == errors.INVALID_RELATION {
+			cmd.ui.Failed("The route %s is already in use.\nTIP: Change the hostname with -n HOSTNAME or use --random-route to generate a new route and then push again.", route.URL())
+		}
 	}
+	cmd.ui.Failed(apiErr.Error())
+	return
+}
 
-	domain := cmd.findDomain(params)
-	hostname := cmd.hostnameForApp(params, c)
-
+func (cmd *Push) findOrCreateRoute(hostname string, domain models.DomainFields) (route models.Route) {
 	route, apiErr := cmd.routeRepo.FindByHostAndDomain(hostname, domain.Name)
 
 	switch apiErr.(type) {
@@ -236,22 +916,89 @@ func (cmd *Push) bindAppToRoute(app models.Application, params models.AppParams,
 		cmd.ui.Failed(apiErr.Error())
 	}
 
-	if !app.HasRoute(route) {
-		cmd.ui.Say("Binding %s to %s...", terminal.EntityNameColor(domain.UrlForHost(hostname)), terminal.EntityNameColor(app.Name))
+	return
+}
+
+type routeSpec struct {
+	host   string
+	domain models.DomainFields
+}
+
+func hasRouteLists(params models.AppParams) bool {
+	return (params.Routes != nil && len(*params.Routes) > 0) ||
+		(params.Hosts != nil && len(*params.Hosts) > 0) ||
+		(params.Domains != nil && len(*params.Domains) > 0)
+}
+
+// listedRoutes returns the routes given by the routes, hosts and domains
+// lists in the manifest. Every host is combined with every domain, using the
+// app's usual hostname or domain when only one of the lists is given.
+func (cmd *Push) listedRoutes(params models.AppParams, c *cli.Context) (specs []routeSpec) {
+	if params.Routes != nil {
+		for _, url := range *params.Routes {
+			specs = append(specs, cmd.routeSpecForURL(url))
+		}
+	}
+
+	hosts := []string{}
+	if params.Hosts != nil {
+		hosts = *params.Hosts
+	}
+
+	domainNames := []string{}
+	if params.Domains != nil {
+		domainNames = *params.Domains
+	}
+
+	if len(hosts) == 0 && len(domainNames) == 0 {
+		return
+	}
+
+	if len(hosts) == 0 {
+		hosts = []string{cmd.hostnameForApp(params, c)}
+	}
+
+	domains := []models.DomainFields{}
+	for _, domainName := range domainNames {
+		domain, err := cmd.domainRepo.FindByNameInOrg(domainName, cmd.config.OrganizationFields().Guid)
+		if err != nil {
+			cmd.ui.Failed(err.Error())
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		domains = []models.DomainFields{cmd.findDomain(params)}
+	}
+
+	for _, host := range hosts {
+		for _, domain := range domains {
+			specs = append(specs, routeSpec{host: host, domain: domain})
+		}
+	}
 
-		apiErr = cmd.routeRepo.Bind(route.Guid, app.Guid)
-		switch apiErr := apiErr.(type) {
+	return
+}
+
+// routeSpecForURL splits a route such as 'api.example.com' into its hostname
+// and the longest domain in the org that it ends with.
+func (cmd *Push) routeSpecForURL(url string) routeSpec {
+	parts := strings.Split(url, ".")
+	for i := range parts {
+		domainName := strings.Join(parts[i:], ".")
+		domain, err := cmd.domainRepo.FindByNameInOrg(domainName, cmd.config.OrganizationFields().Guid)
+
+		switch err.(type) {
 		case nil:
-			cmd.ui.Ok()
-			cmd.ui.Say("")
-			return
-		case errors.HttpError:
-			if apiErr.ErrorCode() == errors.INVALID_RELATION {
-				cmd.ui.Failed("The route %s is already in use.\nTIP: Change the hostname with -n HOSTNAME or use --random-route to generate a new route and then push again.", route.URL())
-			}
+			return routeSpec{host: strings.Join(parts[:i], "."), domain: domain}
+		case *errors.ModelNotFoundError:
+			continue
+		default:
+			cmd.ui.Failed(err.Error())
 		}
-		cmd.ui.Failed(apiErr.Error())
 	}
+
+	cmd.ui.Failed("Could not find a domain for the route %s", url)
+	return routeSpec{}
 }
 
 func (cmd Push) hostnameForApp(appParams models.AppParams, c *cli.Context) string {
@@ -278,7 +1025,7 @@ func hostNameForString(name string) string {
 	return name
 }
 
-func (cmd *Push) restart(app models.Application, params models.AppParams, c *cli.Context) {
+func (cmd *Push) restart(app models.Application, params models.AppParams, snapshot *appSnapshot, c *cli.Context) {
 	if app.State != "stopped" {
 		cmd.ui.Say("")
 		app, _ = cmd.appStopper.ApplicationStop(app)
@@ -294,7 +1041,109 @@ func (cmd *Push) restart(app models.Application, params models.AppParams, c *cli
 		cmd.appStarter.SetStartTimeoutInSeconds(*params.HealthCheckTimeout)
 	}
 
-	cmd.appStarter.ApplicationStart(app)
+	_, err := cmd.appStarter.ApplicationStart(app)
+	if err != nil && snapshot != nil {
+		cmd.rollBack(app, params, *snapshot, err.Error())
+	} else if err != nil {
+		cmd.ui.Failed(err.Error())
+	}
+}
+
+// appSnapshot is how an existing app was set up before push updated it.
+type appSnapshot struct {
+	app    models.Application
+	params models.AppParams
+	routes []models.RouteSummary
+}
+
+func newAppSnapshot(app models.Application) *appSnapshot {
+	params := app.ToParams()
+	if *params.EnvironmentVars == nil {
+		envVars := map[string]string{}
+		params.EnvironmentVars = &envVars
+	}
+	return &appSnapshot{app: app, params: params, routes: app.Routes}
+}
+
+// rollBack puts an app that failed to start back the way it was before it
+// was pushed, restarting it if it was running. The new bits stay uploaded,
+// as the old ones cannot be restored.
+func (cmd *Push) rollBack(app models.Application, params models.AppParams, snapshot appSnapshot, reason string) {
+	cmd.ui.Say("")
+	cmd.ui.Warn("App %s failed to start, rolling back to its previous settings...", app.Name)
+
+	restoreParams := snapshot.params
+	stopped := "STOPPED"
+	restoreParams.State = &stopped
+
+	restoredApp, apiErr := cmd.appRepo.Update(app.Guid, restoreParams)
+	if apiErr != nil {
+		cmd.ui.Failed("%s\n\nCould not roll back app %s: %s", reason, app.Name, apiErr.Error())
+		return
+	}
+	cmd.ui.Ok()
+
+	changes := planFieldChanges(snapshot.app, params)
+	if len(changes) > 0 {
+		cmd.ui.Say("")
+		rows := [][]string{}
+		for _, change := range changes {
+			rows = append(rows, []string{change.Field, change.Desired, change.Current})
+		}
+		cmd.ui.Table([]string{"reverted", "pushed", "restored"}).Print(rows)
+	}
+
+	cmd.restoreRoutes(app, snapshot.routes)
+
+	if *snapshot.params.State == "STARTED" {
+		cmd.ui.Say("")
+		_, apiErr = cmd.appStarter.ApplicationStart(restoredApp)
+		if apiErr != nil {
+			cmd.ui.Failed("%s\n\nApp %s was rolled back, but could not be restarted: %s", reason, app.Name, apiErr.Error())
+			return
+		}
+	}
+
+	cmd.ui.Failed("%s\n\nApp %s was rolled back to its previous settings and routes. The new bits are still uploaded.", reason, app.Name)
+}
+
+// restoreRoutes binds app to exactly the given routes again, undoing the
+// route changes push made.
+func (cmd *Push) restoreRoutes(app models.Application, routes []models.RouteSummary) {
+	currentApp, apiErr := cmd.appRepo.Read(app.Name)
+	if apiErr != nil {
+		cmd.ui.Warn("Could not restore the routes of app %s: %s", app.Name, apiErr.Error())
+		return
+	}
+
+	for _, route := range currentApp.Routes {
+		if !containsRoute(routes, route) {
+			cmd.ui.Say("Removing route %s...", terminal.EntityNameColor(route.URL()))
+			apiErr = cmd.routeRepo.Unbind(route.Guid, app.Guid)
+			if apiErr != nil {
+				cmd.ui.Warn("Could not remove route %s: %s", route.URL(), apiErr.Error())
+			}
+		}
+	}
+
+	for _, route := range routes {
+		if !containsRoute(currentApp.Routes, route) {
+			cmd.ui.Say("Binding %s back to %s...", terminal.EntityNameColor(route.URL()), terminal.EntityNameColor(app.Name))
+			apiErr = cmd.routeRepo.Bind(route.Guid, app.Guid)
+			if apiErr != nil {
+				cmd.ui.Warn("Could not bind route %s: %s", route.URL(), apiErr.Error())
+			}
+		}
+	}
+}
+
+func containsRoute(routes []models.RouteSummary, route models.RouteSummary) bool {
+	for _, candidate := range routes {
+		if candidate.Guid == route.Guid {
+			return true
+		}
+	}
+	return false
 }
 
 func (cmd *Push) findDomain(appParams models.AppParams) (domain models.DomainFields) {
@@ -331,7 +1180,9 @@ func (cmd *Push) findDefaultDomain() (models.DomainFields, error) {
 	return *foundDomain, nil
 }
 
-func (cmd *Push) createOrUpdateApp(appParams models.AppParams) (app models.Application) {
+// createOrUpdateApp returns the app, and for an app that already existed how
+// it was set up before it was updated.
+func (cmd *Push) createOrUpdateApp(appParams models.AppParams) (app models.Application, snapshot *appSnapshot) {
 	if appParams.Name == nil {
 		cmd.ui.Failed("Error: No name found for app")
 	}
@@ -340,6 +1191,7 @@ func (cmd *Push) createOrUpdateApp(appParams models.AppParams) (app models.Appli
 
 	switch apiErr.(type) {
 	case nil:
+		snapshot = newAppSnapshot(app)
 		app = cmd.updateApp(app, appParams)
 	case *errors.ModelNotFoundError:
 		app, apiErr = cmd.createApp(appParams)
@@ -410,7 +1262,14 @@ func (cmd *Push) updateApp(app models.Application, appParams models.AppParams) (
 func (cmd *Push) findAndValidateAppsToPush(c *cli.Context) []models.AppParams {
 	appsFromManifest := cmd.getAppParamsFromManifest(c)
 	appFromContext := cmd.getAppParamsFromContext(c)
-	return cmd.createAppSetFromContextAndManifest(appFromContext, appsFromManifest)
+	appSet := cmd.createAppSetFromContextAndManifest(appFromContext, appsFromManifest)
+
+	orderedAppSet, err := orderAppsByDependencies(appSet)
+	if err != nil {
+		cmd.ui.Failed("Error reading manifest file:\n%s", err)
+	}
+
+	return orderedAppSet
 }
 
 func (cmd *Push) getAppParamsFromManifest(c *cli.Context) []models.AppParams {
@@ -439,15 +1298,66 @@ func (cmd *Push) getAppParamsFromManifest(c *cli.Context) []models.AppParams {
 		}
 	}
 
+	cmd.validateManifest(c, m.Path)
+
+	m.Variables = cmd.manifestVariables(c)
+	m.VariablesFromEnv = c.Bool("vars-from-env")
+
 	apps, err := m.Applications()
 	if err != nil {
 		cmd.ui.Failed("Error reading manifest file:\n%s", err)
 	}
 
-	cmd.ui.Say("Using manifest file %s\n", terminal.EntityNameColor(m.Path))
+	if !isJSONDryRun(c) {
+		cmd.ui.Say("Using manifest file %s\n", terminal.EntityNameColor(m.Path))
+	}
 	return apps
 }
 
+// validateManifest fails on errors in the manifest, but only warns about keys
+// it does not know, which may be meant for another version of cf.
+// validate-manifest treats those as errors too.
+func (cmd *Push) validateManifest(c *cli.Context, path string) {
+	errs := []error{}
+	for _, err := range cmd.manifestRepo.ValidateManifest(path) {
+		if manifestErr, ok := err.(manifest.ManifestError); ok && manifestErr.UnknownKey {
+			if !isJSONDryRun(c) {
+				cmd.ui.Warn("Warning: %s", manifestErr)
+			}
+			continue
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		cmd.ui.Failed("Invalid manifest file:\n%s", errors.NewWithSlice(errs))
+	}
+}
+
+func (cmd *Push) manifestVariables(c *cli.Context) map[string]string {
+	vars := map[string]string{}
+
+	if c.String("vars-file") != "" {
+		fileVars, err := manifest.ReadVariablesFile(c.String("vars-file"))
+		if err != nil {
+			cmd.ui.Failed(err.Error())
+		}
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+
+	flagVars, err := manifest.ParseVariables(c.StringSlice("var"))
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+	}
+	for name, value := range flagVars {
+		vars[name] = value
+	}
+
+	return vars
+}
+
 func (cmd *Push) createAppSetFromContextAndManifest(contextApp models.AppParams, manifestApps []models.AppParams) (apps []models.AppParams) {
 	var err error
 
@@ -572,6 +1482,14 @@ func (cmd *Push) getAppParamsFromContext(c *cli.Context) (appParams models.AppPa
 		appParams.Path = &path
 	}
 
+	if c.String("health-check-type") != "" {
+		healthCheckType := c.String("health-check-type")
+		if !models.IsValidHealthCheckType(healthCheckType) {
+			cmd.ui.Failed("Invalid health check type: %s\nSupported types: %s", healthCheckType, strings.Join(models.HealthCheckTypes, ", "))
+		}
+		appParams.HealthCheckType = &healthCheckType
+	}
+
 	if c.String("s") != "" {
 		stackName := c.String("s")
 		appParams.StackName = &stackName
@@ -588,3 +1506,245 @@ func (cmd *Push) getAppParamsFromContext(c *cli.Context) (appParams models.AppPa
 
 	return
 }
+
+func (cmd *Push) printPushPlan(appSet []models.AppParams, c *cli.Context) {
+	format := c.String("dry-run-format")
+	if format != "" && format != "table" && format != "json" {
+		cmd.ui.Failed("Invalid dry run format: %s\nSupported formats: table, json", format)
+	}
+
+	plan := PushPlan{Apps: []AppPlan{}}
+	for _, appParams := range appSet {
+		plan.Apps = append(plan.Apps, cmd.planApp(appParams, c))
+	}
+
+	if format == "json" {
+		output, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			cmd.ui.Failed(err.Error())
+		}
+		cmd.ui.Say("%s", output)
+		return
+	}
+
+	for _, appPlan := range plan.Apps {
+		cmd.printAppPlan(appPlan)
+	}
+}
+
+func isJSONDryRun(c *cli.Context) bool {
+	return c.Bool("dry-run") && c.String("dry-run-format") == "json"
+}
+
+func (cmd *Push) printAppPlan(appPlan AppPlan) {
+	cmd.ui.Say("Plan for app %s: %s\n", terminal.EntityNameColor(appPlan.Name), appPlan.Action)
+
+	if len(appPlan.Changes) > 0 {
+		table := cmd.ui.Table([]string{"field", "current", "desired"})
+		rows := [][]string{}
+		for _, change := range appPlan.Changes {
+			rows = append(rows, []string{change.Field, change.Current, change.Desired})
+		}
+		table.Print(rows)
+		cmd.ui.Say("")
+	}
+
+	if len(appPlan.Routes) > 0 {
+		table := cmd.ui.Table([]string{"route", "action"})
+		rows := [][]string{}
+		for _, route := range appPlan.Routes {
+			rows = append(rows, []string{route.URL, route.Action})
+		}
+		table.Print(rows)
+		cmd.ui.Say("")
+	}
+
+	if len(appPlan.Services) > 0 {
+		table := cmd.ui.Table([]string{"service", "action"})
+		rows := [][]string{}
+		for _, service := range appPlan.Services {
+			rows = append(rows, []string{service.Name, service.Action})
+		}
+		table.Print(rows)
+		cmd.ui.Say("")
+	}
+
+	cmd.ui.Say("Files to upload: %d (%s)\n", appPlan.FilesToUpload, formatters.ByteSize(uint64(appPlan.BytesToUpload)))
+}
+
+func (cmd *Push) planApp(appParams models.AppParams, c *cli.Context) (appPlan AppPlan) {
+	if appParams.Name == nil {
+		cmd.ui.Failed("Error: No name found for app")
+	}
+
+	appPlan.Name = *appParams.Name
+
+	app, apiErr := cmd.appRepo.Read(*appParams.Name)
+	switch apiErr.(type) {
+	case nil:
+		appPlan.Action = PlanActionUpdate
+	case *errors.ModelNotFoundError:
+		appPlan.Action = PlanActionCreate
+	default:
+		cmd.ui.Failed(apiErr.Error())
+		return
+	}
+
+	appPlan.Changes = planFieldChanges(app, appParams)
+	appPlan.Routes = cmd.planRoutes(app, appParams, c)
+	appPlan.Services = cmd.planServices(app, appParams)
+
+	// a dry run must not change anything, so the fingerprints are not saved
+	filesToUpload, apiErr := cmd.appBitsRepo.MatchFiles(*appParams.Path, api.MatchOptions{Ignore: ignoreOptions(c), NoSave: true, AppGuid: app.Guid})
+	if apiErr != nil {
+		cmd.ui.Failed("Error matching application files.\n%s", apiErr.Error())
+		return
+	}
+
+	appPlan.FilesToUpload = len(filesToUpload)
+	for _, file := range filesToUpload {
+		appPlan.BytesToUpload += file.Size
+	}
+
+	return
+}
+
+func planFieldChanges(app models.Application, params models.AppParams) (changes []FieldChange) {
+	addChange := func(field, current, desired string) {
+		if current != desired {
+			changes = append(changes, FieldChange{Field: field, Current: current, Desired: desired})
+		}
+	}
+
+	if params.BuildpackUrl != nil {
+		addChange("buildpack", app.BuildpackUrl, *params.BuildpackUrl)
+	}
+	if params.Command != nil {
+		addChange("command", app.Command, *params.Command)
+	}
+	if params.DiskQuota != nil {
+		addChange("disk_quota", megabytesOrEmpty(app.DiskQuota), megabytesOrEmpty(*params.DiskQuota))
+	}
+	if params.InstanceCount != nil {
+		current := ""
+		if app.Guid != "" {
+			current = fmt.Sprintf("%d", app.InstanceCount)
+		}
+		addChange("instances", current, fmt.Sprintf("%d", *params.InstanceCount))
+	}
+	if params.HealthCheckType != nil {
+		addChange("health-check-type", app.HealthCheckType, *params.HealthCheckType)
+	}
+	if params.Memory != nil {
+		addChange("memory", megabytesOrEmpty(app.Memory), megabytesOrEmpty(*params.Memory))
+	}
+	if params.StackName != nil {
+		current := ""
+		if app.Stack != nil {
+			current = app.Stack.Name
+		}
+		addChange("stack", current, *params.StackName)
+	}
+	if params.EnvironmentVars != nil {
+		keys := []string{}
+		for key := range *params.EnvironmentVars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			addChange("env."+key, app.EnvironmentVars[key], (*params.EnvironmentVars)[key])
+		}
+	}
+
+	return
+}
+
+func megabytesOrEmpty(megabytes uint64) string {
+	if megabytes == 0 {
+		return ""
+	}
+	return formatters.ByteSize(megabytes * formatters.MEGABYTE)
+}
+
+func (cmd *Push) planRoutes(app models.Application, params models.AppParams, c *cli.Context) (routes []RoutePlan) {
+	if params.NoRoute {
+		for _, route := range app.Routes {
+			routes = append(routes, RoutePlan{URL: route.URL(), Action: PlanActionUnbind})
+		}
+		return
+	}
+
+	if hasRouteLists(params) {
+		listedURLs := map[string]bool{}
+		for _, spec := range cmd.listedRoutes(params, c) {
+			routes = append(routes, cmd.planRoute(app, spec.host, spec.domain))
+			listedURLs[spec.domain.UrlForHost(spec.host)] = true
+		}
+
+		if c.Bool("unbind-unlisted-routes") {
+			for _, route := range app.Routes {
+				if !listedURLs[route.URL()] {
+					routes = append(routes, RoutePlan{URL: route.URL(), Action: PlanActionUnbind})
+				}
+			}
+		}
+		return
+	}
+
+	routeFlagsPresent := c.String("n") != "" || c.String("d") != "" || c.Bool("no-hostname")
+	if len(app.Routes) > 0 && !routeFlagsPresent {
+		for _, route := range app.Routes {
+			routes = append(routes, RoutePlan{URL: route.URL(), Action: PlanActionUnchanged})
+		}
+		return
+	}
+
+	domain := cmd.findDomain(params)
+	hostname := cmd.hostnameForApp(params, c)
+	routes = append(routes, cmd.planRoute(app, hostname, domain))
+
+	return
+}
+
+func (cmd *Push) planRoute(app models.Application, hostname string, domain models.DomainFields) (routePlan RoutePlan) {
+	route, apiErr := cmd.routeRepo.FindByHostAndDomain(hostname, domain.Name)
+	switch apiErr.(type) {
+	case nil:
+		routePlan = RoutePlan{URL: route.URL(), Action: PlanActionBind}
+		if app.HasRoute(route) {
+			routePlan.Action = PlanActionUnchanged
+		}
+	case *errors.ModelNotFoundError:
+		routePlan = RoutePlan{URL: domain.UrlForHost(hostname), Action: PlanActionCreateAndBind}
+	default:
+		cmd.ui.Failed(apiErr.Error())
+	}
+
+	return
+}
+
+func (cmd *Push) planServices(app models.Application, params models.AppParams) (services []ServicePlan) {
+	if params.ServicesToBind == nil {
+		return
+	}
+
+	for _, serviceName := range *params.ServicesToBind {
+		serviceInstance, err := cmd.serviceRepo.FindInstanceByName(serviceName)
+		if err != nil {
+			cmd.ui.Failed("Could not find service %s to bind to %s", serviceName, *params.Name)
+			return
+		}
+
+		action := PlanActionBind
+		for _, binding := range serviceInstance.ServiceBindings {
+			if app.Guid != "" && binding.AppGuid == app.Guid {
+				action = PlanActionUnchanged
+			}
+		}
+
+		services = append(services, ServicePlan{Name: serviceName, Action: action})
+	}
+
+	return
+}
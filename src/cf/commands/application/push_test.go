@@ -1,21 +1,27 @@
 package application_test
 
 import (
+	"cf/api"
+	"cf/app_files"
 	. "cf/commands/application"
 	"cf/configuration"
 	"cf/errors"
 	"cf/manifest"
 	"cf/models"
+	"encoding/json"
 	"generic"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
 	testconfig "testhelpers/configuration"
+	testhooks "testhelpers/hooks"
 	"testhelpers/maker"
 	testmanifest "testhelpers/manifest"
 	testreq "testhelpers/requirements"
@@ -34,11 +40,13 @@ var _ = Describe("Push Command", func() {
 		stopper             *testcmd.FakeAppStopper
 		serviceBinder       *testcmd.FakeAppBinder
 		appRepo             *testapi.FakeApplicationRepository
+		appSummaryRepo      *testapi.FakeAppSummaryRepo
 		domainRepo          *testapi.FakeDomainRepository
 		routeRepo           *testapi.FakeRouteRepository
 		stackRepo           *testapi.FakeStackRepository
 		appBitsRepo         *testapi.FakeApplicationBitsRepository
 		serviceRepo         *testapi.FakeServiceRepo
+		hookRunner          *testhooks.FakeRunner
 		wordGenerator       words.WordGenerator
 		requirementsFactory *testreq.FakeReqFactory
 		authRepo            *testapi.FakeAuthenticationRepository
@@ -50,6 +58,7 @@ var _ = Describe("Push Command", func() {
 		stopper = &testcmd.FakeAppStopper{}
 		serviceBinder = &testcmd.FakeAppBinder{}
 		appRepo = &testapi.FakeApplicationRepository{}
+		appSummaryRepo = &testapi.FakeAppSummaryRepo{}
 
 		domainRepo = &testapi.FakeDomainRepository{}
 		sharedDomain := maker.NewSharedDomainFields(maker.Overrides{"name": "foo.cf-app.com", "guid": "foo-domain-guid"})
@@ -61,6 +70,7 @@ var _ = Describe("Push Command", func() {
 		serviceRepo = &testapi.FakeServiceRepo{}
 		authRepo = &testapi.FakeAuthenticationRepository{}
 		wordGenerator = testwords.NewFakeWordGenerator("laughing-cow")
+		hookRunner = &testhooks.FakeRunner{}
 
 		ui = new(testterm.FakeUI)
 		configRepo = testconfig.NewRepositoryWithDefaults()
@@ -69,13 +79,15 @@ var _ = Describe("Push Command", func() {
 
 		cmd = NewPush(ui, configRepo, manifestRepo, starter, stopper, serviceBinder,
 			appRepo,
+			appSummaryRepo,
 			domainRepo,
 			routeRepo,
 			stackRepo,
 			serviceRepo,
 			appBitsRepo,
 			authRepo,
-			wordGenerator)
+			wordGenerator,
+			hookRunner)
 	})
 
 	callPush := func(args ...string) {
@@ -372,6 +384,42 @@ var _ = Describe("Push Command", func() {
 			})
 		})
 
+		It("fails without pushing anything when the manifest is invalid", func() {
+			manifestRepo.ReadManifestReturns.Manifest = singleAppManifest()
+			manifestRepo.ReadManifestReturns.Manifest.Path = "manifest.yml"
+			manifestRepo.ValidateManifestReturns.Errors = []error{
+				manifest.ManifestError{File: "manifest.yml", Line: 4, Column: 3, Message: "instances must be an integer"},
+			}
+
+			callPush()
+
+			Expect(manifestRepo.ValidateManifestArgs.Path).To(Equal("manifest.yml"))
+			Expect(appRepo.CreateAppParams).To(BeEmpty())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Invalid manifest file"},
+				{"manifest.yml:4:3: instances must be an integer"},
+			})
+		})
+
+		It("only warns about keys in the manifest it does not know", func() {
+			manifestRepo.ReadManifestReturns.Manifest = singleAppManifest()
+			manifestRepo.ReadManifestReturns.Manifest.Path = "manifest.yml"
+			manifestRepo.ValidateManifestReturns.Errors = []error{
+				manifest.ManifestError{File: "manifest.yml", Line: 4, Column: 3, Message: "Unknown key 'instance', did you mean 'instances'?", UnknownKey: true},
+			}
+
+			callPush()
+
+			Expect(appRepo.CreateAppParams).NotTo(BeEmpty())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Warning", "manifest.yml:4:3: Unknown key 'instance', did you mean 'instances'?"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+			})
+		})
+
 		It("does not fail when the current working directory does not contain a manifest", func() {
 			manifestRepo.ReadManifestReturns.Manifest = singleAppManifest()
 			manifestRepo.ReadManifestReturns.Error = syscall.ENOENT
@@ -784,230 +832,1212 @@ var _ = Describe("Push Command", func() {
 				Expect(routeRepo.CreatedDomainGuid).To(Equal("domain-guid"))
 			})
 		})
-	})
 
-	Describe("service instances", func() {
-		BeforeEach(func() {
-			serviceRepo.FindInstanceByNameMap = generic.NewMap(map[interface{}]interface{}{
-				"global-service": maker.NewServiceInstance("global-service"),
-				"app1-service":   maker.NewServiceInstance("app1-service"),
-				"app2-service":   maker.NewServiceInstance("app2-service"),
-			})
+		Describe("when the new version fails to start", func() {
+			BeforeEach(func() {
+				domain := models.DomainFields{Name: "example.com", Guid: "domain-guid", Shared: true}
 
-			manifestRepo.ReadManifestReturns.Manifest = manifestWithServicesAndEnv()
-		})
+				existingApp.State = "started"
+				existingApp.Memory = 256
+				existingApp.Routes = []models.RouteSummary{{
+					Guid:   "existing-route-guid",
+					Host:   "existing-app",
+					Domain: domain,
+				}}
 
-		Context("when the service is not bound", func() {
-			BeforeEach(func() {
-				appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+				appRepo.ReadReturns.App = existingApp
+				appRepo.UpdateAppResult = existingApp
+				starter.StartErrors = []error{errors.New("Start unsuccessful")}
 			})
 
-			It("binds service instances to the app", func() {
-				callPush()
-				Expect(len(serviceBinder.AppsToBind)).To(Equal(4))
-				Expect(serviceBinder.AppsToBind[0].Name).To(Equal("app1"))
-				Expect(serviceBinder.AppsToBind[1].Name).To(Equal("app1"))
-				Expect(serviceBinder.InstancesToBindTo[0].Name).To(Equal("app1-service"))
-				Expect(serviceBinder.InstancesToBindTo[1].Name).To(Equal("global-service"))
+			It("restores the previous settings and routes, restarts the app and fails", func() {
+				pushedApp := existingApp
+				pushedApp.Routes = []models.RouteSummary{{
+					Guid:   "new-route-guid",
+					Host:   "new-host",
+					Domain: models.DomainFields{Name: "example.com"},
+				}}
+				appRepo.ReadResponses = []models.Application{existingApp, pushedApp}
 
-				Expect(serviceBinder.AppsToBind[2].Name).To(Equal("app2"))
-				Expect(serviceBinder.AppsToBind[3].Name).To(Equal("app2"))
-				Expect(serviceBinder.InstancesToBindTo[2].Name).To(Equal("app2-service"))
-				Expect(serviceBinder.InstancesToBindTo[3].Name).To(Equal("global-service"))
+				callPush("-m", "1G", "existing-app")
+
+				Expect(appRepo.UpdateAppGuid).To(Equal("existing-app-guid"))
+				Expect(*appRepo.UpdateParams.Memory).To(Equal(uint64(256)))
+				Expect(*appRepo.UpdateParams.Command).To(Equal("unicorn -c config/unicorn.rb -D"))
+				Expect(*appRepo.UpdateParams.State).To(Equal("STOPPED"))
+
+				Expect(routeRepo.UnboundRouteGuids).To(Equal([]string{"new-route-guid"}))
+				Expect(routeRepo.BoundRouteGuids).To(Equal([]string{"existing-route-guid"}))
+				Expect(len(starter.StartedApps)).To(Equal(2))
 
 				testassert.SliceContains(ui.Outputs, testassert.Lines{
-					{"Creating", "app1"},
-					{"OK"},
-					{"Binding service", "app1-service", "app1", "my-org", "my-space", "my-user"},
-					{"OK"},
-					{"Binding service", "global-service", "app1", "my-org", "my-space", "my-user"},
-					{"OK"},
-					{"Creating", "app2"},
-					{"OK"},
-					{"Binding service", "app2-service", "app2", "my-org", "my-space", "my-user"},
-					{"OK"},
-					{"Binding service", "global-service", "app2", "my-org", "my-space", "my-user"},
-					{"OK"},
+					{"existing-app", "failed to start", "rolling back"},
+					{"reverted", "pushed", "restored"},
+					{"memory", "1G", "256M"},
+					{"Removing route", "new-host.example.com"},
+					{"Binding", "existing-app.example.com", "back to", "existing-app"},
+					{"FAILED"},
+					{"Start unsuccessful"},
+					{"existing-app", "rolled back to its previous settings and routes"},
 				})
 			})
-		})
 
-		Context("when the app is already bound to the service", func() {
-			BeforeEach(func() {
-				appRepo.ReadReturns.App = maker.NewApp(maker.Overrides{})
-				serviceBinder.BindApplicationReturns.Error = errors.NewHttpError(500, "90003", "it don't work")
-			})
+			It("leaves the app stopped when it was stopped before", func() {
+				existingApp.State = "stopped"
+				appRepo.ReadReturns.App = existingApp
 
-			It("gracefully continues", func() {
-				callPush()
-				Expect(len(serviceBinder.AppsToBind)).To(Equal(4))
-				testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{{"FAILED"}})
-			})
-		})
+				callPush("existing-app")
 
-		Context("when the service instance can't be found", func() {
-			BeforeEach(func() {
-				routeRepo.FindByHostAndDomainErr = true
-				serviceRepo.FindInstanceByNameErr = true
-				manifestRepo.ReadManifestReturns.Manifest = manifestWithServicesAndEnv()
+				Expect(*appRepo.UpdateParams.State).To(Equal("STOPPED"))
+				Expect(len(starter.StartedApps)).To(Equal(1))
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"FAILED"},
+					{"rolled back"},
+				})
 			})
 
-			It("fails with an error", func() {
-				callPush()
+			It("says so when the app cannot be restarted after rolling back", func() {
+				starter.StartErrors = []error{errors.New("Start unsuccessful"), errors.New("Start app timeout")}
+
+				callPush("existing-app")
+
 				testassert.SliceContains(ui.Outputs, testassert.Lines{
 					{"FAILED"},
-					{"Could not find service", "app1-service", "app1"},
+					{"Start unsuccessful"},
+					{"rolled back, but could not be restarted", "Start app timeout"},
 				})
 			})
 		})
-
 	})
 
-	Describe("checking for bad flags", func() {
-		It("fails when non-positive value is given for memory limit", func() {
-			callPush("-m", "0G", "my-new-app")
+	Describe("blue-green deployments", func() {
+		var existingApp models.Application
+
+		BeforeEach(func() {
+			domain := models.DomainFields{Name: "example.com", Guid: "domain-guid", Shared: true}
+
+			existingApp = models.Application{}
+			existingApp.Name = "existing-app"
+			existingApp.Guid = "existing-app-guid"
+			existingApp.State = "started"
+			existingApp.EnvironmentVars = map[string]string{"crazy": "pants"}
+			existingApp.Routes = []models.RouteSummary{{
+				Guid:   "existing-route-guid",
+				Host:   "existing-app",
+				Domain: domain,
+			}}
+
+			appRepo.ReadAppsByName = map[string]models.Application{"existing-app": existingApp}
+			appRepo.UpdateAppResult = existingApp
+			appSummaryRepo.GetSummarySummary = existingApp
+		})
+
+		It("fails when given an unknown strategy", func() {
+			callPush("--strategy", "canary", "existing-app")
 
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
 				{"FAILED"},
-				{"memory"},
-				{"positive integer"},
+				{"Invalid deployment strategy", "canary"},
 			})
+			Expect(appRepo.CreateAppParams).To(BeNil())
 		})
 
-		It("fails when non-positive value is given for instances", func() {
-			callPush("-i", "0", "my-new-app")
+		It("fails when combined with --no-start", func() {
+			callPush("--strategy", "blue-green", "--no-start", "existing-app")
 
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
 				{"FAILED"},
-				{"instance count"},
-				{"positive integer"},
+				{"blue-green", "--no-start"},
 			})
 		})
 
-		It("fails when non-positive value is given for disk quota", func() {
-			callPush("-k", "-1G", "my-new-app")
+		It("starts the new version alongside the old one and moves its routes", func() {
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(stopper.AppToStop.Guid).To(Equal(""))
+
+			Expect(*appRepo.CreatedAppParams().Name).To(Equal("existing-app-new"))
+			Expect((*appRepo.CreatedAppParams().EnvironmentVars)["crazy"]).To(Equal("pants"))
+			Expect(appBitsRepo.UploadedAppGuid).To(Equal("existing-app-new-guid"))
+			Expect(starter.AppToStart.Guid).To(Equal("existing-app-new-guid"))
+
+			Expect(routeRepo.BoundRouteGuid).To(Equal("existing-route-guid"))
+			Expect(routeRepo.BoundAppGuid).To(Equal("existing-app-new-guid"))
+			Expect(routeRepo.UnboundRouteGuid).To(Equal("existing-route-guid"))
+			Expect(routeRepo.UnboundAppGuid).To(Equal("existing-app-guid"))
+
+			Expect(appRepo.UpdateAppGuids).To(Equal([]string{"existing-app-guid", "existing-app-new-guid"}))
+			Expect(*appRepo.AllUpdateParams[0].Name).To(Equal("existing-app-old"))
+			Expect(*appRepo.AllUpdateParams[1].Name).To(Equal("existing-app"))
+			Expect(appRepo.DeletedAppGuids).To(Equal([]string{"existing-app-guid"}))
 
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Creating app", "existing-app-new"},
+				{"Uploading", "existing-app-new"},
+				{"Moving route", "existing-app.example.com", "existing-app", "existing-app-new"},
+				{"OK"},
+				{"Renaming app", "existing-app", "existing-app-old"},
+				{"OK"},
+				{"Renaming app", "existing-app-new", "existing-app"},
+				{"OK"},
+				{"Deleting old app", "existing-app-old"},
+				{"OK"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
 				{"FAILED"},
-				{"disk quota"},
-				{"positive integer"},
 			})
 		})
 
-		It("fails when a non-numeric start timeout is given", func() {
-			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+		It("keeps the settings and services of the old app that are not changed", func() {
+			summary := existingApp
+			summary.InstanceCount = 3
+			summary.Memory = 512
+			summary.DiskQuota = 1024
+			summary.BuildpackUrl = "ruby-buildpack"
+			summary.Command = "bundle exec rackup"
+			summary.HealthCheckType = "port"
+			summary.HealthCheckTimeout = 120
+			summary.Stack = &models.Stack{Name: "cflinuxfs2", Guid: "stack-guid"}
+			summary.Services = []models.ServiceInstanceFields{{Name: "my-db", Guid: "my-db-guid"}}
+			appSummaryRepo.GetSummarySummary = summary
+
+			serviceInstance := models.ServiceInstance{}
+			serviceInstance.Name = "my-db"
+			serviceRepo.FindInstanceByNameMap = generic.NewMap(map[interface{}]interface{}{"my-db": serviceInstance})
+
+			callPush("--strategy", "blue-green", "-m", "1G", "existing-app")
+
+			Expect(appSummaryRepo.GetSummaryAppGuid).To(Equal("existing-app-guid"))
+
+			params := appRepo.CreatedAppParams()
+			Expect(*params.Name).To(Equal("existing-app-new"))
+			Expect(*params.InstanceCount).To(Equal(3))
+			Expect(*params.Memory).To(Equal(uint64(1024)))
+			Expect(*params.DiskQuota).To(Equal(uint64(1024)))
+			Expect(*params.BuildpackUrl).To(Equal("ruby-buildpack"))
+			Expect(*params.Command).To(Equal("bundle exec rackup"))
+			Expect(*params.HealthCheckType).To(Equal("port"))
+			Expect(*params.StackGuid).To(Equal("stack-guid"))
+			Expect(starter.Timeout).To(Equal(120))
+
+			Expect(serviceBinder.AppsToBind).To(HaveLen(1))
+			Expect(serviceBinder.AppsToBind[0].Guid).To(Equal("existing-app-new-guid"))
+			Expect(serviceBinder.InstancesToBindTo[0].Name).To(Equal("my-db"))
 
-			callPush("-t", "FooeyTimeout", "my-new-app")
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Binding service", "my-db", "existing-app-new"},
+				{"OK"},
+				{"Moving route", "existing-app.example.com"},
+			})
+		})
+
+		It("deletes the new app when a service of the old app cannot be bound to it", func() {
+			summary := existingApp
+			summary.Services = []models.ServiceInstanceFields{{Name: "my-db", Guid: "my-db-guid"}}
+			appSummaryRepo.GetSummarySummary = summary
 
+			serviceInstance := models.ServiceInstance{}
+			serviceInstance.Name = "my-db"
+			serviceRepo.FindInstanceByNameMap = generic.NewMap(map[interface{}]interface{}{"my-db": serviceInstance})
+			serviceBinder.BindApplicationReturns.Error = errors.New("bind failed")
+
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(appRepo.DeletedAppGuid).To(Equal("existing-app-new-guid"))
+			Expect(starter.AppToStart.Guid).To(Equal(""))
+			Expect(routeRepo.BoundRouteGuid).To(Equal(""))
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
 				{"FAILED"},
-				{"invalid", "timeout"},
+				{"Could not bind to service my-db"},
 			})
 		})
-	})
 
-	Describe("displaying information about files being uploaded", func() {
-		It("displays information about the files being uploaded", func() {
-			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
-			appBitsRepo.CallbackPath = "path/to/app"
-			appBitsRepo.CallbackZipSize = 61 * 1024 * 1024
-			appBitsRepo.CallbackFileCount = 11
+		It("fails without creating an app when the old app cannot be read", func() {
+			appSummaryRepo.GetSummaryErrorCode = "some-error"
 
-			callPush("appName")
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(appRepo.CreateAppParams).To(BeNil())
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
-				{"Uploading", "path/to/app"},
-				{"61M", "11 files"},
+				{"FAILED"},
 			})
 		})
 
-		It("omits the size when there are no files being uploaded", func() {
-			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
-			appBitsRepo.CallbackPath = "path/to/app"
-			appBitsRepo.CallbackFileCount = 0
+		It("creates the app normally when it does not exist yet", func() {
+			appRepo.ReadAppsByName = nil
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "my-new-app")
+			routeRepo.FindByHostAndDomainErr = true
 
-			callPush("appName")
-			testassert.SliceContains(ui.WarnOutputs, testassert.Lines{
-				{"None of your application files have changed", "nothing will be uploaded"},
-			})
+			callPush("--strategy", "blue-green", "my-new-app")
+
+			Expect(*appRepo.CreatedAppParams().Name).To(Equal("my-new-app"))
+			Expect(starter.AppToStart.Guid).To(Equal("my-new-app-guid"))
+			Expect(appRepo.DeletedAppGuid).To(Equal(""))
 		})
-	})
 
-	It("fails when the app can't be uploaded", func() {
-		appBitsRepo.UploadAppErr = true
+		It("deletes the new app and leaves the old one alone when it fails to start", func() {
+			starter.StartErr = errors.New("Start unsuccessful")
 
-		callPush("app")
+			callPush("--strategy", "blue-green", "existing-app")
 
-		testassert.SliceContains(ui.Outputs, testassert.Lines{
-			{"Uploading"},
-			{"FAILED"},
+			Expect(appRepo.DeletedAppGuid).To(Equal("existing-app-new-guid"))
+			Expect(routeRepo.BoundRouteGuid).To(Equal(""))
+			Expect(routeRepo.UnboundRouteGuid).To(Equal(""))
+			Expect(appRepo.UpdateAppGuid).To(Equal(""))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"failed", "rolling back"},
+				{"Deleting app", "existing-app-new"},
+				{"FAILED"},
+				{"Start unsuccessful"},
+				{"existing-app", "still running the previous version"},
+			})
 		})
-	})
 
-	Describe("when binding the route fails", func() {
-		BeforeEach(func() {
-			routeRepo.FindByHostAndDomainRoute.Host = "existing-app"
-			routeRepo.FindByHostAndDomainRoute.Domain = models.DomainFields{Name: "foo.cf-app.com"}
+		It("deletes the new app when the upload fails", func() {
+			appBitsRepo.UploadAppErr = true
+
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(appRepo.DeletedAppGuid).To(Equal("existing-app-new-guid"))
+			Expect(starter.AppToStart.Guid).To(Equal(""))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Error uploading application"},
+			})
 		})
 
-		It("suggests using 'random-route' if the default route is taken", func() {
-			routeRepo.BindErr = errors.NewHttpError(400, errors.INVALID_RELATION, "The URL not available")
+		It("deletes the apps an earlier blue-green push left behind before creating the new app", func() {
+			appRepo.ReadAppsByName["existing-app-new"] = models.Application{
+				ApplicationFields: models.ApplicationFields{Name: "existing-app-new", Guid: "leftover-new-guid"},
+			}
+			appRepo.ReadAppsByName["existing-app-old"] = models.Application{
+				ApplicationFields: models.ApplicationFields{Name: "existing-app-old", Guid: "leftover-old-guid"},
+			}
 
-			callPush("existing-app")
+			callPush("--strategy", "blue-green", "existing-app")
 
+			Expect(appRepo.DeletedAppGuids).To(Equal([]string{"leftover-new-guid", "leftover-old-guid", "existing-app-guid"}))
+			Expect(*appRepo.CreatedAppParams().Name).To(Equal("existing-app-new"))
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Deleting app", "existing-app-new", "left over"},
+				{"OK"},
+				{"Deleting app", "existing-app-old", "left over"},
+				{"OK"},
+				{"Creating app", "existing-app-new"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
 				{"FAILED"},
-				{"existing-app.foo.cf-app.com", "already in use"},
-				{"TIP", "random-route"},
 			})
 		})
 
-		It("does not suggest using 'random-route' for other failures", func() {
-			routeRepo.BindErr = errors.NewHttpError(500, "some-code", "exception happened")
+		It("keeps the old app and its routes when the new app cannot be renamed", func() {
+			appRepo.UpdateErrorsByGuid = map[string]error{"existing-app-new-guid": errors.New("name taken")}
 
-			callPush("existing-app")
+			callPush("--strategy", "blue-green", "existing-app")
 
+			Expect(appRepo.UpdateAppGuids).To(Equal([]string{"existing-app-guid", "existing-app-new-guid", "existing-app-guid"}))
+			Expect(*appRepo.AllUpdateParams[2].Name).To(Equal("existing-app"))
+			Expect(appRepo.DeletedAppGuids).To(Equal([]string{"existing-app-new-guid"}))
+			Expect(routeRepo.BoundAppGuid).To(Equal("existing-app-guid"))
 			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Renaming app", "existing-app-old", "back to", "existing-app"},
+				{"Moving route", "existing-app.example.com", "back to", "existing-app"},
+				{"Deleting app", "existing-app-new"},
 				{"FAILED"},
+				{"name taken"},
+				{"existing-app", "still running the previous version"},
 			})
+		})
+
+		It("keeps the new version when the old app cannot be deleted once it is replaced", func() {
+			appRepo.DeleteErrorsByGuid = map[string]error{"existing-app-guid": errors.New("delete exploded")}
 
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(appRepo.DeletedAppGuids).To(Equal([]string{"existing-app-guid"}))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Renaming app", "existing-app-new", "existing-app"},
+				{"OK"},
+				{"Could not delete old app", "existing-app-old", "delete exploded"},
+			})
 			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
-				{"TIP", "random-route"},
+				{"FAILED"},
 			})
 		})
-	})
 
-	It("fails when neither a manifest nor a name is given", func() {
-		manifestRepo.ReadManifestReturns.Error = errors.New("No such manifest")
-		callPush()
-		testassert.SliceContains(ui.Outputs, testassert.Lines{
-			{"FAILED"},
-			{"App name"},
+		It("moves the routes back when a route cannot be bound to the new app", func() {
+			routeRepo.BindErr = errors.New("route binding exploded")
+
+			callPush("--strategy", "blue-green", "existing-app")
+
+			Expect(appRepo.DeletedAppGuid).To(Equal("existing-app-new-guid"))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"route binding exploded"},
+			})
 		})
 	})
-})
 
-func singleAppManifest() *manifest.Manifest {
-	return &manifest.Manifest{
-		Path: "manifest.yml",
-		Data: generic.NewMap(map[interface{}]interface{}{
-			"applications": []interface{}{
-				generic.NewMap(map[interface{}]interface{}{
-					"name":      "manifest-app-name",
-					"memory":    "128MB",
-					"instances": 1,
-					"host":      "manifest-host",
-					"domain":    "manifest-example.com",
-					"stack":     "custom-stack",
-					"timeout":   360,
-					"buildpack": "some-buildpack",
-					"command":   `JAVA_HOME=$PWD/.openjdk JAVA_OPTS="-Xss995K" ./bin/start.sh run`,
-					"path":      filepath.Clean("some/path/from/manifest"),
-					"env": generic.NewMap(map[interface{}]interface{}{
-						"FOO":  "baz",
-						"PATH": "/u/apps/my-app/bin",
-					}),
-				}),
-			},
+	Describe("dry runs", func() {
+		BeforeEach(func() {
+			appBitsRepo.MatchFilesToUpload = []models.AppFileFields{
+				{Path: "app.rb", Size: 100},
+				{Path: "Gemfile", Size: 50},
+			}
+		})
+
+		Context("when the app does not exist", func() {
+			BeforeEach(func() {
+				appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "my-new-app")
+				routeRepo.FindByHostAndDomainErr = true
+			})
+
+			It("prints the plan without making any changes", func() {
+				callPush("--dry-run", "-m", "512M", "my-new-app")
+
+				Expect(appRepo.CreateAppParams).To(BeNil())
+				Expect(appRepo.UpdateAppGuid).To(Equal(""))
+				Expect(routeRepo.CreatedHost).To(Equal(""))
+				Expect(routeRepo.BoundRouteGuid).To(Equal(""))
+				Expect(appBitsRepo.UploadedAppGuid).To(Equal(""))
+				Expect(appBitsRepo.MatchedOptions.NoSave).To(BeTrue())
+				Expect(starter.AppToStart.Guid).To(Equal(""))
+
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"Plan for app", "my-new-app", "create"},
+					{"field", "current", "desired"},
+					{"memory", "512M"},
+					{"route", "action"},
+					{"my-new-app.foo.cf-app.com", "create and bind"},
+					{"Files to upload", "2", "150"},
+				})
+			})
+		})
+
+		Context("when the app exists", func() {
+			BeforeEach(func() {
+				existingApp := maker.NewApp(maker.Overrides{"name": "existing-app", "guid": "existing-app-guid"})
+				existingApp.Memory = 256
+				existingApp.InstanceCount = 1
+
+				domain := models.DomainFields{Name: "example.com", Guid: "domain-guid"}
+				existingApp.Routes = []models.RouteSummary{{Guid: "route-guid", Host: "existing-app", Domain: domain}}
+
+				appRepo.ReadReturns.App = existingApp
+			})
+
+			It("shows the fields that will change and leaves the existing routes alone", func() {
+				callPush("--dry-run", "-m", "1G", "-i", "1", "existing-app")
+
+				Expect(appRepo.UpdateAppGuid).To(Equal(""))
+				Expect(routeRepo.UnboundRouteGuid).To(Equal(""))
+				Expect(appBitsRepo.MatchedOptions.AppGuid).To(Equal("existing-app-guid"))
+
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"Plan for app", "existing-app", "update"},
+					{"memory", "256M", "1G"},
+					{"existing-app.example.com", "unchanged"},
+				})
+				testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+					{"instances"},
+				})
+			})
+
+			It("shows the routes that will be unbound when --no-route is given", func() {
+				callPush("--dry-run", "--no-route", "existing-app")
+
+				Expect(routeRepo.UnboundRouteGuid).To(Equal(""))
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"existing-app.example.com", "unbind"},
+				})
+			})
+		})
+
+		It("shows which services will be bound", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			serviceRepo.FindInstanceByNameMap = generic.NewMap(map[interface{}]interface{}{
+				"global-service": maker.NewServiceInstance("global-service"),
+				"app1-service":   maker.NewServiceInstance("app1-service"),
+				"app2-service":   maker.NewServiceInstance("app2-service"),
+			})
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithServicesAndEnv()
+
+			callPush("--dry-run")
+
+			Expect(serviceBinder.AppsToBind).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Plan for app", "app1", "create"},
+				{"app1-service", "bind"},
+				{"global-service", "bind"},
+				{"Plan for app", "app2", "create"},
+				{"app2-service", "bind"},
+			})
+		})
+
+		It("prints the plan as JSON when asked to", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "my-new-app")
+			routeRepo.FindByHostAndDomainErr = true
+
+			callPush("--dry-run", "--dry-run-format", "json", "my-new-app")
+
+			plan := PushPlan{}
+			err := json.Unmarshal([]byte(strings.Join(ui.Outputs, "\n")), &plan)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(len(plan.Apps)).To(Equal(1))
+			Expect(plan.Apps[0].Name).To(Equal("my-new-app"))
+			Expect(plan.Apps[0].Action).To(Equal("create"))
+			Expect(plan.Apps[0].Routes).To(Equal([]RoutePlan{{URL: "my-new-app.foo.cf-app.com", Action: "create and bind"}}))
+			Expect(plan.Apps[0].FilesToUpload).To(Equal(2))
+			Expect(plan.Apps[0].BytesToUpload).To(Equal(int64(150)))
+		})
+
+		It("fails with an unknown format", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "my-new-app")
+			callPush("--dry-run", "--dry-run-format", "xml", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Invalid dry run format", "xml"},
+			})
+		})
+	})
+
+	Describe("apps that depend on other apps", func() {
+		BeforeEach(func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithDependencies()
+		})
+
+		It("pushes the apps each app depends on first", func() {
+			callPush()
+
+			Expect(len(appRepo.CreateAppParams)).To(Equal(3))
+			Expect(*appRepo.CreateAppParams[0].Name).To(Equal("database-migrator"))
+			Expect(*appRepo.CreateAppParams[1].Name).To(Equal("api"))
+			Expect(*appRepo.CreateAppParams[2].Name).To(Equal("web"))
+		})
+
+		It("fails when an app depends on an app that is not being pushed", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithDependency("web", "missing-app")
+
+			callPush()
+
+			Expect(appRepo.CreateAppParams).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"web", "depends on", "missing-app"},
+			})
+		})
+
+		It("fails when apps depend on each other", func() {
+			manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+				Path: "manifest.yml",
+				Data: generic.NewMap(map[interface{}]interface{}{
+					"applications": []interface{}{
+						map[interface{}]interface{}{"name": "chicken", "depends_on": []interface{}{"egg"}},
+						map[interface{}]interface{}{"name": "egg", "depends_on": []interface{}{"chicken"}},
+					},
+				}),
+			}
+
+			callPush()
+
+			Expect(appRepo.CreateAppParams).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"circular dependencies", "chicken -> egg -> chicken"},
+			})
+		})
+
+		Context("when pushing in parallel", func() {
+			It("prefixes the output of each app with its name", func() {
+				callPush("--parallel", "2")
+
+				Expect(len(appRepo.CreateAppParams)).To(Equal(3))
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"[database-migrator]", "Creating app", "database-migrator"},
+					{"[api]", "Creating app", "api"},
+					{"[web]", "Creating app", "web"},
+					{"Pushed 3 apps"},
+					{"OK"},
+				})
+			})
+
+			It("summarizes the apps that failed to push, skipping the apps that depend on them", func() {
+				appBitsRepo.UploadAppErr = true
+
+				callPush("--parallel", "2")
+
+				Expect(len(appRepo.CreateAppParams)).To(Equal(1))
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"[database-migrator]", "FAILED"},
+					{"[database-migrator]", "Error uploading app"},
+					{"Pushed 0 of 3 apps"},
+					{"app", "error"},
+					{"database-migrator", "Error uploading app"},
+					{"api", "Not pushed because database-migrator failed to push"},
+					{"web", "Not pushed because api failed to push"},
+					{"FAILED"},
+					{"3 of 3 apps failed to push"},
+				})
+			})
+		})
+	})
+
+	Describe("before_push and after_push commands", func() {
+		var appDir string
+
+		BeforeEach(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "push-hooks")
+			Expect(err).NotTo(HaveOccurred())
+
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "my-app")
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithHooks(appDir)
+			hookRunner.Output = map[string][]string{"npm run build": {"> build", "done"}}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(appDir)
+		})
+
+		It("runs the commands in the app's directory before and after pushing it", func() {
+			callPush()
+
+			Expect(hookRunner.Commands).To(Equal([]string{"npm install", "npm run build", "./notify-deploy.sh"}))
+			Expect(hookRunner.Dirs).To(Equal([]string{appDir, appDir, appDir}))
+			Expect(hookRunner.Envs[0]).To(Equal(map[string]string{
+				"CF_ORG":   "my-org",
+				"CF_SPACE": "my-space",
+				"CF_APP":   "my-app",
+			}))
+			Expect(appBitsRepo.UploadedDir).To(Equal(appDir))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Running before_push command", "my-app", "npm install"},
+				{"OK"},
+				{"Running before_push command", "my-app", "npm run build"},
+				{"> build"},
+				{"done"},
+				{"OK"},
+				{"Creating app", "my-app"},
+				{"Uploading", "my-app"},
+				{"Running after_push command", "my-app", "./notify-deploy.sh"},
+				{"OK"},
+			})
+		})
+
+		It("stops the push when a before_push command fails", func() {
+			hookRunner.Errors = map[string]error{"npm run build": errors.New("exit status 1")}
+
+			callPush()
+
+			Expect(hookRunner.Commands).To(Equal([]string{"npm install", "npm run build"}))
+			Expect(appRepo.CreateAppParams).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"before_push command 'npm run build' failed for app my-app", "exit status 1"},
+			})
+		})
+
+		It("fails when an after_push command fails", func() {
+			hookRunner.Errors = map[string]error{"./notify-deploy.sh": errors.New("exit status 2")}
+
+			callPush()
+
+			Expect(starter.AppToStart.Name).To(Equal("my-app"))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"after_push command './notify-deploy.sh' failed", "exit status 2"},
+			})
+		})
+
+		It("runs the commands in the directory the app's path is in when it is a file that does not exist yet", func() {
+			jarPath := filepath.Join(appDir, "target", "app.jar")
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithHooks(jarPath)
+
+			callPush()
+
+			Expect(hookRunner.Dirs[0]).To(Equal(filepath.Join(appDir, "target")))
+		})
+
+		It("does not run the commands with --skip-hooks", func() {
+			callPush("--skip-hooks")
+
+			Expect(hookRunner.Commands).To(BeEmpty())
+			Expect(*appRepo.CreatedAppParams().Name).To(Equal("my-app"))
+		})
+	})
+
+	Describe("manifest variables", func() {
+		BeforeEach(func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			manifestRepo.ReadManifestReturns.Manifest = &manifest.Manifest{
+				Path: "manifest.yml",
+				Data: generic.NewMap(map[interface{}]interface{}{
+					"applications": []interface{}{
+						generic.NewMap(map[interface{}]interface{}{
+							"name":      "((app-name))",
+							"instances": "((instances))",
+						}),
+					},
+				}),
+			}
+		})
+
+		It("substitutes values from a vars file and --var flags, preferring the flags", func() {
+			callPush("--vars-file", "../../../fixtures/manifests/vars.yml", "--var", "instances=5")
+
+			Expect(*appRepo.CreatedAppParams().Name).To(Equal("my-app"))
+			Expect(*appRepo.CreatedAppParams().InstanceCount).To(Equal(5))
+		})
+
+		It("fails when a variable has no value", func() {
+			callPush("--var", "app-name=my-app")
+
+			Expect(appRepo.CreateAppParams).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Error reading manifest file"},
+				{"((instances))", "applications[0].instances", "manifest.yml"},
+			})
+		})
+
+		It("fails when a --var flag is not a name=value pair", func() {
+			callPush("--var", "app-name")
+
+			Expect(appRepo.CreateAppParams).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Invalid variable", "app-name"},
+			})
+		})
+	})
+
+	Describe("manifests with lists of routes", func() {
+		var existingApp models.Application
+
+		BeforeEach(func() {
+			domainRepo.FindByNameInOrgDomainsMap = map[string]models.DomainFields{
+				"example.com": {Name: "example.com", Guid: "example-com-guid"},
+				"example.org": {Name: "example.org", Guid: "example-org-guid"},
+			}
+			routeRepo.FindByHostAndDomainErr = true
+
+			existingApp = maker.NewApp(maker.Overrides{"name": "my-app", "guid": "my-app-guid"})
+			existingApp.Routes = []models.RouteSummary{
+				{Guid: "old-route-guid", Host: "old", Domain: models.DomainFields{Name: "example.com"}},
+			}
+			appRepo.ReadReturns.App = existingApp
+			appRepo.UpdateAppResult = existingApp
+		})
+
+		It("creates and binds every combination of hosts and domains", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"hosts":   []interface{}{"www", "api"},
+				"domains": []interface{}{"example.com", "example.org"},
+			})
+
+			callPush()
+
+			Expect(routeRepo.CreatedHosts).To(Equal([]string{"www", "www", "api", "api"}))
+			Expect(routeRepo.CreatedDomainGuids).To(Equal([]string{"example-com-guid", "example-org-guid", "example-com-guid", "example-org-guid"}))
+			Expect(len(routeRepo.BoundRouteGuids)).To(Equal(4))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Creating route", "www.example.com"},
+				{"Binding", "www.example.com", "my-app"},
+				{"Creating route", "www.example.org"},
+				{"Binding", "www.example.org", "my-app"},
+				{"Creating route", "api.example.com"},
+				{"Binding", "api.example.com", "my-app"},
+				{"Creating route", "api.example.org"},
+				{"Binding", "api.example.org", "my-app"},
+			})
+
+			Expect(routeRepo.UnboundRouteGuids).To(BeNil())
+		})
+
+		It("uses the app's name as the host when only domains are given", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"domains": []interface{}{"example.com", "example.org"},
+			})
+
+			callPush()
+
+			Expect(routeRepo.CreatedHosts).To(Equal([]string{"my-app", "my-app"}))
+			Expect(routeRepo.CreatedDomainGuids).To(Equal([]string{"example-com-guid", "example-org-guid"}))
+		})
+
+		It("splits routes into a host and the longest matching domain", func() {
+			domainRepo.FindByNameInOrgDomainsMap["api.example.org"] = models.DomainFields{Name: "api.example.org", Guid: "api-example-org-guid"}
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"routes": []interface{}{"v1.api.example.com", "api.example.org", "example.com"},
+			})
+
+			callPush()
+
+			Expect(routeRepo.CreatedHosts).To(Equal([]string{"v1.api", "", ""}))
+			Expect(routeRepo.CreatedDomainGuids).To(Equal([]string{"example-com-guid", "api-example-org-guid", "example-com-guid"}))
+		})
+
+		It("fails when a route is not in any of the org's domains", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"routes": []interface{}{"api.example.net"},
+			})
+
+			callPush()
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Could not find a domain", "api.example.net"},
+			})
+		})
+
+		It("unbinds routes that are not listed when asked to", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"routes": []interface{}{"www.example.com"},
+			})
+
+			callPush("--unbind-unlisted-routes")
+
+			Expect(routeRepo.UnboundRouteGuids).To(Equal([]string{"old-route-guid"}))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Binding", "www.example.com", "my-app"},
+				{"Removing route", "old.example.com"},
+			})
+		})
+
+		It("shows the listed routes in a dry run", func() {
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithRoutes(map[interface{}]interface{}{
+				"hosts": []interface{}{"www", "api"},
+			})
+
+			callPush("--dry-run", "--unbind-unlisted-routes")
+
+			Expect(routeRepo.CreatedHosts).To(BeNil())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"www.foo.cf-app.com", "create and bind"},
+				{"api.foo.cf-app.com", "create and bind"},
+				{"old.example.com", "unbind"},
+			})
+		})
+	})
+
+	Describe("service instances", func() {
+		BeforeEach(func() {
+			serviceRepo.FindInstanceByNameMap = generic.NewMap(map[interface{}]interface{}{
+				"global-service": maker.NewServiceInstance("global-service"),
+				"app1-service":   maker.NewServiceInstance("app1-service"),
+				"app2-service":   maker.NewServiceInstance("app2-service"),
+			})
+
+			manifestRepo.ReadManifestReturns.Manifest = manifestWithServicesAndEnv()
+		})
+
+		Context("when the service is not bound", func() {
+			BeforeEach(func() {
+				appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			})
+
+			It("binds service instances to the app", func() {
+				callPush()
+				Expect(len(serviceBinder.AppsToBind)).To(Equal(4))
+				Expect(serviceBinder.AppsToBind[0].Name).To(Equal("app1"))
+				Expect(serviceBinder.AppsToBind[1].Name).To(Equal("app1"))
+				Expect(serviceBinder.InstancesToBindTo[0].Name).To(Equal("app1-service"))
+				Expect(serviceBinder.InstancesToBindTo[1].Name).To(Equal("global-service"))
+
+				Expect(serviceBinder.AppsToBind[2].Name).To(Equal("app2"))
+				Expect(serviceBinder.AppsToBind[3].Name).To(Equal("app2"))
+				Expect(serviceBinder.InstancesToBindTo[2].Name).To(Equal("app2-service"))
+				Expect(serviceBinder.InstancesToBindTo[3].Name).To(Equal("global-service"))
+
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"Creating", "app1"},
+					{"OK"},
+					{"Binding service", "app1-service", "app1", "my-org", "my-space", "my-user"},
+					{"OK"},
+					{"Binding service", "global-service", "app1", "my-org", "my-space", "my-user"},
+					{"OK"},
+					{"Creating", "app2"},
+					{"OK"},
+					{"Binding service", "app2-service", "app2", "my-org", "my-space", "my-user"},
+					{"OK"},
+					{"Binding service", "global-service", "app2", "my-org", "my-space", "my-user"},
+					{"OK"},
+				})
+			})
+		})
+
+		Context("when the app is already bound to the service", func() {
+			BeforeEach(func() {
+				appRepo.ReadReturns.App = maker.NewApp(maker.Overrides{})
+				serviceBinder.BindApplicationReturns.Error = errors.NewHttpError(500, "90003", "it don't work")
+			})
+
+			It("gracefully continues", func() {
+				callPush()
+				Expect(len(serviceBinder.AppsToBind)).To(Equal(4))
+				testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{{"FAILED"}})
+			})
+		})
+
+		Context("when the service instance can't be found", func() {
+			BeforeEach(func() {
+				routeRepo.FindByHostAndDomainErr = true
+				serviceRepo.FindInstanceByNameErr = true
+				manifestRepo.ReadManifestReturns.Manifest = manifestWithServicesAndEnv()
+			})
+
+			It("fails with an error", func() {
+				callPush()
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"FAILED"},
+					{"Could not find service", "app1-service", "app1"},
+				})
+			})
+		})
+
+	})
+
+	Describe("checking for bad flags", func() {
+		It("fails when non-positive value is given for memory limit", func() {
+			callPush("-m", "0G", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"memory"},
+				{"positive integer"},
+			})
+		})
+
+		It("fails when non-positive value is given for instances", func() {
+			callPush("-i", "0", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"instance count"},
+				{"positive integer"},
+			})
+		})
+
+		It("fails when non-positive value is given for disk quota", func() {
+			callPush("-k", "-1G", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"disk quota"},
+				{"positive integer"},
+			})
+		})
+
+		It("sets the health check type from the flag", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+
+			callPush("--health-check-type", "none", "my-new-app")
+
+			Expect(*appRepo.CreatedAppParams().HealthCheckType).To(Equal("none"))
+		})
+
+		It("fails when an unsupported health check type is given", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+
+			callPush("--health-check-type", "http", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Invalid health check type", "http"},
+			})
+		})
+
+		It("passes the staging log file to the starter", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+
+			callPush("--staging-log", "staging.log", "my-new-app")
+
+			Expect(starter.StagingLogPath).To(Equal("staging.log"))
+		})
+
+		It("does not roll back a new app that fails to start", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			starter.StartErr = errors.New("Start unsuccessful")
+
+			callPush("my-new-app")
+
+			Expect(appRepo.UpdateAppGuid).To(Equal(""))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Start unsuccessful"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"rolling back"},
+			})
+		})
+
+		It("fails when a non-numeric start timeout is given", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+
+			callPush("-t", "FooeyTimeout", "my-new-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"invalid", "timeout"},
+			})
+		})
+	})
+
+	Describe("displaying information about files being uploaded", func() {
+		It("displays information about the files being uploaded", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			appBitsRepo.CallbackPath = "path/to/app"
+			appBitsRepo.CallbackZipSize = 61 * 1024 * 1024
+			appBitsRepo.CallbackFileCount = 11
+
+			callPush("appName")
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Uploading", "path/to/app"},
+				{"61M", "11 files"},
+			})
+		})
+
+		It("omits the size when there are no files being uploaded", func() {
+			appRepo.ReadReturns.Error = errors.NewModelNotFoundError("App", "the-app")
+			appBitsRepo.CallbackPath = "path/to/app"
+			appBitsRepo.CallbackFileCount = 0
+
+			callPush("appName")
+			testassert.SliceContains(ui.WarnOutputs, testassert.Lines{
+				{"None of your application files have changed", "nothing will be uploaded"},
+			})
+		})
+	})
+
+	It("fails when the app can't be uploaded", func() {
+		appBitsRepo.UploadAppErr = true
+
+		callPush("app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Uploading"},
+			{"FAILED"},
+			{"TIP", "--resume"},
+		})
+	})
+
+	Describe("upload options", func() {
+		AfterEach(func() {
+			os.Setenv("CF_UPLOAD_RETRIES", "")
+		})
+
+		It("retries uploads the default number of times", func() {
+			callPush("app")
+			Expect(appBitsRepo.UploadOptions.Retries).To(Equal(api.DefaultAppUploadRetries))
+			Expect(appBitsRepo.UploadOptions.Resume).To(BeFalse())
+		})
+
+		It("takes the number of retries from CF_UPLOAD_RETRIES", func() {
+			os.Setenv("CF_UPLOAD_RETRIES", "7")
+			callPush("app")
+			Expect(appBitsRepo.UploadOptions.Retries).To(Equal(7))
+		})
+
+		It("takes the number of retries from --upload-retries over CF_UPLOAD_RETRIES", func() {
+			os.Setenv("CF_UPLOAD_RETRIES", "7")
+			callPush("--upload-retries", "0", "app")
+			Expect(appBitsRepo.UploadOptions.Retries).To(Equal(0))
+		})
+
+		It("shows the progress of the upload", func() {
+			callPush("app")
+
+			appBitsRepo.UploadOptions.Progress(50, 100)
+			Expect(ui.ProgressBars).To(HaveLen(1))
+			Expect(ui.ProgressBars[0].Sent).To(Equal([]int64{50}))
+			Expect(ui.ProgressBars[0].Finished).To(BeTrue())
+			Expect(ui.ProgressBars[0].FinishErr).NotTo(HaveOccurred())
+		})
+
+		It("tells the progress that the upload failed", func() {
+			appBitsRepo.UploadAppErr = true
+			callPush("app")
+
+			Expect(ui.ProgressBars).To(HaveLen(1))
+			Expect(ui.ProgressBars[0].FinishErr).To(HaveOccurred())
+		})
+
+		It("resumes the last failed upload with --resume", func() {
+			callPush("--resume", "app")
+			Expect(appBitsRepo.UploadOptions.Resume).To(BeTrue())
+		})
+
+		It("leaves out the files .gitignore files ignore with --use-gitignore", func() {
+			callPush("--use-gitignore", "app")
+			Expect(appBitsRepo.UploadOptions.Ignore.UseGitignore).To(BeTrue())
+		})
+	})
+
+	Describe("--show-ignored", func() {
+		It("lists the ignored files and the rules that ignore them without pushing", func() {
+			appBitsRepo.IgnoredFilesReturns = []app_files.IgnoredFile{
+				{Path: ".git/", Rule: app_files.IgnoreRule{Pattern: ".git"}},
+				{Path: "logs/", Rule: app_files.IgnoreRule{Source: ".cfignore", Line: 2, Pattern: "logs/"}},
+			}
+
+			callPush("--show-ignored", "--use-gitignore", "-p", "/some/path", "app")
+
+			Expect(appBitsRepo.IgnoredFilesDir).To(Equal("/some/path"))
+			Expect(appBitsRepo.IgnoredFilesOptions.UseGitignore).To(BeTrue())
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Files left out of app", "app", "/some/path"},
+				{".git/", ".git (default)"},
+				{"logs/", ".cfignore:2: logs/"},
+			})
+
+			Expect(appRepo.CreateAppParams).To(BeEmpty())
+			Expect(appBitsRepo.UploadedAppGuid).To(BeEmpty())
+		})
+
+		It("says when no files are ignored", func() {
+			callPush("--show-ignored", "app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Files left out of app", "app"},
+				{"none"},
+			})
+		})
+	})
+
+	Describe("when binding the route fails", func() {
+		BeforeEach(func() {
+			routeRepo.FindByHostAndDomainRoute.Host = "existing-app"
+			routeRepo.FindByHostAndDomainRoute.Domain = models.DomainFields{Name: "foo.cf-app.com"}
+		})
+
+		It("suggests using 'random-route' if the default route is taken", func() {
+			routeRepo.BindErr = errors.NewHttpError(400, errors.INVALID_RELATION, "The URL not available")
+
+			callPush("existing-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"existing-app.foo.cf-app.com", "already in use"},
+				{"TIP", "random-route"},
+			})
+		})
+
+		It("does not suggest using 'random-route' for other failures", func() {
+			routeRepo.BindErr = errors.NewHttpError(500, "some-code", "exception happened")
+
+			callPush("existing-app")
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+			})
+
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"TIP", "random-route"},
+			})
+		})
+	})
+
+	It("fails when neither a manifest nor a name is given", func() {
+		manifestRepo.ReadManifestReturns.Error = errors.New("No such manifest")
+		callPush()
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App name"},
+		})
+	})
+})
+
+func singleAppManifest() *manifest.Manifest {
+	return &manifest.Manifest{
+		Path: "manifest.yml",
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				generic.NewMap(map[interface{}]interface{}{
+					"name":      "manifest-app-name",
+					"memory":    "128MB",
+					"instances": 1,
+					"host":      "manifest-host",
+					"domain":    "manifest-example.com",
+					"stack":     "custom-stack",
+					"timeout":   360,
+					"buildpack": "some-buildpack",
+					"command":   `JAVA_HOME=$PWD/.openjdk JAVA_OPTS="-Xss995K" ./bin/start.sh run`,
+					"path":      filepath.Clean("some/path/from/manifest"),
+					"env": generic.NewMap(map[interface{}]interface{}{
+						"FOO":  "baz",
+						"PATH": "/u/apps/my-app/bin",
+					}),
+				}),
+			},
+		}),
+	}
+}
+
+func manifestWithHooks(path string) *manifest.Manifest {
+	return &manifest.Manifest{
+		Path: "manifest.yml",
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				generic.NewMap(map[interface{}]interface{}{
+					"name":        "my-app",
+					"path":        path,
+					"before_push": []interface{}{"npm install", "npm run build"},
+					"after_push":  []interface{}{"./notify-deploy.sh"},
+				}),
+			},
+		}),
+	}
+}
+
+func manifestWithDependencies() *manifest.Manifest {
+	return &manifest.Manifest{
+		Path: "manifest.yml",
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				generic.NewMap(map[interface{}]interface{}{
+					"name":       "web",
+					"depends_on": []interface{}{"api"},
+				}),
+				generic.NewMap(map[interface{}]interface{}{
+					"name":       "api",
+					"depends_on": []interface{}{"database-migrator"},
+				}),
+				generic.NewMap(map[interface{}]interface{}{
+					"name": "database-migrator",
+				}),
+			},
+		}),
+	}
+}
+
+func manifestWithDependency(name, dependency string) *manifest.Manifest {
+	return &manifest.Manifest{
+		Path: "manifest.yml",
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				generic.NewMap(map[interface{}]interface{}{
+					"name":       name,
+					"depends_on": []interface{}{dependency},
+				}),
+			},
+		}),
+	}
+}
+
+func manifestWithRoutes(routes map[interface{}]interface{}) *manifest.Manifest {
+	app := generic.NewMap(routes)
+	app.Set("name", "my-app")
+
+	return &manifest.Manifest{
+		Path: "manifest.yml",
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{app},
 		}),
 	}
 }
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/flag_helpers"
+	"cf/models"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+)
+
+type Restage struct {
+	ui             terminal.UI
+	config         configuration.Reader
+	appRepo        api.ApplicationRepository
+	stagingWatcher ApplicationStagingWatcher
+	appReq         requirements.ApplicationRequirement
+}
+
+func NewRestage(ui terminal.UI, config configuration.Reader, appRepo api.ApplicationRepository, stagingWatcher ApplicationStagingWatcher) (cmd *Restage) {
+	cmd = new(Restage)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appRepo = appRepo
+	cmd.stagingWatcher = stagingWatcher
+	return
+}
+
+func (command *Restage) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "restage",
+		ShortName:   "rg",
+		Description: "Restage an app",
+		Usage:       "CF_NAME restage APP [--staging-log FILE]",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("staging-log", "Append the staging logs of the app to FILE"),
+		},
+	}
+}
+
+func (cmd *Restage) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "restage")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *Restage) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+	cmd.stagingWatcher.SetStagingLogPath(c.String("staging-log"))
+
+	_, err := cmd.stagingWatcher.ApplicationWatchStaging(app, func(app models.Application) (models.Application, error) {
+		cmd.ui.Say("Restaging app %s in org %s / space %s as %s...",
+			terminal.EntityNameColor(app.Name),
+			terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+			terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+			terminal.EntityNameColor(cmd.config.Username()),
+		)
+		return cmd.appRepo.Restage(app.Guid)
+	})
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+	}
+}
@@ -0,0 +1,97 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/errors"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("restage command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		app                 models.Application
+		appRepo             *testapi.FakeApplicationRepository
+		stagingWatcher      *testcmd.FakeAppStagingWatcher
+		requirementsFactory *testreq.FakeReqFactory
+	)
+
+	BeforeEach(func() {
+		app = models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+
+		ui = &testterm.FakeUI{}
+		appRepo = &testapi.FakeApplicationRepository{}
+		stagingWatcher = &testcmd.FakeAppStagingWatcher{}
+		requirementsFactory = &testreq.FakeReqFactory{LoginSuccess: true, TargetedSpaceSuccess: true, Application: app}
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewRestage(ui, testconfig.NewRepositoryWithDefaults(), appRepo, stagingWatcher)
+		testcmd.RunCommand(cmd, testcmd.NewContext("restage", args), requirementsFactory)
+	}
+
+	It("fails with usage when not given an app", func() {
+		runCommand()
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	It("fails requirements when not logged in", func() {
+		requirementsFactory.LoginSuccess = false
+		runCommand("my-app")
+		Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+	})
+
+	It("fails requirements when a space is not targeted", func() {
+		requirementsFactory.TargetedSpaceSuccess = false
+		runCommand("my-app")
+		Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+	})
+
+	It("restages the app while watching it stage", func() {
+		runCommand("my-app")
+
+		Expect(requirementsFactory.ApplicationName).To(Equal("my-app"))
+		Expect(stagingWatcher.WatchedApp.Guid).To(Equal("my-app-guid"))
+		Expect(appRepo.RestageAppGuid).To(Equal("my-app-guid"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Restaging app", "my-app", "my-org", "my-space", "my-user"},
+		})
+	})
+
+	It("passes the staging log file to the staging watcher", func() {
+		runCommand("--staging-log", "staging.log", "my-app")
+
+		Expect(stagingWatcher.StagingLogPath).To(Equal("staging.log"))
+	})
+
+	It("fails when the app cannot be restaged", func() {
+		appRepo.RestageErr = errors.New("App has not been staged")
+
+		runCommand("my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App has not been staged"},
+		})
+	})
+
+	It("fails when the app does not start after staging", func() {
+		stagingWatcher.WatchErr = errors.New("Start unsuccessful")
+
+		runCommand("my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Start unsuccessful"},
+		})
+	})
+})
@@ -1,30 +1,45 @@
 package application
 
 import (
+	"cf"
+	"cf/api"
 	"cf/command_metadata"
+	"cf/configuration"
+	"cf/errors"
 	"cf/models"
 	"cf/requirements"
 	"cf/terminal"
-	"errors"
+	"fmt"
 	"github.com/codegangsta/cli"
+	"strings"
+	"time"
 )
 
 type Restart struct {
-	ui      terminal.UI
-	starter ApplicationStarter
-	stopper ApplicationStopper
-	appReq  requirements.ApplicationRequirement
+	ui               terminal.UI
+	config           configuration.Reader
+	starter          ApplicationStarter
+	stopper          ApplicationStopper
+	appInstancesRepo api.AppInstancesRepository
+	appReq           requirements.ApplicationRequirement
+
+	StartupTimeout time.Duration
+	PingerThrottle time.Duration
 }
 
 type ApplicationRestarter interface {
 	ApplicationRestart(app models.Application)
 }
 
-func NewRestart(ui terminal.UI, starter ApplicationStarter, stopper ApplicationStopper) (cmd *Restart) {
+func NewRestart(ui terminal.UI, config configuration.Reader, starter ApplicationStarter, stopper ApplicationStopper, appInstancesRepo api.AppInstancesRepository) (cmd *Restart) {
 	cmd = new(Restart)
 	cmd.ui = ui
+	cmd.config = config
 	cmd.starter = starter
 	cmd.stopper = stopper
+	cmd.appInstancesRepo = appInstancesRepo
+	cmd.StartupTimeout = DefaultStartupTimeout
+	cmd.PingerThrottle = DefaultPingerThrottle
 	return
 }
 
@@ -33,7 +48,10 @@ func (command *Restart) Metadata() command_metadata.CommandMetadata {
 		Name:        "restart",
 		ShortName:   "rs",
 		Description: "Restart an app",
-		Usage:       "CF_NAME restart APP",
+		Usage:       "CF_NAME restart APP [--rolling]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{Name: "rolling", Usage: "Restart one instance at a time, waiting for each to be running again before the next"},
+		},
 	}
 }
 
@@ -56,6 +74,10 @@ func (cmd *Restart) GetRequirements(requirementsFactory requirements.Factory, c
 
 func (cmd *Restart) Run(c *cli.Context) {
 	app := cmd.appReq.GetApplication()
+	if c.Bool("rolling") {
+		cmd.rollingRestart(app)
+		return
+	}
 	cmd.ApplicationRestart(app)
 }
 
@@ -74,3 +96,88 @@ func (cmd *Restart) ApplicationRestart(app models.Application) {
 		return
 	}
 }
+
+// rollingRestart replaces the instances of app one at a time, so that the
+// others keep serving requests.
+func (cmd *Restart) rollingRestart(app models.Application) {
+	if strings.ToLower(app.State) != "started" || app.InstanceCount == 0 {
+		cmd.ui.Failed("App %s is not started, so it cannot be restarted one instance at a time\n\nTIP: use '%s' to start it",
+			app.Name, terminal.CommandColor(fmt.Sprintf("%s start %s", cf.Name(), app.Name)))
+		return
+	}
+
+	cmd.ui.Say("Restarting app %s one instance at a time in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	for index := 0; index < app.InstanceCount; index++ {
+		cmd.ui.Say("\nRestarting instance #%d (%d of %d)...", index, index+1, app.InstanceCount)
+
+		previous, err := cmd.instanceAt(app, index)
+		if err == nil {
+			err = cmd.appInstancesRepo.DeleteInstance(app.Guid, index)
+		}
+		if err == nil {
+			err = cmd.waitForReplacement(app, index, previous)
+		}
+
+		if err != nil {
+			cmd.ui.Failed("%s\n\n%d of %d instances of app %s were restarted", err.Error(), index, app.InstanceCount, app.Name)
+			return
+		}
+
+		cmd.ui.Ok()
+	}
+
+	cmd.ui.Say("\nAll %d instances of app %s were restarted", app.InstanceCount, terminal.EntityNameColor(app.Name))
+}
+
+// instanceAt returns the instance at index before it is deleted, which its
+// replacement is told apart from.
+func (cmd *Restart) instanceAt(app models.Application, index int) (instance models.AppInstanceFields, err error) {
+	instances, err := cmd.appInstancesRepo.GetInstances(app.Guid)
+	if err != nil {
+		err = errors.NewWithError(fmt.Sprintf("Error reading instance #%d before restarting it", index), err)
+		return
+	}
+	if index >= len(instances) {
+		err = errors.NewWithFmt("Instance #%d of app %s was not found", index, app.Name)
+		return
+	}
+
+	instance = instances[index]
+	return
+}
+
+// waitForReplacement waits for the instance at index to be running again. It
+// has been replaced once it has started since previous, or been seen not to
+// be running.
+func (cmd *Restart) waitForReplacement(app models.Application, index int, previous models.AppInstanceFields) error {
+	startTime := time.Now()
+	replaced := false
+
+	for {
+		cmd.ui.Wait(cmd.PingerThrottle)
+
+		instances, apiErr := cmd.appInstancesRepo.GetInstances(app.Guid)
+		if apiErr == nil && index < len(instances) {
+			instance := instances[index]
+			replaced = replaced || instance.State != models.InstanceRunning || !instance.Since.Equal(previous.Since)
+
+			switch {
+			case replaced && instance.State == models.InstanceRunning:
+				return nil
+			case replaced && instance.State == models.InstanceFlapping:
+				return errors.NewWithFmt("Instance #%d is crashing after being restarted\n\nTIP: use '%s' for more information",
+					index, terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name)))
+			}
+		}
+
+		if time.Since(startTime) > cmd.StartupTimeout {
+			return errors.NewWithFmt("Timed out waiting for instance #%d to be running again", index)
+		}
+	}
+}
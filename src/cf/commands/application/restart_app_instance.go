@@ -0,0 +1,82 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+	"strconv"
+)
+
+type RestartAppInstance struct {
+	ui               terminal.UI
+	config           configuration.Reader
+	appInstancesRepo api.AppInstancesRepository
+	appReq           requirements.ApplicationRequirement
+}
+
+func NewRestartAppInstance(ui terminal.UI, config configuration.Reader, appInstancesRepo api.AppInstancesRepository) (cmd *RestartAppInstance) {
+	cmd = new(RestartAppInstance)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appInstancesRepo = appInstancesRepo
+	return
+}
+
+func (cmd *RestartAppInstance) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "restart-app-instance",
+		Description: "Terminate the running application instance at the given index, so that it is replaced with a new one",
+		Usage:       "CF_NAME restart-app-instance APP INDEX",
+	}
+}
+
+func (cmd *RestartAppInstance) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 2 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "restart-app-instance")
+		return
+	}
+
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		requirementsFactory.NewTargetedSpaceRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *RestartAppInstance) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+
+	index, err := strconv.Atoi(c.Args()[1])
+	if err != nil || index < 0 {
+		cmd.ui.Failed("Invalid instance index: %s\nThe index must be a number from 0", c.Args()[1])
+		return
+	}
+
+	if index >= app.InstanceCount {
+		cmd.ui.Failed("Invalid instance index: %d\nApp %s has %d instances, numbered from 0", index, app.Name, app.InstanceCount)
+		return
+	}
+
+	cmd.ui.Say("Restarting instance %s of app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(strconv.Itoa(index)),
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	err = cmd.appInstancesRepo.DeleteInstance(app.Guid, index)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+}
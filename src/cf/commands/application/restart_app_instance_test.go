@@ -0,0 +1,103 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/errors"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("restart-app-instance command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		appInstancesRepo    *testapi.FakeAppInstancesRepo
+		requirementsFactory *testreq.FakeReqFactory
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		app.InstanceCount = 3
+
+		ui = new(testterm.FakeUI)
+		appInstancesRepo = &testapi.FakeAppInstancesRepo{}
+		requirementsFactory = &testreq.FakeReqFactory{Application: app, LoginSuccess: true, TargetedSpaceSuccess: true}
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewRestartAppInstance(ui, testconfig.NewRepositoryWithDefaults(), appInstancesRepo)
+		testcmd.RunCommand(cmd, testcmd.NewContext("restart-app-instance", args), requirementsFactory)
+	}
+
+	Describe("requirements", func() {
+		It("fails with usage without an app and an index", func() {
+			runCommand("my-app")
+			Expect(ui.FailedWithUsage).To(BeTrue())
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when not logged in", func() {
+			requirementsFactory.LoginSuccess = false
+			runCommand("my-app", "0")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+
+		It("fails when a space is not targeted", func() {
+			requirementsFactory.TargetedSpaceSuccess = false
+			runCommand("my-app", "0")
+			Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+		})
+	})
+
+	It("deletes the instance so that it is replaced", func() {
+		runCommand("my-app", "2")
+
+		Expect(requirementsFactory.ApplicationName).To(Equal("my-app"))
+		Expect(appInstancesRepo.DeleteInstanceAppGuid).To(Equal("my-app-guid"))
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(Equal([]int{2}))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Restarting instance 2 of app my-app in org my-org / space my-space as my-user"},
+			{"OK"},
+		})
+	})
+
+	It("fails when the index is not a number", func() {
+		runCommand("my-app", "first")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid instance index: first"},
+		})
+	})
+
+	It("fails when the app has no instance at the index", func() {
+		runCommand("my-app", "3")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid instance index: 3"},
+			{"App my-app has 3 instances"},
+		})
+	})
+
+	It("fails when the instance cannot be deleted", func() {
+		appInstancesRepo.DeleteInstanceErr = errors.New("instance not found")
+
+		runCommand("my-app", "1")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"instance not found"},
+		})
+	})
+})
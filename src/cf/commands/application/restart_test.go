@@ -30,16 +30,20 @@ import (
 	"cf/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
 	testreq "testhelpers/requirements"
 	testterm "testhelpers/terminal"
+	"time"
 )
 
 func callRestart(args []string, requirementsFactory *testreq.FakeReqFactory, starter ApplicationStarter, stopper ApplicationStopper) (ui *testterm.FakeUI) {
 	ui = new(testterm.FakeUI)
 	ctxt := testcmd.NewContext("restart", args)
 
-	cmd := NewRestart(ui, starter, stopper)
+	cmd := NewRestart(ui, testconfig.NewRepositoryWithDefaults(), starter, stopper, &testapi.FakeAppInstancesRepo{})
 	testcmd.RunCommand(cmd, ctxt, requirementsFactory)
 	return
 }
@@ -89,3 +93,138 @@ var _ = Describe("Testing with ginkgo", func() {
 		Expect(starter.AppToStart).To(Equal(app))
 	})
 })
+
+var _ = Describe("restart --rolling", func() {
+	var (
+		ui                  *testterm.FakeUI
+		starter             *testcmd.FakeAppStarter
+		stopper             *testcmd.FakeAppStopper
+		appInstancesRepo    *testapi.FakeAppInstancesRepo
+		requirementsFactory *testreq.FakeReqFactory
+		since               time.Time
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+		app.State = "started"
+		app.InstanceCount = 2
+
+		ui = new(testterm.FakeUI)
+		starter = &testcmd.FakeAppStarter{}
+		stopper = &testcmd.FakeAppStopper{}
+		appInstancesRepo = &testapi.FakeAppInstancesRepo{}
+		requirementsFactory = &testreq.FakeReqFactory{Application: app, LoginSuccess: true, TargetedSpaceSuccess: true}
+		since = time.Unix(1379522342, 0)
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewRestart(ui, testconfig.NewRepositoryWithDefaults(), starter, stopper, appInstancesRepo)
+		cmd.PingerThrottle = 0
+		cmd.StartupTimeout = 10 * time.Millisecond
+		testcmd.RunCommand(cmd, testcmd.NewContext("restart", args), requirementsFactory)
+	}
+
+	instance := func(state models.InstanceState, since time.Time) models.AppInstanceFields {
+		return models.AppInstanceFields{State: state, Since: since}
+	}
+
+	It("replaces each instance in turn, waiting for it to be running again", func() {
+		later := since.Add(time.Minute)
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{instance(models.InstanceRunning, since), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceRunning, since), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceStarting, later), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceRunning, later), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceRunning, later), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceRunning, later), instance(models.InstanceDown, since)},
+			{instance(models.InstanceRunning, later), instance(models.InstanceRunning, since)},
+		}
+
+		runCommand("--rolling", "my-app")
+
+		Expect(appInstancesRepo.DeleteInstanceAppGuid).To(Equal("my-app-guid"))
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(Equal([]int{0, 1}))
+		Expect(appInstancesRepo.GetInstancesResponses).To(BeEmpty())
+		Expect(stopper.AppToStop.Guid).To(Equal(""))
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Restarting app", "my-app", "one instance at a time", "my-org", "my-space", "my-user"},
+			{"Restarting instance #0 (1 of 2)"},
+			{"OK"},
+			{"Restarting instance #1 (2 of 2)"},
+			{"OK"},
+			{"All 2 instances of app my-app were restarted"},
+		})
+	})
+
+	It("stops when a replaced instance is crashing", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{instance(models.InstanceRunning, since), instance(models.InstanceRunning, since)},
+			{instance(models.InstanceFlapping, since.Add(time.Minute)), instance(models.InstanceRunning, since)},
+		}
+
+		runCommand("--rolling", "my-app")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(Equal([]int{0}))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Instance #0 is crashing after being restarted"},
+			{"0 of 2 instances of app my-app were restarted"},
+		})
+	})
+
+	It("times out when a replaced instance does not come back", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{instance(models.InstanceRunning, since), instance(models.InstanceRunning, since)},
+		}
+
+		runCommand("--rolling", "my-app")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Timed out waiting for instance #0 to be running again"},
+		})
+	})
+
+	It("fails without deleting an instance when its stats cannot be read", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{
+			{instance(models.InstanceRunning, since), instance(models.InstanceRunning, since)},
+		}
+		appInstancesRepo.GetInstancesErrorCodes = []string{"500"}
+
+		runCommand("--rolling", "my-app")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Error reading instance #0 before restarting it"},
+			{"0 of 2 instances of app my-app were restarted"},
+		})
+	})
+
+	It("fails without deleting an instance that is not in its stats", func() {
+		appInstancesRepo.GetInstancesResponses = [][]models.AppInstanceFields{{}}
+
+		runCommand("--rolling", "my-app")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Instance #0 of app my-app was not found"},
+		})
+	})
+
+	It("fails when the app is not started", func() {
+		requirementsFactory.Application.State = "stopped"
+
+		runCommand("--rolling", "my-app")
+
+		Expect(appInstancesRepo.DeleteInstanceIndexes).To(BeEmpty())
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"App my-app is not started"},
+		})
+	})
+})
@@ -5,11 +5,15 @@ import (
 	"cf/api"
 	"cf/command_metadata"
 	"cf/configuration"
+	"cf/flag_helpers"
+	"cf/manifest"
 	"cf/models"
 	"cf/requirements"
 	"cf/terminal"
 	"errors"
 	"github.com/codegangsta/cli"
+	"sort"
+	"strings"
 )
 
 type SetEnv struct {
@@ -32,12 +36,19 @@ func (command *SetEnv) Metadata() command_metadata.CommandMetadata {
 		Name:        "set-env",
 		ShortName:   "se",
 		Description: "Set an env variable for an app",
-		Usage:       "CF_NAME set-env APP NAME VALUE",
+		Usage: "CF_NAME set-env APP NAME VALUE\n" +
+			"   CF_NAME set-env APP --from-file FILE\n\n" +
+			"   FILE is in the .env format: a NAME=VALUE per line, with # comments,\n" +
+			"   and values in single or double quotes that may span several lines.",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("from-file", "Set every env variable in FILE"),
+		},
 	}
 }
 
 func (cmd *SetEnv) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
-	if len(c.Args()) < 3 {
+	fromFile := c.String("from-file") != ""
+	if (fromFile && len(c.Args()) != 1) || (!fromFile && len(c.Args()) < 3) {
 		err = errors.New("Incorrect Usage")
 		cmd.ui.FailWithUsage(c, "set-env")
 		return
@@ -53,6 +64,11 @@ func (cmd *SetEnv) GetRequirements(requirementsFactory requirements.Factory, c *
 }
 
 func (cmd *SetEnv) Run(c *cli.Context) {
+	if c.String("from-file") != "" {
+		cmd.setEnvFromFile(c.String("from-file"))
+		return
+	}
+
 	varName := c.Args()[1]
 	varValue := c.Args()[2]
 	app := cmd.appReq.GetApplication()
@@ -82,3 +98,43 @@ func (cmd *SetEnv) Run(c *cli.Context) {
 	cmd.ui.Ok()
 	cmd.ui.Say("TIP: Use '%s' to ensure your env variable changes take effect", terminal.CommandColor(cf.Name()+" push"))
 }
+
+func (cmd *SetEnv) setEnvFromFile(path string) {
+	app := cmd.appReq.GetApplication()
+
+	cmd.ui.Say("Setting env variables from %s for app %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(path),
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	fileVars, err := manifest.ReadEnvFile(path)
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+		return
+	}
+
+	envParams := map[string]string{}
+	for name, value := range app.EnvironmentVars {
+		envParams[name] = value
+	}
+
+	names := []string{}
+	for name, value := range fileVars {
+		envParams[name] = value
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, apiErr := cmd.appRepo.Update(app.Guid, models.AppParams{EnvironmentVars: &envParams})
+	if apiErr != nil {
+		cmd.ui.Failed(apiErr.Error())
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("Set %s", terminal.EntityNameColor(strings.Join(names, ", ")))
+	cmd.ui.Say("TIP: Use '%s' to ensure your env variable changes take effect", terminal.CommandColor(cf.Name()+" push"))
+}
@@ -31,6 +31,8 @@ import (
 	"cf/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
 	testcmd "testhelpers/commands"
@@ -172,6 +174,72 @@ var _ = Describe("set-env command", func() {
 		args = []string{}
 		ui = callSetEnv(args, requirementsFactory, appRepo)
 		Expect(ui.FailedWithUsage).To(BeTrue())
+
+		args = []string{"--from-file", ".env", "my-app"}
+		ui = callSetEnv(args, requirementsFactory, appRepo)
+		Expect(ui.FailedWithUsage).To(BeFalse())
+
+		args = []string{"--from-file", ".env", "my-app", "DATABASE_URL"}
+		ui = callSetEnv(args, requirementsFactory, appRepo)
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	Describe("--from-file", func() {
+		var (
+			app                 models.Application
+			requirementsFactory *testreq.FakeReqFactory
+			appRepo             *testapi.FakeApplicationRepository
+			envFile             string
+		)
+
+		BeforeEach(func() {
+			app = models.Application{}
+			app.Name = "my-app"
+			app.Guid = "my-app-guid"
+			app.EnvironmentVars = map[string]string{"foo": "bar", "DATABASE_URL": "old"}
+			requirementsFactory = &testreq.FakeReqFactory{Application: app, LoginSuccess: true, TargetedSpaceSuccess: true}
+			appRepo = &testapi.FakeApplicationRepository{}
+
+			file, err := ioutil.TempFile("", "set-env")
+			Expect(err).NotTo(HaveOccurred())
+			file.WriteString("# my settings\nDATABASE_URL=mysql://example.com/my-db\nGREETING=\"hello\nworld\"\n")
+			file.Close()
+			envFile = file.Name()
+		})
+
+		AfterEach(func() {
+			os.Remove(envFile)
+		})
+
+		It("merges the variables in the file into the app's env in one update", func() {
+			ui := callSetEnv([]string{"--from-file", envFile, "my-app"}, requirementsFactory, appRepo)
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Setting env variables from", envFile, "my-app", "my-org", "my-space", "my-user"},
+				{"OK"},
+				{"Set", "DATABASE_URL, GREETING"},
+				{"TIP"},
+			})
+
+			Expect(appRepo.UpdateAppGuid).To(Equal("my-app-guid"))
+			Expect(*appRepo.UpdateParams.EnvironmentVars).To(Equal(map[string]string{
+				"foo":          "bar",
+				"DATABASE_URL": "mysql://example.com/my-db",
+				"GREETING":     "hello\nworld",
+			}))
+		})
+
+		It("fails without updating the app when the file cannot be parsed", func() {
+			ioutil.WriteFile(envFile, []byte("A=1\nB='unterminated\n"), os.ModePerm)
+
+			ui := callSetEnv([]string{"--from-file", envFile, "my-app"}, requirementsFactory, appRepo)
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{envFile + ":2: The value of B has no closing '"},
+			})
+			Expect(appRepo.UpdateAppGuid).To(Equal(""))
+		})
 	})
 })
 
@@ -0,0 +1,81 @@
+package application
+
+import (
+	"cf/api"
+	"cf/command_metadata"
+	"cf/configuration"
+	"cf/models"
+	"cf/requirements"
+	"cf/terminal"
+	"errors"
+	"github.com/codegangsta/cli"
+	"strings"
+)
+
+type SetHealthCheck struct {
+	ui      terminal.UI
+	config  configuration.Reader
+	appRepo api.ApplicationRepository
+	appReq  requirements.ApplicationRequirement
+}
+
+func NewSetHealthCheck(ui terminal.UI, config configuration.Reader, appRepo api.ApplicationRepository) (cmd *SetHealthCheck) {
+	cmd = new(SetHealthCheck)
+	cmd.ui = ui
+	cmd.config = config
+	cmd.appRepo = appRepo
+	return
+}
+
+func (command *SetHealthCheck) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "set-health-check",
+		Description: "Set how to tell that an app has started",
+		Usage:       "CF_NAME set-health-check APP TYPE\n\n   TYPE is 'port' to wait for the app to listen on its port, or 'none'",
+	}
+}
+
+func (cmd *SetHealthCheck) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	if len(c.Args()) != 2 {
+		err = errors.New("Incorrect Usage")
+		cmd.ui.FailWithUsage(c, "set-health-check")
+		return
+	}
+	cmd.appReq = requirementsFactory.NewApplicationRequirement(c.Args()[0])
+	reqs = []requirements.Requirement{
+		requirementsFactory.NewLoginRequirement(),
+		cmd.appReq,
+	}
+	return
+}
+
+func (cmd *SetHealthCheck) Run(c *cli.Context) {
+	app := cmd.appReq.GetApplication()
+	healthCheckType := c.Args()[1]
+
+	if !models.IsValidHealthCheckType(healthCheckType) {
+		cmd.ui.Failed("Invalid health check type: %s\nSupported types: %s", healthCheckType, strings.Join(models.HealthCheckTypes, ", "))
+		return
+	}
+
+	cmd.ui.Say("Setting health check type of app %s to %s in org %s / space %s as %s...",
+		terminal.EntityNameColor(app.Name),
+		terminal.EntityNameColor(healthCheckType),
+		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+		terminal.EntityNameColor(cmd.config.Username()),
+	)
+
+	params := models.AppParams{HealthCheckType: &healthCheckType}
+
+	_, apiErr := cmd.appRepo.Update(app.Guid, params)
+	if apiErr != nil {
+		cmd.ui.Failed(apiErr.Error())
+		return
+	}
+	cmd.ui.Ok()
+
+	if app.State == "started" {
+		cmd.ui.Say("\nTIP: The new health check is used the next time the app is started")
+	}
+}
@@ -0,0 +1,71 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testapi "testhelpers/api"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testconfig "testhelpers/configuration"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("set-health-check command", func() {
+	var (
+		ui                  *testterm.FakeUI
+		appRepo             *testapi.FakeApplicationRepository
+		requirementsFactory *testreq.FakeReqFactory
+	)
+
+	BeforeEach(func() {
+		app := models.Application{}
+		app.Name = "my-app"
+		app.Guid = "my-app-guid"
+
+		ui = &testterm.FakeUI{}
+		appRepo = &testapi.FakeApplicationRepository{}
+		requirementsFactory = &testreq.FakeReqFactory{LoginSuccess: true, Application: app}
+	})
+
+	runCommand := func(args ...string) {
+		cmd := NewSetHealthCheck(ui, testconfig.NewRepositoryWithDefaults(), appRepo)
+		testcmd.RunCommand(cmd, testcmd.NewContext("set-health-check", args), requirementsFactory)
+	}
+
+	It("fails with usage when not given an app and a type", func() {
+		runCommand("my-app")
+		Expect(ui.FailedWithUsage).To(BeTrue())
+	})
+
+	It("fails requirements when not logged in", func() {
+		requirementsFactory.LoginSuccess = false
+		runCommand("my-app", "none")
+		Expect(testcmd.CommandDidPassRequirements).To(BeFalse())
+	})
+
+	It("sets the health check type of the app", func() {
+		runCommand("my-app", "none")
+
+		Expect(requirementsFactory.ApplicationName).To(Equal("my-app"))
+		Expect(appRepo.UpdateAppGuid).To(Equal("my-app-guid"))
+		Expect(*appRepo.UpdateParams.HealthCheckType).To(Equal("none"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Setting health check type", "my-app", "none", "my-org", "my-space", "my-user"},
+			{"OK"},
+		})
+	})
+
+	It("fails without updating the app when the type is not supported", func() {
+		runCommand("my-app", "http")
+
+		Expect(appRepo.UpdateAppGuid).To(Equal(""))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Invalid health check type", "http"},
+			{"Supported types", "port, none"},
+		})
+	})
+})
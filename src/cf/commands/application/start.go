@@ -6,6 +6,7 @@ import (
 	"cf/command_metadata"
 	"cf/configuration"
 	"cf/errors"
+	"cf/flag_helpers"
 	"cf/models"
 	"cf/requirements"
 	"cf/terminal"
@@ -16,6 +17,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -27,6 +29,10 @@ const (
 
 const LogMessageTypeStaging = "STG"
 
+// StagingSummaryErrorLines is how many of the last error lines of the
+// staging log are shown when staging fails.
+const StagingSummaryErrorLines = 10
+
 type Start struct {
 	ui               terminal.UI
 	config           configuration.Reader
@@ -39,13 +45,23 @@ type Start struct {
 	StartupTimeout time.Duration
 	StagingTimeout time.Duration
 	PingerThrottle time.Duration
+	StagingLogPath string
 }
 
 type ApplicationStarter interface {
 	SetStartTimeoutInSeconds(timeout int)
+	SetStagingLogPath(path string)
 	ApplicationStart(app models.Application) (updatedApp models.Application, err error)
 }
 
+// ApplicationStagingWatcher shows the staging logs of an app while start
+// asks the server to stage it, then waits for an instance of it to be
+// running.
+type ApplicationStagingWatcher interface {
+	SetStagingLogPath(path string)
+	ApplicationWatchStaging(app models.Application, start func(app models.Application) (models.Application, error)) (updatedApp models.Application, err error)
+}
+
 func NewStart(ui terminal.UI, config configuration.Reader, appDisplayer ApplicationDisplayer, appRepo api.ApplicationRepository, appInstancesRepo api.AppInstancesRepository, logRepo api.LogsRepository) (cmd *Start) {
 	cmd = new(Start)
 	cmd.ui = ui
@@ -80,12 +96,25 @@ func NewStart(ui terminal.UI, config configuration.Reader, appDisplayer Applicat
 	return
 }
 
+// WithUI returns a copy of the command that writes its output to ui. The
+// copy tails staging logs with a connection of its own, so that copies can
+// start apps at the same time.
+func (cmd *Start) WithUI(ui terminal.UI) ApplicationStarter {
+	starter := *cmd
+	starter.ui = ui
+	starter.logRepo = cmd.logRepo.Clone()
+	return &starter
+}
+
 func (command *Start) Metadata() command_metadata.CommandMetadata {
 	return command_metadata.CommandMetadata{
 		Name:        "start",
 		ShortName:   "st",
 		Description: "Start an app",
-		Usage:       "CF_NAME start APP",
+		Usage:       "CF_NAME start APP [--staging-log FILE]",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("staging-log", "Append the staging logs of the app to FILE"),
+		},
 	}
 }
 
@@ -103,7 +132,11 @@ func (cmd *Start) GetRequirements(requirementsFactory requirements.Factory, c *c
 }
 
 func (cmd *Start) Run(c *cli.Context) {
-	cmd.ApplicationStart(cmd.appReq.GetApplication())
+	cmd.SetStagingLogPath(c.String("staging-log"))
+	_, err := cmd.ApplicationStart(cmd.appReq.GetApplication())
+	if err != nil {
+		cmd.ui.Failed(err.Error())
+	}
 }
 
 func (cmd *Start) ApplicationStart(app models.Application) (updatedApp models.Application, err error) {
@@ -112,35 +145,59 @@ func (cmd *Start) ApplicationStart(app models.Application) (updatedApp models.Ap
 		return
 	}
 
+	return cmd.ApplicationWatchStaging(app, func(app models.Application) (models.Application, error) {
+		cmd.ui.Say("Starting app %s in org %s / space %s as %s...",
+			terminal.EntityNameColor(app.Name),
+			terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
+			terminal.EntityNameColor(cmd.config.SpaceFields().Name),
+			terminal.EntityNameColor(cmd.config.Username()),
+		)
+
+		state := "STARTED"
+		return cmd.appRepo.Update(app.Guid, models.AppParams{State: &state})
+	})
+}
+
+func (cmd *Start) ApplicationWatchStaging(app models.Application, start func(app models.Application) (models.Application, error)) (updatedApp models.Application, err error) {
+	stagingLog, err := newStagingLog(app, cmd.StagingLogPath)
+	if err != nil {
+		return
+	}
+	defer stagingLog.Close()
+
 	stopLoggingChan := make(chan bool, 1)
 	loggingStartedChan := make(chan bool)
 
-	go cmd.tailStagingLogs(app, loggingStartedChan, stopLoggingChan)
+	go cmd.tailStagingLogs(app, stagingLog, loggingStartedChan, stopLoggingChan)
 
 	<-loggingStartedChan // block until we have established connection to Loggregator
 
-	cmd.ui.Say("Starting app %s in org %s / space %s as %s...",
-		terminal.EntityNameColor(app.Name),
-		terminal.EntityNameColor(cmd.config.OrganizationFields().Name),
-		terminal.EntityNameColor(cmd.config.SpaceFields().Name),
-		terminal.EntityNameColor(cmd.config.Username()),
-	)
-
-	state := "STARTED"
-	updatedApp, apiErr := cmd.appRepo.Update(app.Guid, models.AppParams{State: &state})
-	if apiErr != nil {
-		cmd.ui.Failed(apiErr.Error())
+	updatedApp, err = start(app)
+	if err != nil {
+		stopLoggingChan <- true
 		return
 	}
 
 	cmd.ui.Ok()
 
-	cmd.waitForInstancesToStage(updatedApp)
+	err = cmd.waitForInstancesToStage(updatedApp, stagingLog)
 	stopLoggingChan <- true
+	if err != nil {
+		cmd.showStagingSummary(stagingLog)
+		return
+	}
 
 	cmd.ui.Say("")
 
-	cmd.waitForOneRunningInstance(updatedApp)
+	if updatedApp.HealthCheckType == "" {
+		updatedApp.HealthCheckType = app.HealthCheckType
+	}
+
+	err = cmd.waitForOneRunningInstance(updatedApp)
+	if err != nil {
+		cmd.showStagingSummary(stagingLog)
+		return
+	}
 	cmd.ui.Say(terminal.HeaderColor("\nApp started\n"))
 
 	cmd.appDisplayer.ShowApp(updatedApp)
@@ -151,6 +208,10 @@ func (cmd *Start) SetStartTimeoutInSeconds(timeout int) {
 	cmd.StartupTimeout = time.Duration(timeout) * time.Second
 }
 
+func (cmd *Start) SetStagingLogPath(path string) {
+	cmd.StagingLogPath = path
+}
+
 func simpleLogMessageOutput(logMsg *logmessage.LogMessage) (msgText string) {
 	msgText = string(logMsg.GetMessage())
 	reg, err := regexp.Compile("[\n\r]+$")
@@ -161,7 +222,7 @@ func simpleLogMessageOutput(logMsg *logmessage.LogMessage) (msgText string) {
 	return
 }
 
-func (cmd Start) tailStagingLogs(app models.Application, startChan chan bool, stopChan chan bool) {
+func (cmd Start) tailStagingLogs(app models.Application, stagingLog *stagingLog, startChan chan bool, stopChan chan bool) {
 	onConnect := func() {
 		startChan <- true
 	}
@@ -173,6 +234,7 @@ func (cmd Start) tailStagingLogs(app models.Application, startChan chan bool, st
 		default:
 			if msg.GetSourceName() == LogMessageTypeStaging {
 				cmd.ui.Say(simpleLogMessageOutput(msg))
+				stagingLog.Add(msg)
 			}
 		}
 	})
@@ -184,32 +246,72 @@ func (cmd Start) tailStagingLogs(app models.Application, startChan chan bool, st
 	}
 }
 
-func (cmd Start) waitForInstancesToStage(app models.Application) {
+func (cmd Start) waitForInstancesToStage(app models.Application, stagingLog *stagingLog) error {
 	stagingStartTime := time.Now()
 	_, err := cmd.appInstancesRepo.GetInstances(app.Guid)
+	stagingLog.SetStagingError(err)
 
 	for err != nil && time.Since(stagingStartTime) < cmd.StagingTimeout {
 		if err, ok := err.(errors.HttpError); ok && err.ErrorCode() != errors.APP_NOT_STAGED {
 			cmd.ui.Say("")
-			cmd.ui.Failed(fmt.Sprintf("%s\n\nTIP: use '%s' for more information",
+			return errors.NewWithFmt("%s\n\nTIP: use '%s' for more information",
 				err.Error(),
-				terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name))))
-			return
+				terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name)))
 		}
 		cmd.ui.Wait(cmd.PingerThrottle)
 		_, err = cmd.appInstancesRepo.GetInstances(app.Guid)
+		stagingLog.SetStagingError(err)
 	}
-	return
+	return nil
+}
+
+// showStagingSummary shows why staging failed, so that it can be seen once
+// the staging logs have scrolled away.
+func (cmd Start) showStagingSummary(stagingLog *stagingLog) {
+	buildpack, errorLines, stagingErr := stagingLog.Summary()
+	if stagingErr == nil {
+		return
+	}
+
+	if buildpack == "" {
+		buildpack = "none detected"
+	}
+
+	cmd.ui.Say("")
+	cmd.ui.Say(terminal.HeaderColor("Staging failed"))
+	cmd.ui.Say("buildpack: %s", terminal.EntityNameColor(buildpack))
+	cmd.ui.Say("error: %s", stagingErr.Error())
+
+	if len(errorLines) > 0 {
+		cmd.ui.Say("last errors in the staging log:")
+		for _, line := range errorLines {
+			cmd.ui.Say("  %s", line)
+		}
+	}
+
+	if cmd.StagingLogPath != "" {
+		cmd.ui.Say("full staging log: %s", cmd.StagingLogPath)
+	}
+	cmd.ui.Say("")
 }
 
-func (cmd Start) waitForOneRunningInstance(app models.Application) {
+// waitForOneRunningInstance waits until an instance of app is running. Apps
+// with no health check are never checked for listening on their port, so for
+// them an instance that is starting is good enough.
+func (cmd Start) waitForOneRunningInstance(app models.Application) error {
 	var runningCount, startingCount, flappingCount, downCount int
 	startupStartTime := time.Now()
 
-	for runningCount == 0 {
+	started := func() bool {
+		if app.HealthCheckType == models.HealthCheckTypeNone {
+			return runningCount+startingCount > 0
+		}
+		return runningCount > 0
+	}
+
+	for !started() {
 		if time.Since(startupStartTime) > cmd.StartupTimeout {
-			cmd.ui.Failed(fmt.Sprintf("Start app timeout\n\nTIP: use '%s' for more information", terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name))))
-			return
+			return errors.NewWithFmt("Start app timeout\n\nTIP: use '%s' for more information", terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name)))
 		}
 
 		instances, apiErr := cmd.appInstancesRepo.GetInstances(app.Guid)
@@ -237,10 +339,11 @@ func (cmd Start) waitForOneRunningInstance(app models.Application) {
 		cmd.ui.Say(instancesDetails(startingCount, downCount, runningCount, flappingCount, totalCount))
 
 		if flappingCount > 0 {
-			cmd.ui.Failed(fmt.Sprintf("Start unsuccessful\n\nTIP: use '%s' for more information", terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name))))
-			return
+			return errors.NewWithFmt("Start unsuccessful\n\nTIP: use '%s' for more information", terminal.CommandColor(fmt.Sprintf("%s logs %s --recent", cf.Name(), app.Name)))
 		}
 	}
+
+	return nil
 }
 
 func instancesDetails(startingCount, downCount, runningCount, flappingCount, totalCount int) string {
@@ -260,3 +363,85 @@ func instancesDetails(startingCount, downCount, runningCount, flappingCount, tot
 
 	return strings.Join(details, ", ")
 }
+
+var (
+	stagingErrorPattern       = regexp.MustCompile(`(?i)\b(error|failed|failure|fatal)\b`)
+	detectedBuildpackPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^-----> (.+) app detected`),
+		regexp.MustCompile(`^-----> (.+ Buildpack) Version`),
+	}
+)
+
+// stagingLog keeps what is needed to summarize the staging of an app,
+// writing the staging logs to a file as well when given one. Log messages
+// arrive while staging is being waited for, so it is safe to use from
+// several goroutines.
+type stagingLog struct {
+	file       *os.File
+	buildpack  string
+	errorLines []string
+	stagingErr error
+	mutex      sync.Mutex
+}
+
+func newStagingLog(app models.Application, path string) (log *stagingLog, err error) {
+	log = &stagingLog{}
+	if path == "" {
+		return
+	}
+
+	log.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		err = errors.NewWithFmt("Could not open staging log %s\n%s", path, err.Error())
+		return
+	}
+
+	fmt.Fprintf(log.file, "==> Staging app %s at %s\n", app.Name, time.Now().Format(time.RFC3339))
+	return
+}
+
+func (log *stagingLog) Add(msg *logmessage.LogMessage) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	line := simpleLogMessageOutput(msg)
+	if log.file != nil {
+		fmt.Fprintln(log.file, line)
+	}
+
+	for _, pattern := range detectedBuildpackPatterns {
+		if match := pattern.FindStringSubmatch(line); match != nil {
+			log.buildpack = match[1]
+		}
+	}
+
+	if msg.GetMessageType() == logmessage.LogMessage_ERR || stagingErrorPattern.MatchString(line) {
+		log.errorLines = append(log.errorLines, line)
+		if len(log.errorLines) > StagingSummaryErrorLines {
+			log.errorLines = log.errorLines[len(log.errorLines)-StagingSummaryErrorLines:]
+		}
+	}
+}
+
+// SetStagingError remembers why the instances of the app could not be found
+// the last time they were asked for, which is nil once it has staged.
+func (log *stagingLog) SetStagingError(err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.stagingErr = err
+}
+
+func (log *stagingLog) Summary() (buildpack string, errorLines []string, stagingErr error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.buildpack, append([]string{}, log.errorLines...), log.stagingErr
+}
+
+func (log *stagingLog) Close() {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.file != nil {
+		log.file.Close()
+		log.file = nil
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/cloudfoundry/loggregatorlib/logmessage"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
 	"os"
 	testapi "testhelpers/api"
 	testassert "testhelpers/assert"
@@ -94,6 +95,16 @@ var _ = Describe("start command", func() {
 		Expect(cmd.StartupTimeout).To(Equal(5 * time.Minute))
 	})
 
+	It("tails staging logs with a connection of its own in each copy for another UI", func() {
+		logRepo := &testapi.FakeLogsRepository{}
+		cmd := NewStart(new(testterm.FakeUI), testconfig.NewRepository(), &testcmd.FakeAppDisplayer{}, &testapi.FakeApplicationRepository{}, &testapi.FakeAppInstancesRepo{}, logRepo)
+
+		cmd.WithUI(new(testterm.FakeUI))
+		cmd.WithUI(new(testterm.FakeUI))
+
+		Expect(logRepo.Clones).To(Equal(2))
+	})
+
 	It("fails requirements when not logged in", func() {
 		requirementsFactory.LoginSuccess = false
 		cmd := NewStart(new(testterm.FakeUI), testconfig.NewRepository(), &testcmd.FakeAppDisplayer{}, &testapi.FakeApplicationRepository{}, &testapi.FakeAppInstancesRepo{}, &testapi.FakeLogsRepository{})
@@ -241,6 +252,83 @@ var _ = Describe("start command", func() {
 			})
 		})
 
+		Describe("when staging fails", func() {
+			var (
+				appRepo          *testapi.FakeApplicationRepository
+				appInstancesRepo *testapi.FakeAppInstancesRepo
+				logRepo          *testapi.FakeLogsRepository
+			)
+
+			BeforeEach(func() {
+				requirementsFactory.Application = defaultAppForStart
+				appRepo = &testapi.FakeApplicationRepository{UpdateAppResult: defaultAppForStart}
+				appInstancesRepo = &testapi.FakeAppInstancesRepo{
+					GetInstancesResponses:  [][]models.AppInstanceFields{{}, {}},
+					GetInstancesErrorCodes: []string{errors.APP_NOT_STAGED, "170001"},
+				}
+
+				now := time.Now()
+				logRepo = &testapi.FakeLogsRepository{
+					TailLogMessages: []*logmessage.LogMessage{
+						testlogs.NewLogMessage("-----> Ruby app detected", defaultAppForStart.Guid, LogMessageTypeStaging, now),
+						testlogs.NewLogMessage("ERROR: could not install gems", defaultAppForStart.Guid, LogMessageTypeStaging, now),
+						testlogs.NewLogMessage("Instance 0 has crashed", defaultAppForStart.Guid, "DEA", now),
+					},
+				}
+			})
+
+			It("summarizes the staging logs", func() {
+				ui := callStart([]string{"my-app"}, testconfig.NewRepositoryWithDefaults(), requirementsFactory, &testcmd.FakeAppDisplayer{}, appRepo, appInstancesRepo, logRepo)
+
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"Staging failed"},
+					{"buildpack", "Ruby"},
+					{"error", "170001", "Error staging app"},
+					{"last errors in the staging log"},
+					{"ERROR: could not install gems"},
+					{"FAILED"},
+				})
+				testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+					{"Instance 0 has crashed"},
+				})
+			})
+
+			It("appends the staging logs to the file given with --staging-log", func() {
+				logFile, err := ioutil.TempFile("", "staging-log")
+				Expect(err).NotTo(HaveOccurred())
+				logFile.WriteString("earlier logs\n")
+				logFile.Close()
+				defer os.Remove(logFile.Name())
+
+				ui := callStart([]string{"--staging-log", logFile.Name(), "my-app"}, testconfig.NewRepositoryWithDefaults(), requirementsFactory, &testcmd.FakeAppDisplayer{}, appRepo, appInstancesRepo, logRepo)
+
+				contents, err := ioutil.ReadFile(logFile.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(HavePrefix("earlier logs\n==> Staging app my-app at "))
+				Expect(string(contents)).To(HaveSuffix("\n-----> Ruby app detected\nERROR: could not install gems\n"))
+				testassert.SliceContains(ui.Outputs, testassert.Lines{
+					{"full staging log", logFile.Name()},
+				})
+			})
+		})
+
+		It("does not show a staging summary when the app stages but fails to start", func() {
+			displayApp := &testcmd.FakeAppDisplayer{}
+			appInstance := models.AppInstanceFields{}
+			appInstance.State = models.InstanceFlapping
+			instances := [][]models.AppInstanceFields{{appInstance}, {appInstance}}
+
+			ui, _, _ := startAppWithInstancesAndErrors(displayApp, defaultAppForStart, instances, []string{"", ""}, requirementsFactory)
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+				{"Start unsuccessful"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"Staging failed"},
+			})
+		})
+
 		It("TestStartApplicationWhenOneInstanceFlaps", func() {
 			displayApp := &testcmd.FakeAppDisplayer{}
 			appInstance := models.AppInstanceFields{}
@@ -304,6 +392,64 @@ var _ = Describe("start command", func() {
 			})
 		})
 
+		It("does not wait for instances to be running when the app has no health check", func() {
+			displayApp := &testcmd.FakeAppDisplayer{}
+			appInstance := models.AppInstanceFields{}
+			appInstance.State = models.InstanceStarting
+			instances := [][]models.AppInstanceFields{
+				[]models.AppInstanceFields{appInstance},
+				[]models.AppInstanceFields{appInstance},
+			}
+
+			app := defaultAppForStart
+			app.HealthCheckType = models.HealthCheckTypeNone
+
+			ui, _, _ := startAppWithInstancesAndErrors(displayApp, app, instances, []string{"", ""}, requirementsFactory)
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"0 of 1 instances running", "1 starting"},
+				{"App started"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"FAILED"},
+			})
+		})
+
+		It("watches an app staged by the given function", func() {
+			displayApp := &testcmd.FakeAppDisplayer{}
+			appRepo := &testapi.FakeApplicationRepository{}
+			appInstancesRepo := &testapi.FakeAppInstancesRepo{
+				GetInstancesResponses:  defaultInstanceReponses,
+				GetInstancesErrorCodes: defaultInstanceErrorCodes,
+			}
+			logRepo := &testapi.FakeLogsRepository{
+				TailLogMessages: []*logmessage.LogMessage{
+					testlogs.NewLogMessage("Staging...", defaultAppForStart.Guid, LogMessageTypeStaging, time.Now()),
+				},
+			}
+			ui := new(testterm.FakeUI)
+
+			cmd := NewStart(ui, testconfig.NewRepositoryWithDefaults(), displayApp, appRepo, appInstancesRepo, logRepo)
+			cmd.StagingTimeout = 50 * time.Millisecond
+			cmd.StartupTimeout = 50 * time.Millisecond
+			cmd.PingerThrottle = 50 * time.Millisecond
+
+			stagedApp := models.Application{}
+			_, err := cmd.ApplicationWatchStaging(defaultAppForStart, func(app models.Application) (models.Application, error) {
+				stagedApp = app
+				return app, nil
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stagedApp.Guid).To(Equal("my-app-guid"))
+			Expect(appRepo.UpdateAppGuid).To(Equal(""))
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Staging..."},
+				{"OK"},
+				{"App started"},
+			})
+		})
+
 		It("tells the user about the failure when starting the app fails", func() {
 			config := testconfig.NewRepository()
 			displayApp := &testcmd.FakeAppDisplayer{}
@@ -31,6 +31,13 @@ func NewStop(ui terminal.UI, config configuration.Reader, appRepo api.Applicatio
 	return
 }
 
+// WithUI returns a copy of the command that writes its output to ui.
+func (cmd *Stop) WithUI(ui terminal.UI) ApplicationStopper {
+	stopper := *cmd
+	stopper.ui = ui
+	return &stopper
+}
+
 func (command *Stop) Metadata() command_metadata.CommandMetadata {
 	return command_metadata.CommandMetadata{
 		Name:        "stop",
@@ -0,0 +1,65 @@
+package application
+
+import (
+	"cf/command_metadata"
+	"cf/errors"
+	"cf/flag_helpers"
+	"cf/manifest"
+	"cf/requirements"
+	"cf/terminal"
+	"github.com/codegangsta/cli"
+	"os"
+)
+
+type ValidateManifest struct {
+	ui           terminal.UI
+	manifestRepo manifest.ManifestRepository
+}
+
+func NewValidateManifest(ui terminal.UI, manifestRepo manifest.ManifestRepository) (cmd *ValidateManifest) {
+	cmd = new(ValidateManifest)
+	cmd.ui = ui
+	cmd.manifestRepo = manifestRepo
+	return
+}
+
+func (command *ValidateManifest) Metadata() command_metadata.CommandMetadata {
+	return command_metadata.CommandMetadata{
+		Name:        "validate-manifest",
+		Description: "Check a manifest for mistakes without pushing it, including unknown keys that push only warns about",
+		Usage: "CF_NAME validate-manifest [-f MANIFEST_PATH]\n\n" +
+			"Each problem is given with the line and column it was found at. In manifests that use\n" +
+			"flow style YAML such as {key: value}, anchors or complex keys, these are approximate,\n" +
+			"and labelled as such.",
+		Flags: []cli.Flag{
+			flag_helpers.NewStringFlag("f", "Path to manifest, defaults to the manifest in the current directory"),
+		},
+	}
+}
+
+func (cmd *ValidateManifest) GetRequirements(requirementsFactory requirements.Factory, c *cli.Context) (reqs []requirements.Requirement, err error) {
+	return
+}
+
+func (cmd *ValidateManifest) Run(c *cli.Context) {
+	path := c.String("f")
+	if path == "" {
+		var err error
+		path, err = os.Getwd()
+		if err != nil {
+			cmd.ui.Failed("Could not determine the current working directory: %s", err.Error())
+			return
+		}
+	}
+
+	cmd.ui.Say("Validating manifest %s...", terminal.EntityNameColor(path))
+
+	errs := cmd.manifestRepo.ValidateManifest(path)
+	if len(errs) > 0 {
+		cmd.ui.Failed("Found %d problem(s) in the manifest:\n%s", len(errs), errors.NewWithSlice(errs))
+		return
+	}
+
+	cmd.ui.Ok()
+	cmd.ui.Say("Manifest is valid")
+}
@@ -0,0 +1,66 @@
+package application_test
+
+import (
+	. "cf/commands/application"
+	"cf/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testassert "testhelpers/assert"
+	testcmd "testhelpers/commands"
+	testmanifest "testhelpers/manifest"
+	testreq "testhelpers/requirements"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("validate-manifest command", func() {
+	var (
+		cmd          *ValidateManifest
+		ui           *testterm.FakeUI
+		manifestRepo *testmanifest.FakeManifestRepository
+	)
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+		manifestRepo = &testmanifest.FakeManifestRepository{}
+		cmd = NewValidateManifest(ui, manifestRepo)
+	})
+
+	runCommand := func(args ...string) {
+		testcmd.RunCommand(cmd, testcmd.NewContext("validate-manifest", args), &testreq.FakeReqFactory{})
+	}
+
+	It("does not require login", func() {
+		runCommand("-f", "manifest.yml")
+		Expect(testcmd.CommandDidPassRequirements).To(BeTrue())
+	})
+
+	It("validates the manifest at the given path", func() {
+		runCommand("-f", "path/to/manifest.yml")
+
+		Expect(manifestRepo.ValidateManifestArgs.Path).To(Equal("path/to/manifest.yml"))
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"Validating manifest", "path/to/manifest.yml"},
+			{"OK"},
+			{"Manifest is valid"},
+		})
+	})
+
+	It("prints every problem found and fails", func() {
+		manifestRepo.ValidateManifestReturns.Errors = []error{
+			manifest.ManifestError{File: "manifest.yml", Line: 5, Column: 3, Message: "Unknown key 'instance', did you mean 'instances'?"},
+			manifest.ManifestError{File: "manifest.yml", Line: 6, Column: 3, Message: "Invalid memory 'lots'"},
+		}
+
+		runCommand("-f", "manifest.yml")
+
+		testassert.SliceContains(ui.Outputs, testassert.Lines{
+			{"FAILED"},
+			{"Found 2 problem(s) in the manifest"},
+			{"manifest.yml:5:3: Unknown key 'instance', did you mean 'instances'?"},
+			{"manifest.yml:6:3: Invalid memory 'lots'"},
+		})
+		testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+			{"Manifest is valid"},
+		})
+	})
+})
@@ -75,7 +75,9 @@ func (cmd CreateBuildpack) Run(c *cli.Context) {
 
 	dir := c.Args()[1]
 
-	err = cmd.buildpackBitsRepo.UploadBuildpack(buildpack, dir)
+	progress := cmd.ui.ProgressBar()
+	err = cmd.buildpackBitsRepo.UploadBuildpack(buildpack, dir, progress.Update)
+	progress.Finish(err)
 	if err != nil {
 		cmd.ui.Failed(err.Error())
 		return
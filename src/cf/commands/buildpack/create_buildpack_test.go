@@ -61,6 +61,16 @@ var _ = Describe("create-buildpack command", func() {
 		})
 	})
 
+	It("shows the progress of the upload", func() {
+		context := testcmd.NewContext("create-buildpack", []string{"my-buildpack", "my.war", "5"})
+		testcmd.RunCommand(cmd, context, requirementsFactory)
+
+		bitsRepo.UploadBuildpackProgress(10, 20)
+		Expect(ui.ProgressBars).To(HaveLen(1))
+		Expect(ui.ProgressBars[0].Sent).To(Equal([]int64{10}))
+		Expect(ui.ProgressBars[0].Finished).To(BeTrue())
+	})
+
 	It("warns the user when the buildpack already exists", func() {
 		repo.CreateBuildpackExists = true
 		context := testcmd.NewContext("create-buildpack", []string{"my-buildpack", "my.war", "5"})
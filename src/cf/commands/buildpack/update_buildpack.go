@@ -121,7 +121,9 @@ func (cmd *UpdateBuildpack) Run(c *cli.Context) {
 	}
 
 	if dir != "" {
-		apiErr := cmd.buildpackBitsRepo.UploadBuildpack(buildpack, dir)
+		progress := cmd.ui.ProgressBar()
+		apiErr := cmd.buildpackBitsRepo.UploadBuildpack(buildpack, dir, progress.Update)
+		progress.Finish(apiErr)
 		if apiErr != nil {
 			cmd.ui.Failed("Error uploading buildpack %s\n%s", terminal.EntityNameColor(buildpack.Name), apiErr.Error())
 			return
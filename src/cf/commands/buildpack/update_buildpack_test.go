@@ -144,6 +144,8 @@ var _ = Describe("Testing with ginkgo", func() {
 		ui := callUpdateBuildpack([]string{"-p", "buildpack.zip", "my-buildpack"}, requirementsFactory, repo, bitsRepo)
 
 		Expect(bitsRepo.UploadBuildpackPath).To(Equal("buildpack.zip"))
+		Expect(bitsRepo.UploadBuildpackProgress).NotTo(BeNil())
+		Expect(ui.ProgressBars[0].Finished).To(BeTrue())
 
 		testassert.SliceContains(ui.Outputs, testassert.Lines{
 			{"Updating buildpack", "my-buildpack"},
@@ -7,16 +7,28 @@ import (
 )
 
 func DefaultFilePath() string {
-	var configDir string
+	return filepath.Join(defaultConfigDir(), "config.json")
+}
+
+// DefaultUploadArtifactsDir is where app uploads that failed are kept so
+// that they can be resumed.
+func DefaultUploadArtifactsDir() string {
+	return filepath.Join(defaultConfigDir(), "uploads")
+}
+
+// DefaultFingerprintCacheDir is where the SHA1s of pushed app files are
+// cached between pushes.
+func DefaultFingerprintCacheDir() string {
+	return filepath.Join(defaultConfigDir(), "fingerprints")
+}
 
+func defaultConfigDir() string {
 	if os.Getenv("CF_HOME") != "" {
 		cfHome := os.Getenv("CF_HOME")
-		configDir = filepath.Join(cfHome, ".cf")
-	} else {
-		configDir = filepath.Join(userHomeDir(), ".cf")
+		return filepath.Join(cfHome, ".cf")
 	}
 
-	return filepath.Join(configDir, "config.json")
+	return filepath.Join(userHomeDir(), ".cf")
 }
 
 // See: http://stackoverflow.com/questions/7922270/obtain-users-home-directory
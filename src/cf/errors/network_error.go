@@ -0,0 +1,15 @@
+package errors
+
+// NetworkError is returned when a request could not be sent, or its response
+// could not be read, so that callers can tell it from errors of the server.
+type NetworkError struct {
+	err error
+}
+
+func NewNetworkError(err error) error {
+	return &NetworkError{err: err}
+}
+
+func (err *NetworkError) Error() string {
+	return "Error performing request: " + err.err.Error()
+}
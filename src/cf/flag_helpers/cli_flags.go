@@ -1,9 +1,12 @@
 package flag_helpers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/codegangsta/cli"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func NewIntFlag(name, usage string) IntFlagWithNoDefault {
@@ -22,6 +25,12 @@ func NewStringSliceFlag(name, usage string) StringSliceFlagWithNoDefault {
 	return StringSliceFlagWithNoDefault{cli.StringSliceFlag{Name: name, Usage: usage, Value: &cli.StringSlice{}}}
 }
 
+// NewOptionalDurationFlag makes a flag that may be given alone, as --name,
+// to use defaultValue, or with a value, as --name=5 (seconds) or --name=500ms.
+func NewOptionalDurationFlag(name, usage string, defaultValue time.Duration) OptionalDurationFlag {
+	return OptionalDurationFlag{cli.GenericFlag{Name: name, Usage: usage, Value: &OptionalDuration{Default: defaultValue}}}
+}
+
 type IntFlagWithNoDefault struct {
 	cli.IntFlag
 }
@@ -34,6 +43,55 @@ type StringSliceFlagWithNoDefault struct {
 	cli.StringSliceFlag
 }
 
+type OptionalDurationFlag struct {
+	cli.GenericFlag
+}
+
+func (f OptionalDurationFlag) String() string {
+	return fmt.Sprintf("%s%s[=INTERVAL] \t%s", prefixFor(f.Name), f.Name, f.Usage)
+}
+
+// OptionalDuration is the value of an OptionalDurationFlag.
+type OptionalDuration struct {
+	IsSet   bool
+	Value   time.Duration
+	Default time.Duration
+}
+
+func (d *OptionalDuration) Set(value string) error {
+	switch value {
+	case "true":
+		d.IsSet, d.Value = true, d.Default
+		return nil
+	case "false":
+		d.IsSet = false
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if seconds, atoiErr := strconv.Atoi(value); atoiErr == nil {
+		duration, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || duration <= 0 {
+		return errors.New("Expected a number of seconds or a duration such as 500ms")
+	}
+
+	d.IsSet, d.Value = true, duration
+	return nil
+}
+
+func (d *OptionalDuration) String() string {
+	if !d.IsSet {
+		return ""
+	}
+	return d.Value.String()
+}
+
+// IsBoolFlag lets the flag be given without a value.
+func (d *OptionalDuration) IsBoolFlag() bool {
+	return true
+}
+
 func (f IntFlagWithNoDefault) String() string {
 	defaultVal := fmt.Sprintf("'%v'", f.Value)
 	return strings.Replace(f.IntFlag.String(), defaultVal, "", 1)
@@ -0,0 +1,56 @@
+package hooks
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Runner runs the commands the manifest of an app asks to be run locally
+// before and after it is pushed.
+type Runner interface {
+	// Run runs command with the shell in dir, with env added to the
+	// environment, calling onOutput with each line it writes to stdout or
+	// stderr as soon as it is written.
+	Run(command, dir string, env map[string]string, onOutput func(line string)) error
+}
+
+type shellRunner struct{}
+
+func NewRunner() Runner {
+	return shellRunner{}
+}
+
+func (runner shellRunner) Run(command, dir string, env map[string]string, onOutput func(line string)) (err error) {
+	cmd := shellCommand(command)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+
+	outputDone := make(chan bool)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			onOutput(scanner.Text())
+		}
+		io.Copy(ioutil.Discard, reader)
+		close(outputDone)
+	}()
+
+	err = cmd.Wait()
+	writer.Close()
+	<-outputDone
+	return
+}
@@ -0,0 +1,64 @@
+package hooks_test
+
+import (
+	. "cf/hooks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+var _ = Describe("running hooks", func() {
+	var (
+		runner Runner
+		dir    string
+		output []string
+	)
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("these hooks are unix shell commands")
+		}
+
+		runner = NewRunner()
+		output = []string{}
+
+		var err error
+		dir, err = ioutil.TempDir("", "hooks")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	collectOutput := func(line string) {
+		output = append(output, line)
+	}
+
+	It("runs the command in the given directory with the given environment", func() {
+		err := runner.Run("touch built && echo $CF_APP in $CF_SPACE", dir, map[string]string{"CF_APP": "my-app", "CF_SPACE": "my-space"}, collectOutput)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal([]string{"my-app in my-space"}))
+		_, err = os.Stat(filepath.Join(dir, "built"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("passes on each line of stdout and stderr", func() {
+		err := runner.Run("echo one; echo two >&2; echo three", dir, nil, collectOutput)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal([]string{"one", "two", "three"}))
+	})
+
+	It("returns an error when the command fails", func() {
+		err := runner.Run("echo compiling; exit 3", dir, nil, collectOutput)
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("exit status 3"))
+		Expect(output).To(Equal([]string{"compiling"}))
+	})
+})
@@ -0,0 +1,9 @@
+// +build darwin freebsd linux netbsd openbsd
+
+package hooks
+
+import "os/exec"
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
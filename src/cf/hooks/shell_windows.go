@@ -0,0 +1,7 @@
+package hooks
+
+import "os/exec"
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
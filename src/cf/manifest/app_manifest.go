@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"cf/models"
+	"fmt"
+	"generic"
+	"github.com/cloudfoundry-incubator/candiedyaml"
+	"io"
+)
+
+// NewManifestFromApp describes app, as returned by an app summary, in a
+// manifest that can be pushed to create the same app again.
+func NewManifestFromApp(path string, app models.Application) *Manifest {
+	appMap := generic.NewMap()
+	appMap.Set("name", app.Name)
+	appMap.Set("memory", fmt.Sprintf("%dM", app.Memory))
+	appMap.Set("disk_quota", fmt.Sprintf("%dM", app.DiskQuota))
+	appMap.Set("instances", app.InstanceCount)
+
+	if app.BuildpackUrl != "" {
+		appMap.Set("buildpack", app.BuildpackUrl)
+	}
+	if app.Command != "" {
+		appMap.Set("command", app.Command)
+	}
+	if app.HealthCheckType != "" && app.HealthCheckType != models.HealthCheckTypePort {
+		appMap.Set("health-check-type", app.HealthCheckType)
+	}
+	if app.Stack != nil && app.Stack.Name != "" {
+		appMap.Set("stack", app.Stack.Name)
+	}
+
+	if len(app.EnvironmentVars) > 0 {
+		env := generic.NewMap()
+		for name, value := range app.EnvironmentVars {
+			env.Set(name, value)
+		}
+		appMap.Set("env", env)
+	}
+
+	switch {
+	case len(app.Routes) == 0:
+		appMap.Set("no-route", true)
+	case len(app.Routes) == 1 && app.Routes[0].Host != "":
+		appMap.Set("host", app.Routes[0].Host)
+		appMap.Set("domain", app.Routes[0].Domain.Name)
+	default:
+		routes := []interface{}{}
+		for _, route := range app.Routes {
+			routes = append(routes, route.URL())
+		}
+		appMap.Set("routes", routes)
+	}
+
+	if len(app.Services) > 0 {
+		services := []interface{}{}
+		for _, service := range app.Services {
+			services = append(services, service.Name)
+		}
+		appMap.Set("services", services)
+	}
+
+	return &Manifest{
+		Path: path,
+		Data: generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{appMap},
+		}),
+	}
+}
+
+func (m Manifest) Save(writer io.Writer) error {
+	return candiedyaml.NewEncoder(writer).Encode(yamlValue(m.Data))
+}
+
+func yamlValue(input interface{}) interface{} {
+	switch input := input.(type) {
+	case generic.Map:
+		output := map[interface{}]interface{}{}
+		generic.Each(input, func(key, value interface{}) {
+			output[key] = yamlValue(value)
+		})
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(input))
+		for index, item := range input {
+			output[index] = yamlValue(item)
+		}
+		return output
+	default:
+		return input
+	}
+}
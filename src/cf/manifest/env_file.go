@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"cf/errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ReadEnvFile reads the environment variables in the dotenv file at path.
+func ReadEnvFile(path string) (vars map[string]string, err error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = errors.NewWithFmt("Cannot read env file %s\n%s", path, err.Error())
+		return
+	}
+
+	vars, err = ParseEnvFile(string(contents))
+	if err != nil {
+		err = errors.NewWithFmt("%s:%s", path, err.Error())
+	}
+	return
+}
+
+// ParseEnvFile reads environment variables in the dotenv format, one
+// NAME=VALUE per line:
+//   - blank lines, and lines and the rest of unquoted values starting
+//     with `#`, are comments
+//   - a line may start with `export`
+//   - values in single quotes are taken as they are
+//   - values in double quotes may contain \n, \t, \" and \\ escapes
+//   - quoted values may run over several lines
+func ParseEnvFile(text string) (vars map[string]string, err error) {
+	vars = map[string]string{}
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+
+	for index := 0; index < len(lines); index++ {
+		lineNumber := index + 1
+		line := strings.TrimLeft(lines[index], " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimLeft(strings.TrimPrefix(line, "export "), " \t")
+		}
+
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			err = errors.NewWithFmt("%d: Expected NAME=VALUE, but found '%s'", lineNumber, strings.TrimSpace(line))
+			return
+		}
+
+		name := strings.TrimSpace(line[:separator])
+		if !envVarNameRegex.MatchString(name) {
+			err = errors.NewWithFmt("%d: Invalid environment variable name '%s'", lineNumber, name)
+			return
+		}
+
+		value := strings.TrimLeft(line[separator+1:], " \t")
+		if !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+			vars[name] = unquotedEnvValue(value)
+			continue
+		}
+
+		quote := value[0]
+		value = value[1:]
+		var end int
+		for end = closingQuote(value, quote); end < 0 && index+1 < len(lines); end = closingQuote(value, quote) {
+			index++
+			value += "\n" + lines[index]
+		}
+
+		if end < 0 {
+			err = errors.NewWithFmt("%d: The value of %s has no closing %c", lineNumber, name, quote)
+			return
+		}
+
+		rest := strings.TrimSpace(value[end+1:])
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			err = errors.NewWithFmt("%d: Unexpected '%s' after the value of %s", lineNumber, rest, name)
+			return
+		}
+
+		value = value[:end]
+		if quote == '"' {
+			value = unescapeEnvValue(value)
+		}
+		vars[name] = value
+	}
+	return
+}
+
+func unquotedEnvValue(value string) string {
+	for index := range value {
+		if value[index] == '#' && (index == 0 || value[index-1] == ' ' || value[index-1] == '\t') {
+			value = value[:index]
+			break
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// closingQuote returns the index of the quote that ends value, or -1.
+func closingQuote(value string, quote byte) int {
+	for index := 0; index < len(value); index++ {
+		switch value[index] {
+		case '\\':
+			if quote == '"' {
+				index++
+			}
+		case quote:
+			return index
+		}
+	}
+	return -1
+}
+
+func unescapeEnvValue(value string) string {
+	result := make([]byte, 0, len(value))
+	for index := 0; index < len(value); index++ {
+		if value[index] != '\\' || index+1 == len(value) {
+			result = append(result, value[index])
+			continue
+		}
+
+		index++
+		switch value[index] {
+		case 'n':
+			result = append(result, '\n')
+		case 'r':
+			result = append(result, '\r')
+		case 't':
+			result = append(result, '\t')
+		case '"', '\\', '$':
+			result = append(result, value[index])
+		default:
+			result = append(result, '\\', value[index])
+		}
+	}
+	return string(result)
+}
@@ -0,0 +1,115 @@
+package manifest_test
+
+import (
+	. "cf/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("env files", func() {
+	It("reads NAME=VALUE lines, skipping blank lines and comments", func() {
+		vars, err := ParseEnvFile(`
+# the database
+DATABASE_URL=postgres://localhost/db
+export LOG_LEVEL = debug
+  EMPTY=
+PORT=8080 # the port to listen on
+COLOR=red#not-a-comment
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars).To(Equal(map[string]string{
+			"DATABASE_URL": "postgres://localhost/db",
+			"LOG_LEVEL":    "debug",
+			"EMPTY":        "",
+			"PORT":         "8080",
+			"COLOR":        "red#not-a-comment",
+		}))
+	})
+
+	It("reads single quoted values as they are", func() {
+		vars, err := ParseEnvFile(`GREETING='hello # world\n' # says hello`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars["GREETING"]).To(Equal(`hello # world\n`))
+	})
+
+	It("unescapes double quoted values", func() {
+		vars, err := ParseEnvFile(`MESSAGE="say \"hi\"\tthen\\leave\n"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars["MESSAGE"]).To(Equal("say \"hi\"\tthen\\leave\n"))
+	})
+
+	It("reads quoted values over several lines", func() {
+		vars, err := ParseEnvFile("CERT=\"-----BEGIN-----\r\n  abc\r\n-----END-----\"\r\nSCRIPT='a\n\nb'\nNEXT=1\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vars).To(Equal(map[string]string{
+			"CERT":   "-----BEGIN-----\n  abc\n-----END-----",
+			"SCRIPT": "a\n\nb",
+			"NEXT":   "1",
+		}))
+	})
+
+	It("reports the line of lines without a value", func() {
+		_, err := ParseEnvFile("A=1\nJUST_A_NAME\n")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("2: Expected NAME=VALUE, but found 'JUST_A_NAME'"))
+	})
+
+	It("reports invalid names", func() {
+		_, err := ParseEnvFile("MY-VAR=1")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("1: Invalid environment variable name 'MY-VAR'"))
+	})
+
+	It("reports quoted values that are not closed", func() {
+		_, err := ParseEnvFile("A=1\nB=\"open\nstill open\n")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("2: The value of B has no closing \""))
+	})
+
+	It("reports text after a quoted value", func() {
+		_, err := ParseEnvFile(`A="one" two`)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("1: Unexpected 'two' after the value of A"))
+	})
+
+	Describe("ReadEnvFile", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "env-file")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads the variables in the file", func() {
+			path := filepath.Join(dir, ".env")
+			ioutil.WriteFile(path, []byte("A=1\n"), os.ModePerm)
+
+			vars, err := ReadEnvFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]string{"A": "1"}))
+		})
+
+		It("gives the path of the file in errors", func() {
+			path := filepath.Join(dir, ".env")
+			ioutil.WriteFile(path, []byte("A=1\nB\n"), os.ModePerm)
+
+			_, err := ReadEnvFile(path)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(path + ":2: Expected NAME=VALUE, but found 'B'"))
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			_, err := ReadEnvFile(filepath.Join(dir, "missing.env"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot read env file " + filepath.Join(dir, "missing.env")))
+		})
+	})
+})
@@ -16,6 +16,12 @@ import (
 type Manifest struct {
 	Path string
 	Data generic.Map
+
+	// Variables are substituted for ((name)) and ${name} in the manifest.
+	// Names without a value fall back to environment variables when
+	// VariablesFromEnv is set.
+	Variables        map[string]string
+	VariablesFromEnv bool
 }
 
 func NewEmptyManifest() (m *Manifest) {
@@ -23,7 +29,7 @@ func NewEmptyManifest() (m *Manifest) {
 }
 
 func (m Manifest) Applications() (apps []models.AppParams, err error) {
-	rawData, errs := expandProperties(m.Data, words.NewWordGenerator())
+	rawData, errs := m.expandProperties(m.Data, words.NewWordGenerator(), "")
 	if len(errs) > 0 {
 		err = errors.NewWithSlice(errs)
 		return
@@ -75,26 +81,30 @@ func (m Manifest) getAppMaps(data generic.Map) (apps []generic.Map, errs []error
 	return
 }
 
-var propertyRegex = regexp.MustCompile(`\${[\w-]+}`)
+var propertyRegex = regexp.MustCompile(`\(\(([\w-]+)\)\)|\${([\w-]+)}`)
 
-func expandProperties(input interface{}, babbler words.WordGenerator) (output interface{}, errs []error) {
+func (m Manifest) expandProperties(input interface{}, babbler words.WordGenerator, keyPath string) (output interface{}, errs []error) {
 	switch input := input.(type) {
 	case string:
-		match := propertyRegex.FindStringSubmatch(input)
-		if match != nil {
-			if match[0] == "${random-word}" {
-				output = strings.Replace(input, "${random-word}", strings.ToLower(babbler.Babble()), -1)
-			} else {
-				err := errors.NewWithFmt("Property '%s' found in manifest. This feature is no longer supported. Please remove it and try again.", match[0])
-				errs = append(errs, err)
+		output = propertyRegex.ReplaceAllStringFunc(input, func(property string) string {
+			match := propertyRegex.FindStringSubmatch(property)
+			name := match[1] + match[2]
+
+			if value, found := m.lookupVariable(name); found {
+				return value
 			}
-		} else {
-			output = input
-		}
+
+			if property == "${random-word}" {
+				return strings.ToLower(babbler.Babble())
+			}
+
+			errs = append(errs, m.unresolvedVariableError(property, keyPath))
+			return property
+		})
 	case []interface{}:
 		outputSlice := make([]interface{}, len(input))
 		for index, item := range input {
-			itemOutput, itemErrs := expandProperties(item, babbler)
+			itemOutput, itemErrs := m.expandProperties(item, babbler, fmt.Sprintf("%s[%d]", keyPath, index))
 			outputSlice[index] = itemOutput
 			errs = append(errs, itemErrs...)
 		}
@@ -102,7 +112,7 @@ func expandProperties(input interface{}, babbler words.WordGenerator) (output in
 	case map[interface{}]interface{}:
 		outputMap := make(map[interface{}]interface{})
 		for key, value := range input {
-			itemOutput, itemErrs := expandProperties(value, babbler)
+			itemOutput, itemErrs := m.expandProperties(value, babbler, joinKeyPath(keyPath, key))
 			outputMap[key] = itemOutput
 			errs = append(errs, itemErrs...)
 		}
@@ -110,7 +120,7 @@ func expandProperties(input interface{}, babbler words.WordGenerator) (output in
 	case generic.Map:
 		outputMap := generic.NewMap()
 		generic.Each(input, func(key, value interface{}) {
-			itemOutput, itemErrs := expandProperties(value, babbler)
+			itemOutput, itemErrs := m.expandProperties(value, babbler, joinKeyPath(keyPath, key))
 			outputMap.Set(key, itemOutput)
 			errs = append(errs, itemErrs...)
 		})
@@ -139,24 +149,53 @@ func mapToAppParams(basePath string, yamlMap generic.Map) (appParams models.AppP
 	appParams.Memory = bytesVal(yamlMap, "memory", &errs)
 	appParams.InstanceCount = intVal(yamlMap, "instances", &errs)
 	appParams.HealthCheckTimeout = intVal(yamlMap, "timeout", &errs)
+	appParams.HealthCheckType = healthCheckTypeVal(yamlMap, "health-check-type", &errs)
 	appParams.NoRoute = boolVal(yamlMap, "no-route", &errs)
 	appParams.UseRandomHostname = boolVal(yamlMap, "random-route", &errs)
 	appParams.ServicesToBind = sliceOrEmptyVal(yamlMap, "services", &errs)
+	appParams.DependsOn = sliceOrEmptyVal(yamlMap, "depends_on", &errs)
+	appParams.Routes = sliceOrEmptyVal(yamlMap, "routes", &errs)
+	appParams.Hosts = sliceOrEmptyVal(yamlMap, "hosts", &errs)
+	appParams.Domains = sliceOrEmptyVal(yamlMap, "domains", &errs)
 	appParams.EnvironmentVars = envVarOrEmptyMap(yamlMap, &errs)
+	appParams.BeforePush = sliceOrEmptyVal(yamlMap, "before_push", &errs)
+	appParams.AfterPush = sliceOrEmptyVal(yamlMap, "after_push", &errs)
+
+	envFile := stringVal(yamlMap, "env_file", &errs)
+	if envFile != nil && appParams.EnvironmentVars != nil {
+		appParams.EnvironmentVars = mergeEnvFile(absolutePath(basePath, *envFile), *appParams.EnvironmentVars, &errs)
+	}
 
 	if appParams.Path != nil {
-		path := *appParams.Path
-		if filepath.IsAbs(path) {
-			path = filepath.Clean(path)
-		} else {
-			path = filepath.Join(basePath, path)
-		}
+		path := absolutePath(basePath, *appParams.Path)
 		appParams.Path = &path
 	}
 
 	return
 }
 
+func absolutePath(basePath, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(basePath, path)
+}
+
+// mergeEnvFile reads the variables in the env file at path; those set
+// with env in the manifest take precedence.
+func mergeEnvFile(path string, envVars map[string]string, errs *[]error) *map[string]string {
+	result, err := ReadEnvFile(path)
+	if err != nil {
+		*errs = append(*errs, err)
+		return nil
+	}
+
+	for name, value := range envVars {
+		result[name] = value
+	}
+	return &result
+}
+
 func checkForNulls(yamlMap generic.Map) (errs []error) {
 	generic.Each(yamlMap, func(key interface{}, value interface{}) {
 		if key == "command" || key == "buildpack" {
@@ -183,6 +222,15 @@ func stringVal(yamlMap generic.Map, key string, errs *[]error) *string {
 	return &result
 }
 
+func healthCheckTypeVal(yamlMap generic.Map, key string, errs *[]error) *string {
+	result := stringVal(yamlMap, key, errs)
+	if result != nil && !models.IsValidHealthCheckType(*result) {
+		*errs = append(*errs, errors.NewWithFmt("%s must be one of %s", key, strings.Join(models.HealthCheckTypes, ", ")))
+		return nil
+	}
+	return result
+}
+
 func stringValOrDefault(yamlMap generic.Map, key string, errs *[]error) *string {
 	if !yamlMap.Has(key) {
 		return nil
@@ -11,6 +11,8 @@ import (
 
 type ManifestRepository interface {
 	ReadManifest(string) (*Manifest, error)
+	WriteManifest(*Manifest) error
+	ValidateManifest(string) []error
 }
 
 type ManifestDiskRepository struct{}
@@ -39,6 +41,21 @@ func (repo ManifestDiskRepository) ReadManifest(inputPath string) (*Manifest, er
 	return m, nil
 }
 
+func (repo ManifestDiskRepository) WriteManifest(m *Manifest) (err error) {
+	file, err := os.Create(filepath.Clean(m.Path))
+	if err != nil {
+		err = errors.NewWithError("Error creating manifest file", err)
+		return
+	}
+	defer file.Close()
+
+	err = m.Save(file)
+	if err != nil {
+		err = errors.NewWithError("Error writing manifest file", err)
+	}
+	return
+}
+
 func (repo ManifestDiskRepository) readAllYAMLFiles(path string) (mergedMap generic.Map, err error) {
 	file, err := os.Open(filepath.Clean(path))
 	if err != nil {
@@ -2,8 +2,11 @@ package manifest_test
 
 import (
 	. "cf/manifest"
+	"cf/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 )
 
@@ -152,4 +155,101 @@ var _ = Describe("ManifestDiskRepository", func() {
 		services := *applications[1].ServicesToBind
 		Expect(services).To(Equal([]string{"base-service", "foo-service"}))
 	})
+
+	Describe("reading a vars file", func() {
+		It("returns the variables in the file as strings", func() {
+			vars, err := ReadVariablesFile("../../fixtures/manifests/vars.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]string{
+				"app-name":  "my-app",
+				"instances": "2",
+				"use-ssl":   "true",
+			}))
+		})
+
+		It("returns an error that includes the path when the file cannot be read", func() {
+			_, err := ReadVariablesFile("../../fixtures/manifests/does-not-exist.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does-not-exist.yml"))
+		})
+	})
+
+	Describe("writing a manifest created from an app", func() {
+		var (
+			dir string
+			app models.Application
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "manifest-test")
+			Expect(err).NotTo(HaveOccurred())
+
+			app = models.Application{}
+			app.Name = "my-app"
+			app.Memory = 512
+			app.DiskQuota = 2048
+			app.InstanceCount = 3
+			app.BuildpackUrl = "https://github.com/example/my-buildpack.git"
+			app.Command = "bundle exec rackup -p $PORT"
+			app.Stack = &models.Stack{Name: "lucid64"}
+			app.EnvironmentVars = map[string]string{"RACK_ENV": "production", "WORKERS": "4"}
+			app.Services = []models.ServiceInstanceFields{{Name: "my-db"}, {Name: "my-cache"}}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		writeAndReadBack := func(app models.Application) models.AppParams {
+			path := filepath.Join(dir, "manifest.yml")
+			err := repo.WriteManifest(NewManifestFromApp(path, app))
+			Expect(err).NotTo(HaveOccurred())
+
+			m, err := repo.ReadManifest(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(apps)).To(Equal(1))
+			return apps[0]
+		}
+
+		It("round trips the app's settings", func() {
+			app.Routes = []models.RouteSummary{{Host: "my-host", Domain: models.DomainFields{Name: "example.com"}}}
+
+			params := writeAndReadBack(app)
+
+			Expect(*params.Name).To(Equal("my-app"))
+			Expect(*params.Memory).To(Equal(uint64(512)))
+			Expect(*params.DiskQuota).To(Equal(uint64(2048)))
+			Expect(*params.InstanceCount).To(Equal(3))
+			Expect(*params.BuildpackUrl).To(Equal("https://github.com/example/my-buildpack.git"))
+			Expect(*params.Command).To(Equal("bundle exec rackup -p $PORT"))
+			Expect(*params.StackName).To(Equal("lucid64"))
+			Expect(*params.EnvironmentVars).To(Equal(map[string]string{"RACK_ENV": "production", "WORKERS": "4"}))
+			Expect(*params.ServicesToBind).To(Equal([]string{"my-db", "my-cache"}))
+			Expect(*params.Host).To(Equal("my-host"))
+			Expect(*params.Domain).To(Equal("example.com"))
+			Expect(params.NoRoute).To(BeFalse())
+		})
+
+		It("lists the routes when the app has more than one", func() {
+			app.Routes = []models.RouteSummary{
+				{Host: "my-host", Domain: models.DomainFields{Name: "example.com"}},
+				{Host: "", Domain: models.DomainFields{Name: "example.org"}},
+			}
+
+			params := writeAndReadBack(app)
+
+			Expect(params.Host).To(BeNil())
+			Expect(*params.Routes).To(Equal([]string{"my-host.example.com", "example.org"}))
+		})
+
+		It("marks apps without routes as having no route", func() {
+			params := writeAndReadBack(app)
+
+			Expect(params.NoRoute).To(BeTrue())
+		})
+	})
 })
@@ -5,6 +5,9 @@ import (
 	"generic"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	testassert "testhelpers/assert"
@@ -82,6 +85,36 @@ var _ = Describe("Manifests", func() {
 		Expect(*apps[0].HealthCheckTimeout).To(Equal(360))
 	})
 
+	It("sets applications' health check types", func() {
+		m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				map[interface{}]interface{}{
+					"name":              "worker",
+					"health-check-type": "none",
+				},
+			},
+		}))
+
+		apps, err := m.Applications()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*apps[0].HealthCheckType).To(Equal("none"))
+	})
+
+	It("returns an error when the health check type is not supported", func() {
+		m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+			"applications": []interface{}{
+				map[interface{}]interface{}{
+					"name":              "worker",
+					"health-check-type": "http",
+				},
+			},
+		}))
+
+		_, err := m.Applications()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("health-check-type must be one of port, none"))
+	})
+
 	It("does not allow nil values for environment variables", func() {
 		m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
 			"env": generic.NewMap(map[interface{}]interface{}{
@@ -265,6 +298,99 @@ var _ = Describe("Manifests", func() {
 		})
 	})
 
+	Describe("variables", func() {
+		var m *manifest.Manifest
+
+		BeforeEach(func() {
+			m = NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"applications": []interface{}{
+					generic.NewMap(map[interface{}]interface{}{
+						"name":      "((app-name))",
+						"instances": "${instances}",
+						"env": generic.NewMap(map[interface{}]interface{}{
+							"DATABASE_URL": "postgres://((db-host)):5432/${db-name}",
+						}),
+					}),
+				},
+			}))
+		})
+
+		It("substitutes ((name)) and ${name} with the value of the variable", func() {
+			m.Variables = map[string]string{
+				"app-name":  "my-app",
+				"instances": "3",
+				"db-host":   "db.example.com",
+				"db-name":   "production",
+			}
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*apps[0].Name).To(Equal("my-app"))
+			Expect(*apps[0].InstanceCount).To(Equal(3))
+			Expect((*apps[0].EnvironmentVars)["DATABASE_URL"]).To(Equal("postgres://db.example.com:5432/production"))
+		})
+
+		It("returns an error for each variable without a value, showing where it is in the manifest", func() {
+			m.Variables = map[string]string{"app-name": "my-app", "instances": "3"}
+
+			_, err := m.Applications()
+			Expect(err).To(HaveOccurred())
+
+			errorSlice := strings.Split(err.Error(), "\n")
+			testassert.SliceContains(errorSlice, testassert.Lines{
+				{"'((db-host))'", "applications[0].env.DATABASE_URL", "/some/path/manifest.yml"},
+			})
+			testassert.SliceContains(errorSlice, testassert.Lines{
+				{"'${db-name}'", "applications[0].env.DATABASE_URL", "/some/path/manifest.yml"},
+			})
+		})
+
+		It("uses environment variables for variables without a value when asked to", func() {
+			os.Setenv("MANIFEST_TEST_DB_HOST", "env.example.com")
+			defer os.Setenv("MANIFEST_TEST_DB_HOST", "")
+
+			m = NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"name": "((MANIFEST_TEST_DB_HOST))",
+			}))
+
+			_, err := m.Applications()
+			Expect(err).To(HaveOccurred())
+
+			m.VariablesFromEnv = true
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*apps[0].Name).To(Equal("env.example.com"))
+		})
+
+		It("prefers the given variables over environment variables", func() {
+			os.Setenv("MANIFEST_TEST_DB_HOST", "env.example.com")
+			defer os.Setenv("MANIFEST_TEST_DB_HOST", "")
+
+			m = NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"name": "((MANIFEST_TEST_DB_HOST))",
+			}))
+			m.Variables = map[string]string{"MANIFEST_TEST_DB_HOST": "vars.example.com"}
+			m.VariablesFromEnv = true
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*apps[0].Name).To(Equal("vars.example.com"))
+		})
+
+		It("parses variables given as name=value pairs", func() {
+			vars, err := manifest.ParseVariables([]string{"name=my-app", "command=bundle exec rake db:migrate VERSION=3"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]string{
+				"name":    "my-app",
+				"command": "bundle exec rake db:migrate VERSION=3",
+			}))
+
+			_, err = manifest.ParseVariables([]string{"just-a-name"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("just-a-name"))
+		})
+	})
+
 	It("sets the command and buildpack to blank when their values are null in the manifest", func() {
 		m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
 			"applications": []interface{}{
@@ -366,6 +492,51 @@ var _ = Describe("Manifests", func() {
 			_, err := m.Applications()
 			Expect(err).To(HaveOccurred())
 		})
+
+		Describe("from an env_file", func() {
+			var dir string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "manifest-env-file")
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(dir, ".env"), []byte("FROM_FILE=file\nOVERRIDDEN=file\n"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("reads the file relative to the manifest, letting env override it", func() {
+				m := NewManifest(filepath.Join(dir, "manifest.yml"), generic.NewMap(map[interface{}]interface{}{
+					"env_file": ".env",
+					"env": map[interface{}]interface{}{
+						"OVERRIDDEN": "manifest",
+						"INLINE":     "manifest",
+					},
+				}))
+
+				apps, err := m.Applications()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*apps[0].EnvironmentVars).To(Equal(map[string]string{
+					"FROM_FILE":  "file",
+					"OVERRIDDEN": "manifest",
+					"INLINE":     "manifest",
+				}))
+			})
+
+			It("returns an error when the file cannot be read", func() {
+				m := NewManifest(filepath.Join(dir, "manifest.yml"), generic.NewMap(map[interface{}]interface{}{
+					"env_file": "missing.env",
+				}))
+
+				_, err := m.Applications()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Cannot read env file " + filepath.Join(dir, "missing.env")))
+			})
+		})
 	})
 
 	Describe("parsing services", func() {
@@ -380,4 +551,66 @@ var _ = Describe("Manifests", func() {
 			Expect(*app[0].ServicesToBind).To(Equal([]string{"service-1", "service-2"}))
 		})
 	})
+
+	Describe("parsing routes", func() {
+		It("can read lists of routes, hosts and domains", func() {
+			m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"routes":  []interface{}{"api.example.com", "example.com"},
+				"hosts":   []interface{}{"www", "web"},
+				"domains": []interface{}{"example.com", "example.org"},
+			}))
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(*apps[0].Routes).To(Equal([]string{"api.example.com", "example.com"}))
+			Expect(*apps[0].Hosts).To(Equal([]string{"www", "web"}))
+			Expect(*apps[0].Domains).To(Equal([]string{"example.com", "example.org"}))
+		})
+
+		It("returns an error when routes is not a list of strings", func() {
+			m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"routes": "api.example.com",
+			}))
+
+			_, err := m.Applications()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Expected routes to be a list of strings."))
+		})
+	})
+
+	Describe("parsing hooks", func() {
+		It("can read the commands to run before and after pushing", func() {
+			m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"before_push": []interface{}{"mvn package"},
+				"after_push":  []interface{}{"./smoke-test.sh", "./notify.sh"},
+			}))
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(*apps[0].BeforePush).To(Equal([]string{"mvn package"}))
+			Expect(*apps[0].AfterPush).To(Equal([]string{"./smoke-test.sh", "./notify.sh"}))
+		})
+	})
+
+	Describe("parsing dependencies", func() {
+		It("can read a list of app names", func() {
+			m := NewManifest("/some/path/manifest.yml", generic.NewMap(map[interface{}]interface{}{
+				"applications": []interface{}{
+					map[interface{}]interface{}{"name": "db-migrator"},
+					map[interface{}]interface{}{
+						"name":       "web",
+						"depends_on": []interface{}{"db-migrator"},
+					},
+				},
+			}))
+
+			apps, err := m.Applications()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(*apps[0].DependsOn).To(BeEmpty())
+			Expect(*apps[1].DependsOn).To(Equal([]string{"db-migrator"}))
+		})
+	})
 })
@@ -0,0 +1,590 @@
+package manifest
+
+import (
+	"bytes"
+	"cf/formatters"
+	"cf/models"
+	"fmt"
+	"generic"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ManifestError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+
+	// Approximate is true when the manifest uses YAML that the line and
+	// column could not be worked out exactly for, such as flow style maps.
+	Approximate bool
+
+	// UnknownKey is true when the error is about a key cf does not know.
+	// Older or newer versions of cf may know it, so push only warns about it.
+	UnknownKey bool
+}
+
+func (err ManifestError) Error() string {
+	switch {
+	case err.Line == 0:
+		return fmt.Sprintf("%s: %s", err.File, err.Message)
+	case err.Approximate:
+		return fmt.Sprintf("%s:%d:%d (approximate position): %s", err.File, err.Line, err.Column, err.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", err.File, err.Line, err.Column, err.Message)
+	}
+}
+
+const (
+	stringKey = iota
+	stringOrNullKey
+	byteSizeKey
+	intKey
+	boolKey
+	stringListKey
+	envKey
+	envFileKey
+	pathKey
+	healthCheckTypeKey
+)
+
+var manifestKeyTypes = map[string]int{
+	"after_push":        stringListKey,
+	"before_push":       stringListKey,
+	"buildpack":         stringOrNullKey,
+	"command":           stringOrNullKey,
+	"depends_on":        stringListKey,
+	"disk_quota":        byteSizeKey,
+	"domain":            stringKey,
+	"domains":           stringListKey,
+	"env":               envKey,
+	"env_file":          envFileKey,
+	"health-check-type": healthCheckTypeKey,
+	"host":              stringKey,
+	"hosts":             stringListKey,
+	"instances":         intKey,
+	"memory":            byteSizeKey,
+	"name":              stringKey,
+	"no-route":          boolKey,
+	"path":              pathKey,
+	"random-route":      boolKey,
+	"routes":            stringListKey,
+	"services":          stringListKey,
+	"stack":             stringKey,
+	"timeout":           intKey,
+}
+
+type appName struct {
+	name     string
+	location ManifestError
+}
+
+// ValidateManifest checks the manifest at inputPath, and the manifests it
+// inherits from, without contacting the API. Every error is a ManifestError
+// giving the file and, where it can be found, the line and column at fault.
+func (repo ManifestDiskRepository) ValidateManifest(inputPath string) (errs []error) {
+	manifestPath, err := repo.manifestPath(inputPath)
+	if err != nil {
+		return []error{ManifestError{File: inputPath, Message: "Error finding manifest: " + err.Error()}}
+	}
+
+	names := []appName{}
+	visited := map[string]bool{}
+
+	for manifestPath != "" {
+		visited[manifestPath] = true
+
+		var fileErrs []error
+		var fileNames []appName
+		var inheritedPath string
+
+		fileNames, inheritedPath, fileErrs = validateManifestFile(manifestPath)
+		errs = append(errs, fileErrs...)
+		names = append(fileNames, names...)
+
+		if visited[inheritedPath] {
+			errs = append(errs, ManifestError{File: manifestPath, Message: "Manifests inherit from each other in a loop"})
+			break
+		}
+		manifestPath = inheritedPath
+	}
+
+	return append(errs, duplicateNameErrors(names)...)
+}
+
+func validateManifestFile(path string) (names []appName, inheritedPath string, errs []error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		errs = append(errs, ManifestError{File: path, Message: "Error reading manifest file: " + err.Error()})
+		return
+	}
+
+	yamlMap, err := parseManifest(bytes.NewReader(data))
+	if err != nil {
+		errs = append(errs, ManifestError{File: path, Message: "Error parsing manifest file: " + err.Error()})
+		return
+	}
+
+	positions, approximate := indexPositions(data)
+	v := manifestValidator{
+		path:        path,
+		positions:   positions,
+		approximate: approximate,
+	}
+
+	// the app path may be made by the before_push hooks, so need not exist yet
+	hasBuildHooks := yamlMap.Has("before_push")
+
+	for _, key := range sortedKeys(yamlMap) {
+		value := yamlMap.Get(key)
+		keyPath := fmt.Sprintf("%v", key)
+
+		switch key {
+		case "applications":
+			names = v.validateApplications(value, hasBuildHooks)
+		case "inherit":
+			inherit, ok := value.(string)
+			if !ok {
+				v.addError(keyPath, "inherit must be a string value")
+				continue
+			}
+
+			if !filepath.IsAbs(inherit) {
+				inherit = filepath.Join(filepath.Dir(path), inherit)
+			}
+			if _, err := os.Stat(inherit); err != nil {
+				v.addError(keyPath, fmt.Sprintf("Cannot read inherited manifest %s", inherit))
+				continue
+			}
+			inheritedPath = inherit
+		default:
+			v.validateAppKey("", key, value, hasBuildHooks)
+		}
+	}
+
+	errs = v.errs
+	return
+}
+
+type manifestValidator struct {
+	path        string
+	positions   map[string]position
+	approximate bool
+	errs        []error
+}
+
+func (v *manifestValidator) location(keyPath string) ManifestError {
+	pos := findPosition(v.positions, keyPath)
+	return ManifestError{File: v.path, Line: pos.line, Column: pos.column, Approximate: v.approximate}
+}
+
+func (v *manifestValidator) addError(keyPath, message string) {
+	err := v.location(keyPath)
+	err.Message = message
+	v.errs = append(v.errs, err)
+}
+
+func (v *manifestValidator) validateApplications(value interface{}, hasBuildHooks bool) (names []appName) {
+	apps, ok := value.([]interface{})
+	if !ok {
+		v.addError("applications", "Expected applications to be a list")
+		return
+	}
+
+	for index, app := range apps {
+		prefix := fmt.Sprintf("applications[%d]", index)
+		if !generic.IsMappable(app) {
+			v.addError(prefix, "Expected application to be a list of key/value pairs")
+			continue
+		}
+
+		appMap := generic.NewMap(app)
+		for _, key := range sortedKeys(appMap) {
+			v.validateAppKey(prefix, key, appMap.Get(key), hasBuildHooks || appMap.Has("before_push"))
+		}
+
+		if name, ok := appMap.Get("name").(string); ok {
+			names = append(names, appName{name: name, location: v.location(joinKeyPath(prefix, "name"))})
+		}
+	}
+
+	return
+}
+
+func (v *manifestValidator) validateAppKey(prefix string, key interface{}, value interface{}, hasBuildHooks bool) {
+	keyPath := joinKeyPath(prefix, key)
+	keyName := fmt.Sprintf("%v", key)
+
+	keyType, known := manifestKeyTypes[keyName]
+	if !known {
+		message := fmt.Sprintf("Unknown key '%s'", keyName)
+		if suggestion := closestKnownKey(keyName); suggestion != "" {
+			message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		err := v.location(keyPath)
+		err.Message = message
+		err.UnknownKey = true
+		v.errs = append(v.errs, err)
+		return
+	}
+
+	if value == nil {
+		if keyType != stringOrNullKey {
+			v.addError(keyPath, fmt.Sprintf("%s should not be null", keyName))
+		}
+		return
+	}
+
+	if str, ok := value.(string); ok && propertyRegex.MatchString(str) {
+		return
+	}
+
+	switch keyType {
+	case stringKey, stringOrNullKey:
+		if _, ok := value.(string); !ok {
+			v.addError(keyPath, fmt.Sprintf("%s must be a string value", keyName))
+		}
+	case healthCheckTypeKey:
+		healthCheckType, ok := value.(string)
+		if !ok || !models.IsValidHealthCheckType(healthCheckType) {
+			v.addError(keyPath, fmt.Sprintf("Expected %s to be one of %s, but it was '%v'", keyName, strings.Join(models.HealthCheckTypes, ", "), value))
+		}
+	case pathKey:
+		path, ok := value.(string)
+		if !ok {
+			v.addError(keyPath, fmt.Sprintf("%s must be a string value", keyName))
+			return
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(v.path), path)
+		}
+		if _, err := os.Stat(path); err != nil && !hasBuildHooks {
+			v.addError(keyPath, fmt.Sprintf("Cannot read the app path %s", path))
+		}
+	case envFileKey:
+		path, ok := value.(string)
+		if !ok {
+			v.addError(keyPath, fmt.Sprintf("%s must be a string value", keyName))
+			return
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(v.path), path)
+		}
+		if _, err := ReadEnvFile(path); err != nil {
+			v.addError(keyPath, err.Error())
+		}
+	case byteSizeKey:
+		size, ok := value.(string)
+		if !ok {
+			v.addError(keyPath, fmt.Sprintf("Expected %s to be a size with a unit, such as 256M or 1G", keyName))
+			return
+		}
+		if _, err := formatters.ToMegabytes(size); err != nil {
+			v.addError(keyPath, fmt.Sprintf("Invalid %s '%s': %s", keyName, size, err.Error()))
+		}
+	case intKey:
+		switch value := value.(type) {
+		case int, int64:
+		case string:
+			if _, err := strconv.Atoi(value); err != nil {
+				v.addError(keyPath, fmt.Sprintf("Expected %s to be a number, but it was '%s'", keyName, value))
+			}
+		default:
+			v.addError(keyPath, fmt.Sprintf("Expected %s to be a number, but it was a %T", keyName, value))
+		}
+	case boolKey:
+		switch value := value.(type) {
+		case bool:
+		case string:
+			if value != "true" && value != "false" {
+				v.addError(keyPath, fmt.Sprintf("Expected %s to be a boolean, but it was '%s'", keyName, value))
+			}
+		default:
+			v.addError(keyPath, fmt.Sprintf("Expected %s to be a boolean", keyName))
+		}
+	case stringListKey:
+		list, ok := value.([]interface{})
+		if !ok {
+			v.addError(keyPath, fmt.Sprintf("Expected %s to be a list of strings", keyName))
+			return
+		}
+		for index, item := range list {
+			if _, ok := item.(string); !ok {
+				v.addError(fmt.Sprintf("%s[%d]", keyPath, index), fmt.Sprintf("Expected %s to be a list of strings", keyName))
+			}
+		}
+	case envKey:
+		if !generic.IsMappable(value) {
+			v.addError(keyPath, "Expected env to be a set of key/value pairs")
+			return
+		}
+		env := generic.NewMap(value)
+		for _, name := range sortedKeys(env) {
+			switch env.Get(name).(type) {
+			case string, int, int64, float64:
+			default:
+				v.addError(joinKeyPath(keyPath, name), fmt.Sprintf("Expected environment variable %v to have a string value", name))
+			}
+		}
+	}
+}
+
+func duplicateNameErrors(names []appName) (errs []error) {
+	firstSeen := map[string]ManifestError{}
+	for _, name := range names {
+		first, found := firstSeen[name.name]
+		if !found {
+			firstSeen[name.name] = name.location
+			continue
+		}
+
+		err := name.location
+		err.Message = fmt.Sprintf("Application name '%s' is used more than once, first at %s:%d", name.name, first.File, first.Line)
+		if first.Approximate {
+			err.Message += " (approximate position)"
+		}
+		errs = append(errs, err)
+	}
+	return
+}
+
+func closestKnownKey(key string) (closest string) {
+	bestDistance := 3
+	for _, knownKey := range append(sortedKnownKeys(), "applications", "inherit") {
+		distance := editDistance(key, knownKey)
+		if distance < bestDistance {
+			bestDistance = distance
+			closest = knownKey
+		}
+	}
+	return
+}
+
+func sortedKnownKeys() (keys []string) {
+	for key := range manifestKeyTypes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
+
+func sortedKeys(yamlMap generic.Map) []interface{} {
+	keys := yamlMap.Keys()
+	sort.Sort(keysByName(keys))
+	return keys
+}
+
+type keysByName []interface{}
+
+func (keys keysByName) Len() int      { return len(keys) }
+func (keys keysByName) Swap(i, j int) { keys[i], keys[j] = keys[j], keys[i] }
+func (keys keysByName) Less(i, j int) bool {
+	return fmt.Sprintf("%v", keys[i]) < fmt.Sprintf("%v", keys[j])
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+// findPosition returns the position of keyPath, or of the closest enclosing
+// key when keyPath itself was not found, e.g. for items of inline lists.
+func findPosition(positions map[string]position, keyPath string) position {
+	for keyPath != "" {
+		if pos, found := positions[keyPath]; found {
+			return pos
+		}
+
+		cut := strings.LastIndexAny(keyPath, ".[")
+		if cut < 0 {
+			break
+		}
+		keyPath = keyPath[:cut]
+	}
+	return position{}
+}
+
+type yamlFrame struct {
+	indent int
+	path   string
+	isList bool
+	count  int
+}
+
+// indexPositions finds the line and column of every key and list item in a
+// block style YAML document, using the same key paths as expandProperties.
+// candiedyaml keeps the events of its parser and their marks unexported, so
+// the positions cannot be taken from the decoder; once it exports them, this
+// scanner should be replaced by one that reads them. Until then, approximate
+// is true when the document uses YAML the scanner does not follow, and the
+// positions it found may be wrong.
+func indexPositions(data []byte) (positions map[string]position, approximate bool) {
+	positions = map[string]position{}
+	frames := []*yamlFrame{{indent: 0}}
+
+	var pending *yamlFrame
+	blockScalarIndent := -1
+
+	for lineIndex, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+
+		if blockScalarIndent >= 0 {
+			if content == "" || indent > blockScalarIndent {
+				continue
+			}
+			blockScalarIndent = -1
+		}
+
+		if content == "---" && len(positions) > 0 {
+			approximate = true
+		}
+
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+
+		if confusesScanner(content, false) {
+			approximate = true
+		}
+
+		for {
+			isItem := content == "-" || strings.HasPrefix(content, "- ")
+
+			if pending != nil {
+				if (isItem && indent >= pending.indent) || (!isItem && indent > pending.indent) {
+					frames = append(frames, &yamlFrame{indent: indent, path: pending.path, isList: isItem})
+				}
+				pending = nil
+			}
+
+			for len(frames) > 1 {
+				top := frames[len(frames)-1]
+				if top.indent < indent || (top.indent == indent && top.isList == isItem) {
+					break
+				}
+				frames = frames[:len(frames)-1]
+			}
+			top := frames[len(frames)-1]
+
+			if isItem {
+				if !top.isList {
+					break
+				}
+
+				itemPath := fmt.Sprintf("%s[%d]", top.path, top.count)
+				top.count++
+				positions[itemPath] = position{line: lineIndex + 1, column: indent + 1}
+
+				rest := strings.TrimLeft(strings.TrimPrefix(content, "-"), " ")
+				if confusesScanner(rest, false) {
+					approximate = true
+				}
+				if rest == "" || !isYAMLKey(rest) {
+					if rest == "" {
+						pending = &yamlFrame{indent: indent, path: itemPath}
+					}
+					break
+				}
+
+				indent = indent + len(content) - len(rest)
+				content = rest
+				frames = append(frames, &yamlFrame{indent: indent, path: itemPath})
+				continue
+			}
+
+			if top.isList || !isYAMLKey(content) {
+				break
+			}
+
+			colon := strings.Index(content, ":")
+			key := strings.Trim(content[:colon], `"'`)
+			value := strings.TrimSpace(content[colon+1:])
+			keyPath := joinKeyPath(top.path, key)
+			positions[keyPath] = position{line: lineIndex + 1, column: indent + 1}
+
+			if confusesScanner(value, true) {
+				approximate = true
+			}
+
+			switch {
+			case value == "" || strings.HasPrefix(value, "#"):
+				pending = &yamlFrame{indent: indent, path: keyPath}
+			case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+				blockScalarIndent = indent
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// confusesScanner is true when a line, or the value of a key, starts with
+// YAML that indexPositions does not follow: flow style maps and lists,
+// complex keys, anchors, aliases, tags, quoted strings that go on over
+// several lines, and quoted keys with a colon in them.
+func confusesScanner(content string, isValue bool) bool {
+	if content == "" {
+		return false
+	}
+
+	if !isValue && (content == "?" || strings.HasPrefix(content, "? ") || strings.HasPrefix(content, "<<")) {
+		return true
+	}
+
+	switch content[0] {
+	case '{', '[', '&', '*', '!':
+		return true
+	case '"', '\'':
+		closingQuote := strings.IndexByte(content[1:], content[0])
+		return closingQuote < 0 || (!isValue && strings.Contains(content[1:closingQuote+1], ":"))
+	}
+	return false
+}
+
+func isYAMLKey(content string) bool {
+	colon := strings.Index(content, ":")
+	if colon <= 0 {
+		return false
+	}
+	return colon == len(content)-1 || content[colon+1] == ' '
+}
@@ -0,0 +1,156 @@
+package manifest_test
+
+import (
+	. "cf/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("validating manifests", func() {
+	var (
+		repo ManifestRepository
+		path string
+	)
+
+	BeforeEach(func() {
+		repo = NewManifestDiskRepository()
+		path = filepath.Clean("../../fixtures/manifests/invalid/manifest.yml")
+	})
+
+	errorMessages := func(errs []error) (messages []string) {
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return
+	}
+
+	It("reports each problem with the file, line and column it was found at", func() {
+		errs := repo.ValidateManifest(path)
+
+		Expect(errorMessages(errs)).To(Equal([]string{
+			path + ":5:3: Unknown key 'instance', did you mean 'instances'?",
+			path + ":6:3: Expected memory to be a size with a unit, such as 256M or 1G",
+			path + ":7:3: Cannot read the app path " + filepath.Join(filepath.Dir(path), "does-not-exist"),
+			path + ":9:3: Invalid disk_quota 'lots': Byte quantity must be a positive integer with a unit of measurement like M, MB, G, or GB",
+			path + ":14:3: Expected health-check-type to be one of port, none, but it was 'http'",
+			path + ":13:3: Expected no-route to be a boolean, but it was 'maybe'",
+			path + ":10:3: Expected timeout to be a number, but it was 'soon'",
+			path + ":15:3: Application name 'base-app' is used more than once, first at " +
+				filepath.Join(filepath.Dir(path), "../base-manifest.yml") + ":8",
+		}))
+	})
+
+	It("returns ManifestErrors with the position of the problem", func() {
+		errs := repo.ValidateManifest(path)
+
+		err := errs[0].(ManifestError)
+		Expect(err.File).To(Equal(path))
+		Expect(err.Line).To(Equal(5))
+		Expect(err.Column).To(Equal(3))
+	})
+
+	It("marks the errors about unknown keys, which push only warns about", func() {
+		errs := repo.ValidateManifest(path)
+
+		Expect(errs[0].(ManifestError).UnknownKey).To(BeTrue())
+		Expect(errs[1].(ManifestError).UnknownKey).To(BeFalse())
+	})
+
+	It("allows variables and null commands", func() {
+		errs := repo.ValidateManifest("../../fixtures/manifests/invalid/valid-manifest.yml")
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("follows the inherit chain of valid manifests", func() {
+		errs := repo.ValidateManifest("../../fixtures/manifests/inherited-manifest.yml")
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("reports inherited manifests that cannot be read", func() {
+		dir, err := ioutil.TempDir("", "validate-manifest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		manifestPath := filepath.Join(dir, "manifest.yml")
+		err = ioutil.WriteFile(manifestPath, []byte("---\ninherit: missing.yml\napplications:\n- name: my-app\n"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		errs := repo.ValidateManifest(manifestPath)
+		Expect(errorMessages(errs)).To(Equal([]string{
+			manifestPath + ":2:1: Cannot read inherited manifest " + filepath.Join(dir, "missing.yml"),
+		}))
+	})
+
+	It("allows app paths that do not exist yet when before_push commands may build them", func() {
+		dir, err := ioutil.TempDir("", "validate-manifest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		manifestPath := filepath.Join(dir, "manifest.yml")
+		err = ioutil.WriteFile(manifestPath, []byte("---\napplications:\n- name: my-app\n  path: target/my-app.jar\n  before_push:\n  - mvn package\n"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		errs := repo.ValidateManifest(manifestPath)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("reports env files that cannot be parsed", func() {
+		dir, err := ioutil.TempDir("", "validate-manifest")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, ".env"), []byte("GOOD=1\nBAD\n"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		manifestPath := filepath.Join(dir, "manifest.yml")
+		err = ioutil.WriteFile(manifestPath, []byte("---\napplications:\n- name: my-app\n  env_file: .env\n"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		errs := repo.ValidateManifest(manifestPath)
+		Expect(errorMessages(errs)).To(Equal([]string{
+			manifestPath + ":4:3: " + filepath.Join(dir, ".env") + ":2: Expected NAME=VALUE, but found 'BAD'",
+		}))
+	})
+
+	Describe("positions in YAML that they cannot be worked out exactly for", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "validate-manifest")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		validate := func(contents string) []string {
+			manifestPath := filepath.Join(dir, "manifest.yml")
+			err := ioutil.WriteFile(manifestPath, []byte(contents), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+			return errorMessages(repo.ValidateManifest(manifestPath))
+		}
+
+		It("labels them as approximate when the manifest uses flow style maps", func() {
+			Expect(validate("---\napplications:\n- {name: my-app, instance: 2}\n")).To(Equal([]string{
+				filepath.Join(dir, "manifest.yml") + ":3:1 (approximate position): Unknown key 'instance', did you mean 'instances'?",
+			}))
+		})
+
+		It("does not label them as approximate because of quoted values", func() {
+			Expect(validate("---\napplications:\n- name: my-app\n  command: \"bundle exec rake: run\"\n  instance: 2\n")).To(Equal([]string{
+				filepath.Join(dir, "manifest.yml") + ":5:3: Unknown key 'instance', did you mean 'instances'?",
+			}))
+		})
+	})
+
+	It("returns an error when the manifest cannot be found", func() {
+		errs := repo.ValidateManifest("some/path/that/doesnt/exist/manifest.yml")
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(ContainSubstring("some/path/that/doesnt/exist/manifest.yml"))
+	})
+})
@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"cf/errors"
+	"fmt"
+	"generic"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func ReadVariablesFile(path string) (vars map[string]string, err error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		err = errors.NewWithError("Error reading vars file "+path, err)
+		return
+	}
+	defer file.Close()
+
+	yamlMap, err := parseManifest(file)
+	if err != nil {
+		err = errors.NewWithError("Error reading vars file "+path, err)
+		return
+	}
+
+	vars = map[string]string{}
+	generic.Each(yamlMap, func(key, value interface{}) {
+		if err != nil {
+			return
+		}
+
+		switch value.(type) {
+		case string, bool, int, int64, float64:
+			vars[fmt.Sprintf("%v", key)] = fmt.Sprintf("%v", value)
+		default:
+			err = errors.NewWithFmt("Error reading vars file %s:\nExpected the value of %v to be a string, number or boolean", path, key)
+		}
+	})
+
+	return
+}
+
+func ParseVariables(pairs []string) (vars map[string]string, err error) {
+	vars = map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			err = errors.NewWithFmt("Invalid variable '%s'. Expected it to look like name=value", pair)
+			return
+		}
+		vars[parts[0]] = parts[1]
+	}
+	return
+}
+
+func (m Manifest) lookupVariable(name string) (value string, found bool) {
+	value, found = m.Variables[name]
+	if found || !m.VariablesFromEnv {
+		return
+	}
+
+	value = os.Getenv(name)
+	found = value != ""
+	return
+}
+
+func (m Manifest) unresolvedVariableError(property, keyPath string) error {
+	if m.Path == "" {
+		return errors.NewWithFmt("No value found for '%s' at %s", property, keyPath)
+	}
+	return errors.NewWithFmt("No value found for '%s' at %s in %s", property, keyPath, m.Path)
+}
+
+func joinKeyPath(keyPath string, key interface{}) string {
+	if keyPath == "" {
+		return fmt.Sprintf("%v", key)
+	}
+	return fmt.Sprintf("%s.%v", keyPath, key)
+}
@@ -5,3 +5,9 @@ type AppFileFields struct {
 	Sha1 string
 	Size int64
 }
+
+// AppInstanceFile is an entry in the listing of a directory in an app instance.
+type AppInstanceFile struct {
+	Name  string
+	IsDir bool
+}
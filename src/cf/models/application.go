@@ -5,10 +5,29 @@ import (
 	"strings"
 )
 
+// Health check types say how the platform decides an app instance is
+// running: once it listens on its port, or as soon as it has started.
+const (
+	HealthCheckTypePort = "port"
+	HealthCheckTypeNone = "none"
+)
+
+var HealthCheckTypes = []string{HealthCheckTypePort, HealthCheckTypeNone}
+
+func IsValidHealthCheckType(healthCheckType string) bool {
+	for _, validType := range HealthCheckTypes {
+		if healthCheckType == validType {
+			return true
+		}
+	}
+	return false
+}
+
 type Application struct {
 	ApplicationFields
-	Stack  *Stack
-	Routes []RouteSummary
+	Stack    *Stack
+	Routes   []RouteSummary
+	Services []ServiceInstanceFields
 }
 
 func (model Application) HasRoute(route Route) bool {
@@ -20,6 +39,15 @@ func (model Application) HasRoute(route Route) bool {
 	return false
 }
 
+func (model Application) HasService(name string) bool {
+	for _, service := range model.Services {
+		if service.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (model Application) ToParams() (params AppParams) {
 	state := strings.ToUpper(model.State)
 	params = AppParams{
@@ -35,6 +63,14 @@ func (model Application) ToParams() (params AppParams) {
 		EnvironmentVars: &model.EnvironmentVars,
 	}
 
+	if model.HealthCheckType != "" {
+		params.HealthCheckType = &model.HealthCheckType
+	}
+
+	if model.HealthCheckTimeout != 0 {
+		params.HealthCheckTimeout = &model.HealthCheckTimeout
+	}
+
 	if model.Stack != nil {
 		params.StackGuid = &model.Stack.Guid
 	}
@@ -43,34 +79,44 @@ func (model Application) ToParams() (params AppParams) {
 }
 
 type ApplicationFields struct {
-	Guid             string
-	Name             string
-	BuildpackUrl     string
-	Command          string
-	DiskQuota        uint64 // in Megabytes
-	EnvironmentVars  map[string]string
-	InstanceCount    int
-	Memory           uint64 // in Megabytes
-	RunningInstances int
-	State            string
-	SpaceGuid        string
+	Guid            string
+	Name            string
+	BuildpackUrl    string
+	Command         string
+	DiskQuota       uint64 // in Megabytes
+	EnvironmentVars map[string]string
+	HealthCheckType string
+	// HealthCheckTimeout is in seconds, and 0 when it is not set.
+	HealthCheckTimeout int
+	InstanceCount      int
+	Memory             uint64 // in Megabytes
+	RunningInstances   int
+	State              string
+	SpaceGuid          string
 }
 
 type AppParams struct {
+	AfterPush          *[]string
+	BeforePush         *[]string
 	BuildpackUrl       *string
 	Command            *string
+	DependsOn          *[]string
 	DiskQuota          *uint64
 	Domain             *string
+	Domains            *[]string
 	EnvironmentVars    *map[string]string
 	Guid               *string
 	HealthCheckTimeout *int
+	HealthCheckType    *string
 	Host               *string
+	Hosts              *[]string
 	InstanceCount      *int
 	Memory             *uint64
 	Name               *string
 	NoRoute            bool
 	UseRandomHostname  bool
 	Path               *string
+	Routes             *[]string
 	ServicesToBind     *[]string
 	SpaceGuid          *string
 	StackGuid          *string
@@ -79,18 +125,30 @@ type AppParams struct {
 }
 
 func (app *AppParams) Merge(other *AppParams) {
+	if other.AfterPush != nil {
+		app.AfterPush = other.AfterPush
+	}
+	if other.BeforePush != nil {
+		app.BeforePush = other.BeforePush
+	}
 	if other.BuildpackUrl != nil {
 		app.BuildpackUrl = other.BuildpackUrl
 	}
 	if other.Command != nil {
 		app.Command = other.Command
 	}
+	if other.DependsOn != nil {
+		app.DependsOn = other.DependsOn
+	}
 	if other.DiskQuota != nil {
 		app.DiskQuota = other.DiskQuota
 	}
 	if other.Domain != nil {
 		app.Domain = other.Domain
 	}
+	if other.Domains != nil {
+		app.Domains = other.Domains
+	}
 	if other.EnvironmentVars != nil {
 		app.EnvironmentVars = other.EnvironmentVars
 	}
@@ -100,9 +158,15 @@ func (app *AppParams) Merge(other *AppParams) {
 	if other.HealthCheckTimeout != nil {
 		app.HealthCheckTimeout = other.HealthCheckTimeout
 	}
+	if other.HealthCheckType != nil {
+		app.HealthCheckType = other.HealthCheckType
+	}
 	if other.Host != nil {
 		app.Host = other.Host
 	}
+	if other.Hosts != nil {
+		app.Hosts = other.Hosts
+	}
 	if other.InstanceCount != nil {
 		app.InstanceCount = other.InstanceCount
 	}
@@ -118,6 +182,9 @@ func (app *AppParams) Merge(other *AppParams) {
 	if other.Path != nil {
 		app.Path = other.Path
 	}
+	if other.Routes != nil {
+		app.Routes = other.Routes
+	}
 	if other.ServicesToBind != nil {
 		app.ServicesToBind = other.ServicesToBind
 	}
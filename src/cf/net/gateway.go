@@ -206,11 +206,18 @@ func (gateway Gateway) NewRequest(method, path, accessToken string, body io.Read
 }
 
 func (gateway Gateway) PerformRequest(request *Request) (rawResponse *http.Response, apiErr error) {
-	return gateway.doRequestHandlingAuth(request)
+	return gateway.doRequestHandlingAuth(request, true)
+}
+
+// PerformStreamingRequest is like PerformRequest, but leaves the response
+// body out of the trace, so that large bodies such as downloaded files are
+// read as they arrive rather than held in memory first.
+func (gateway Gateway) PerformStreamingRequest(request *Request) (rawResponse *http.Response, apiErr error) {
+	return gateway.doRequestHandlingAuth(request, false)
 }
 
 func (gateway Gateway) performRequestForResponseBytes(request *Request) (bytes []byte, headers http.Header, rawResponse *http.Response, apiErr error) {
-	rawResponse, apiErr = gateway.doRequestHandlingAuth(request)
+	rawResponse, apiErr = gateway.doRequestHandlingAuth(request, true)
 	if apiErr != nil {
 		return
 	}
@@ -325,7 +332,7 @@ func (gateway Gateway) waitForJob(jobUrl, accessToken string, timeout time.Durat
 	return
 }
 
-func (gateway Gateway) doRequestHandlingAuth(request *Request) (rawResponse *http.Response, err error) {
+func (gateway Gateway) doRequestHandlingAuth(request *Request, dumpBody bool) (rawResponse *http.Response, err error) {
 	httpReq := request.HttpReq
 
 	if request.SeekableBody != nil {
@@ -333,7 +340,7 @@ func (gateway Gateway) doRequestHandlingAuth(request *Request) (rawResponse *htt
 	}
 
 	// perform request
-	rawResponse, err = gateway.doRequestAndHandlerError(request)
+	rawResponse, err = gateway.doRequestAndHandlerError(request, dumpBody)
 	if err == nil || gateway.authenticator == nil {
 		return
 	}
@@ -355,14 +362,14 @@ func (gateway Gateway) doRequestHandlingAuth(request *Request) (rawResponse *htt
 		}
 
 		// make the request again
-		rawResponse, err = gateway.doRequestAndHandlerError(request)
+		rawResponse, err = gateway.doRequestAndHandlerError(request, dumpBody)
 	}
 
 	return
 }
 
-func (gateway Gateway) doRequestAndHandlerError(request *Request) (rawResponse *http.Response, err error) {
-	rawResponse, err = gateway.doRequest(request.HttpReq)
+func (gateway Gateway) doRequestAndHandlerError(request *Request, dumpBody bool) (rawResponse *http.Response, err error) {
+	rawResponse, err = gateway.doRequest(request.HttpReq, dumpBody)
 	if err != nil {
 		err = WrapNetworkErrors(request.HttpReq.URL.Host, err)
 		return
@@ -378,7 +385,7 @@ func (gateway Gateway) doRequestAndHandlerError(request *Request) (rawResponse *
 	return
 }
 
-func (gateway Gateway) doRequest(request *http.Request) (response *http.Response, err error) {
+func (gateway Gateway) doRequest(request *http.Request, dumpBody bool) (response *http.Response, err error) {
 	httpClient := newHttpClient(gateway.trustedCerts, gateway.config.IsSSLDisabled())
 
 	dumpRequest(request)
@@ -388,7 +395,7 @@ func (gateway Gateway) doRequest(request *http.Request) (response *http.Response
 		return
 	}
 
-	dumpResponse(response)
+	dumpResponse(response, dumpBody)
 
 	header := http.CanonicalHeaderKey("X-Cf-Warnings")
 	raw_warnings := response.Header[header]
@@ -26,11 +26,13 @@
 package net_test
 
 import (
+	"bytes"
 	"cf"
 	"cf/api"
 	"cf/configuration"
 	"cf/errors"
 	. "cf/net"
+	"cf/trace"
 	"crypto/tls"
 	"fmt"
 	. "github.com/onsi/ginkgo"
@@ -70,6 +72,58 @@ var _ = Describe("Gateway", func() {
 		Expect(request.HttpReq.Header.Get("User-Agent")).To(Equal("go-cli " + cf.Version + " / " + runtime.GOOS))
 	})
 
+	Describe("streaming a response", func() {
+		var (
+			apiServer *httptest.Server
+			traceLog  *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			apiServer = httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "application/octet-stream")
+				fmt.Fprint(writer, "the contents of a very large file")
+			}))
+			ccGateway.SetTrustedCerts(apiServer.TLS.Certificates)
+
+			traceLog = &bytes.Buffer{}
+			trace.SetStdout(traceLog)
+			trace.EnableTrace()
+		})
+
+		AfterEach(func() {
+			trace.DisableTrace()
+			trace.SetStdout(os.Stdout)
+			apiServer.Close()
+		})
+
+		It("leaves the body out of the trace so that the caller reads all of it", func() {
+			request, apiErr := ccGateway.NewRequest("GET", apiServer.URL+"/v2/apps/my-app-guid/instances/0/files/app/big.file", "BEARER my-access-token", nil)
+			Expect(apiErr).NotTo(HaveOccurred())
+
+			response, apiErr := ccGateway.PerformStreamingRequest(request)
+			Expect(apiErr).NotTo(HaveOccurred())
+			defer response.Body.Close()
+
+			body, err := ioutil.ReadAll(response.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal("the contents of a very large file"))
+
+			Expect(traceLog.String()).To(ContainSubstring("application/octet-stream"))
+			Expect(traceLog.String()).To(ContainSubstring("[STREAMED RESPONSE BODY HIDDEN]"))
+			Expect(traceLog.String()).NotTo(ContainSubstring("the contents of a very large file"))
+		})
+
+		It("puts the body in the trace of other requests", func() {
+			request, apiErr := ccGateway.NewRequest("GET", apiServer.URL+"/v2/foo", "BEARER my-access-token", nil)
+			Expect(apiErr).NotTo(HaveOccurred())
+
+			_, apiErr = ccGateway.PerformRequest(request)
+			Expect(apiErr).NotTo(HaveOccurred())
+
+			Expect(traceLog.String()).To(ContainSubstring("the contents of a very large file"))
+		})
+	})
+
 	Describe("making an async request", func() {
 		var jobStatus string
 		var apiServer *httptest.Server
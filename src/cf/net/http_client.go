@@ -78,12 +78,15 @@ func dumpRequest(req *http.Request) {
 	}
 }
 
-func dumpResponse(res *http.Response) {
-	dumpedResponse, err := httputil.DumpResponse(res, true)
+func dumpResponse(res *http.Response, shouldDisplayBody bool) {
+	dumpedResponse, err := httputil.DumpResponse(res, shouldDisplayBody)
 	if err != nil {
 		trace.Logger.Printf("Error dumping response\n%s\n", err)
 	} else {
 		trace.Logger.Printf("\n%s [%s]\n%s\n", terminal.HeaderColor("RESPONSE:"), time.Now().Format(time.RFC3339), Sanitize(string(dumpedResponse)))
+		if !shouldDisplayBody {
+			trace.Logger.Println("[STREAMED RESPONSE BODY HIDDEN]")
+		}
 	}
 }
 
@@ -107,6 +110,7 @@ func WrapNetworkErrors(host string, err error) error {
 		case *net.OpError:
 			return typedErr.Err
 		}
+		return errors.NewNetworkError(err)
 	}
 
 	return errors.NewWithError("Error performing request", err)
@@ -259,6 +259,14 @@ Server: Apache-Coyote/1.1
 			Expect(err).To(Equal(underlyingErr))
 		})
 
+		It("wraps other http errors in a network error type", func() {
+			err := WrapNetworkErrors("example.com", &url.Error{Op: "Put", URL: "https://example.com", Err: errors.New("unexpected EOF")})
+			Expect(err.Error()).To(ContainSubstring("unexpected EOF"))
+
+			_, ok := err.(*errors.NetworkError)
+			Expect(ok).To(BeTrue())
+		})
+
 		It("wraps other errors in a generic error type", func() {
 			err := WrapNetworkErrors("example.com", errors.New("whatever"))
 			Expect(err).To(HaveOccurred())
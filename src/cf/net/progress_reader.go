@@ -0,0 +1,66 @@
+package net
+
+import (
+	"io"
+	"sync"
+)
+
+// ProgressCallback is told how many bytes of a request body have been sent.
+type ProgressCallback func(sent, total int64)
+
+// ProgressReader reports the progress of reading a request body. Seeking back
+// to the start, as the gateway does when it sends a request again, starts the
+// count again.
+type ProgressReader struct {
+	body       io.ReadSeeker
+	total      int64
+	onProgress ProgressCallback
+
+	lock sync.Mutex
+	sent int64
+}
+
+func NewProgressReader(body io.ReadSeeker, total int64, onProgress ProgressCallback) *ProgressReader {
+	return &ProgressReader{
+		body:       body,
+		total:      total,
+		onProgress: onProgress,
+	}
+}
+
+func (reader *ProgressReader) Read(p []byte) (n int, err error) {
+	n, err = reader.body.Read(p)
+
+	reader.lock.Lock()
+	reader.sent += int64(n)
+	sent := reader.sent
+	reader.lock.Unlock()
+
+	if n > 0 && reader.onProgress != nil {
+		reader.onProgress(sent, reader.total)
+	}
+	return
+}
+
+func (reader *ProgressReader) Seek(offset int64, whence int) (position int64, err error) {
+	position, err = reader.body.Seek(offset, whence)
+	if err != nil {
+		return
+	}
+
+	reader.lock.Lock()
+	reader.sent = position
+	reader.lock.Unlock()
+	return
+}
+
+// SetProgressCallback makes request report the progress of sending its body
+// to onProgress. It does nothing when onProgress is nil or the request has no
+// body.
+func (request *Request) SetProgressCallback(onProgress ProgressCallback) {
+	if onProgress == nil || request.SeekableBody == nil {
+		return
+	}
+
+	request.SeekableBody = NewProgressReader(request.SeekableBody, request.HttpReq.ContentLength, onProgress)
+}
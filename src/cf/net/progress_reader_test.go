@@ -0,0 +1,43 @@
+package net_test
+
+import (
+	. "cf/net"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"strings"
+)
+
+var _ = Describe("ProgressReader", func() {
+	var (
+		reader *ProgressReader
+		sent   []int64
+	)
+
+	BeforeEach(func() {
+		sent = []int64{}
+		reader = NewProgressReader(strings.NewReader("hello world"), 11, func(s, total int64) {
+			Expect(total).To(Equal(int64(11)))
+			sent = append(sent, s)
+		})
+	})
+
+	It("reports the bytes read so far", func() {
+		buffer := make([]byte, 5)
+		reader.Read(buffer)
+		reader.Read(buffer)
+		reader.Read(buffer)
+
+		Expect(sent).To(Equal([]int64{5, 10, 11}))
+	})
+
+	It("counts from where it was seeked to", func() {
+		ioutil.ReadAll(reader)
+		reader.Seek(0, 0)
+
+		buffer := make([]byte, 5)
+		reader.Read(buffer)
+
+		Expect(sent).To(Equal([]int64{11, 5}))
+	})
+})
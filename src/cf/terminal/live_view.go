@@ -0,0 +1,81 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LiveView shows output that changes over time, such as a dashboard. The
+// lines said since the last Draw make up a frame, which Draw shows in place
+// of the one before.
+type LiveView interface {
+	Say(message string, args ...interface{})
+	Table(headers []string) Table
+	Draw()
+	Close()
+}
+
+type liveView struct {
+	ui  UI
+	tty io.Writer
+
+	lines      []string
+	drawnLines int
+	frames     int
+}
+
+// NewLiveView returns a view that is redrawn in place on tty. When tty is
+// nil, each frame is said through ui instead, with a blank line between them.
+func NewLiveView(ui UI, tty io.Writer) LiveView {
+	return &liveView{ui: ui, tty: tty}
+}
+
+func (view *liveView) Say(message string, args ...interface{}) {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	view.lines = append(view.lines, strings.Split(message, "\n")...)
+}
+
+func (view *liveView) Table(headers []string) Table {
+	return NewTable(view, headers)
+}
+
+func (view *liveView) Draw() {
+	lines := view.lines
+	view.lines = nil
+
+	if view.tty == nil {
+		if view.frames > 0 {
+			view.ui.Say("")
+		}
+		view.ui.Say(strings.Join(lines, "\n"))
+		view.frames++
+		return
+	}
+
+	output := ""
+	if view.frames == 0 {
+		// hide the cursor while the view is being redrawn
+		output += "\033[?25l"
+	} else if view.drawnLines > 0 {
+		output += fmt.Sprintf("\033[%dA", view.drawnLines)
+	}
+
+	for _, line := range lines {
+		output += "\r" + line + "\033[K\n"
+	}
+	output += "\033[J"
+
+	fmt.Fprint(view.tty, output)
+	view.drawnLines = len(lines)
+	view.frames++
+}
+
+// Close leaves the last frame on the screen and shows the cursor again.
+func (view *liveView) Close() {
+	if view.tty != nil && view.frames > 0 {
+		fmt.Fprint(view.tty, "\033[?25h")
+	}
+}
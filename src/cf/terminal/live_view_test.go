@@ -0,0 +1,77 @@
+package terminal_test
+
+import (
+	"bytes"
+	. "cf/terminal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("live view", func() {
+	var ui *testterm.FakeUI
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+	})
+
+	Context("when writing to a terminal", func() {
+		var tty *bytes.Buffer
+
+		BeforeEach(func() {
+			tty = &bytes.Buffer{}
+		})
+
+		It("draws each frame over the one before", func() {
+			view := NewLiveView(ui, tty)
+			view.Say("first\nframe")
+			view.Draw()
+
+			Expect(tty.String()).To(Equal("\033[?25l\rfirst\033[K\n\rframe\033[K\n\033[J"))
+
+			tty.Reset()
+			view.Say("second")
+			view.Draw()
+
+			Expect(tty.String()).To(Equal("\033[2A\rsecond\033[K\n\033[J"))
+			Expect(ui.Outputs).To(BeEmpty())
+		})
+
+		It("shows the cursor again when it is closed", func() {
+			view := NewLiveView(ui, tty)
+			view.Say("frame")
+			view.Draw()
+			view.Close()
+
+			Expect(tty.String()).To(HaveSuffix("\033[?25h"))
+		})
+	})
+
+	Context("when not writing to a terminal", func() {
+		It("says each frame, with a blank line between them", func() {
+			view := NewLiveView(ui, nil)
+			view.Say("first")
+			view.Draw()
+			view.Say("second")
+			view.Draw()
+			view.Close()
+
+			Expect(ui.Outputs).To(Equal([]string{"first", "", "second"}))
+		})
+	})
+
+	It("prints tables into the frame", func() {
+		view := NewLiveView(ui, nil)
+		view.Table([]string{"name", "trend"}).Print([][]string{
+			{"cpu", "▁▃▅█"},
+			{"memory-usage", "▁"},
+		})
+		view.Draw()
+
+		Expect(ui.Outputs).To(Equal([]string{
+			"name           trend   ",
+			"cpu            ▁▃▅█   ",
+			"memory-usage   ▁   ",
+		}))
+	})
+})
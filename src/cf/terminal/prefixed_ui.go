@@ -0,0 +1,157 @@
+package terminal
+
+import (
+	"cf/configuration"
+	"cf/trace"
+	"fmt"
+	"github.com/codegangsta/cli"
+	"strings"
+	"sync"
+	"time"
+)
+
+type prefixedUI struct {
+	ui     UI
+	prefix string
+	lock   sync.Locker
+}
+
+// NewPrefixedUI returns a UI that writes every line of output through ui with
+// prefix in front of it. UIs that share the same lock can be used from several
+// goroutines at once without their lines getting mixed up.
+func NewPrefixedUI(ui UI, prefix string, lock sync.Locker) UI {
+	return prefixedUI{ui: ui, prefix: prefix, lock: lock}
+}
+
+func (p prefixedUI) PrintPaginator(rows []string, err error) {
+	if err != nil {
+		p.Failed(err.Error())
+		return
+	}
+
+	for _, row := range rows {
+		p.Say(row)
+	}
+}
+
+func (p prefixedUI) Say(message string, args ...interface{}) {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.say(message)
+}
+
+func (p prefixedUI) say(message string) {
+	for _, line := range strings.Split(message, "\n") {
+		p.ui.Say("%s", p.prefix+line)
+	}
+}
+
+func (p prefixedUI) Warn(message string, args ...interface{}) {
+	message = fmt.Sprintf(message, args...)
+	p.Say(WarningColor(message))
+}
+
+func (p prefixedUI) Ask(prompt string, args ...interface{}) (answer string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ui.Ask(p.prefix+prompt, args...)
+}
+
+func (p prefixedUI) AskForPassword(prompt string, args ...interface{}) (answer string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ui.AskForPassword(p.prefix+prompt, args...)
+}
+
+func (p prefixedUI) Confirm(message string, args ...interface{}) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ui.Confirm(p.prefix+message, args...)
+}
+
+func (p prefixedUI) ConfirmDelete(modelType, modelName string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ui.ConfirmDelete(modelType, modelName)
+}
+
+func (p prefixedUI) ConfirmDeleteWithAssociations(modelType, modelName string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ui.ConfirmDeleteWithAssociations(modelType, modelName)
+}
+
+func (p prefixedUI) Ok() {
+	p.Say(SuccessColor("OK"))
+}
+
+func (p prefixedUI) Failed(message string, args ...interface{}) {
+	message = fmt.Sprintf(message, args...)
+
+	p.lock.Lock()
+	p.say(FailureColor("FAILED"))
+	p.say(message)
+	p.lock.Unlock()
+
+	trace.Logger.Print("FAILED")
+	trace.Logger.Print(message)
+	panic(FailedWasCalled)
+}
+
+func (p prefixedUI) FailWithUsage(ctxt *cli.Context, cmdName string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ui.FailWithUsage(ctxt, cmdName)
+}
+
+func (p prefixedUI) ConfigFailure(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ui.ConfigFailure(err)
+}
+
+func (p prefixedUI) ShowConfiguration(config configuration.Reader) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ui.ShowConfiguration(config)
+}
+
+func (p prefixedUI) LoadingIndication() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ui.LoadingIndication()
+}
+
+func (p prefixedUI) Wait(duration time.Duration) {
+	p.ui.Wait(duration)
+}
+
+func (p prefixedUI) Table(headers []string) Table {
+	return NewTable(p, headers)
+}
+
+// ProgressBar reports progress in lines, so that it does not get in the way
+// of the output of the UIs it is shared with.
+func (p prefixedUI) ProgressBar() ProgressBar {
+	return NewProgressBar(p, nil)
+}
+
+// LiveView says each frame in turn, as the lines of shared UIs cannot be
+// redrawn in place.
+func (p prefixedUI) LiveView() LiveView {
+	return NewLiveView(p, nil)
+}
@@ -0,0 +1,53 @@
+package terminal_test
+
+import (
+	. "cf/terminal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sync"
+	testassert "testhelpers/assert"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("prefixed UI", func() {
+	var (
+		fakeUI *testterm.FakeUI
+		ui     UI
+	)
+
+	BeforeEach(func() {
+		fakeUI = &testterm.FakeUI{}
+		ui = NewPrefixedUI(fakeUI, "my-app | ", &sync.Mutex{})
+	})
+
+	It("prefixes every line it says", func() {
+		ui.Say("Hello %s\nGoodbye", "world")
+		ui.Ok()
+
+		Expect(fakeUI.Outputs).To(Equal([]string{
+			"my-app | Hello world",
+			"my-app | Goodbye",
+			"my-app | " + SuccessColor("OK"),
+		}))
+	})
+
+	It("prefixes tables", func() {
+		ui.Table([]string{"name", "state"}).Print([][]string{{"web", "running"}})
+
+		testassert.SliceContains(fakeUI.Outputs, testassert.Lines{
+			{"my-app | ", "name", "state"},
+			{"my-app | ", "web", "running"},
+		})
+	})
+
+	It("prefixes failures and panics", func() {
+		testassert.AssertPanic(FailedWasCalled, func() {
+			ui.Failed("oh %s", "no")
+		})
+
+		Expect(fakeUI.Outputs).To(Equal([]string{
+			"my-app | " + FailureColor("FAILED"),
+			"my-app | oh no",
+		}))
+	})
+})
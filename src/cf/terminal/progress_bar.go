@@ -0,0 +1,148 @@
+package terminal
+
+import (
+	"cf/formatters"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth       = 30
+	progressRedrawInterval = 200 * time.Millisecond
+	progressLineInterval   = 5 * time.Second
+)
+
+// ProgressBar shows how much of an upload has been sent. Update can be used
+// directly as a net.ProgressCallback, and Finish is given the error that the
+// upload ended with, if any.
+type ProgressBar interface {
+	Update(sent, total int64)
+	Finish(err error)
+}
+
+type progressBar struct {
+	ui  UI
+	tty io.Writer
+
+	lock       sync.Mutex
+	started    time.Time
+	lastReport time.Time
+	sent       int64
+	total      int64
+}
+
+// NewProgressBar returns a progress bar that is redrawn in place on tty. When
+// tty is nil, progress is reported with a line through ui every few seconds
+// instead, which suits output that is not going to a terminal.
+func NewProgressBar(ui UI, tty io.Writer) ProgressBar {
+	return &progressBar{ui: ui, tty: tty}
+}
+
+func (bar *progressBar) Update(sent, total int64) {
+	bar.lock.Lock()
+	defer bar.lock.Unlock()
+
+	now := time.Now()
+	if bar.started.IsZero() {
+		bar.started = now
+	}
+	bar.sent = sent
+	bar.total = total
+
+	if bar.tty != nil {
+		if now.Sub(bar.lastReport) >= progressRedrawInterval || sent >= total {
+			bar.lastReport = now
+			fmt.Fprintf(bar.tty, "\r%s\033[K", bar.render(now))
+		}
+		return
+	}
+
+	if bar.lastReport.IsZero() {
+		bar.lastReport = now
+	} else if now.Sub(bar.lastReport) >= progressLineInterval {
+		bar.lastReport = now
+		bar.ui.Say(bar.summary(now))
+	}
+}
+
+func (bar *progressBar) Finish(err error) {
+	bar.lock.Lock()
+	defer bar.lock.Unlock()
+
+	if bar.started.IsZero() {
+		return
+	}
+
+	now := time.Now()
+	if bar.tty != nil {
+		fmt.Fprintf(bar.tty, "\r%s\033[K\n", bar.render(now))
+		return
+	}
+
+	elapsed := now.Sub(bar.started)
+	if err != nil {
+		bar.ui.Say("Upload failed after sending %s of %s in %s",
+			formatters.ByteSize(uint64(bar.sent)),
+			formatters.ByteSize(uint64(bar.total)),
+			roundDuration(elapsed))
+		return
+	}
+
+	bar.ui.Say("Uploaded %s in %s (%s/s)",
+		formatters.ByteSize(uint64(bar.sent)),
+		roundDuration(elapsed),
+		formatters.ByteSize(bar.rate(elapsed)))
+}
+
+func (bar *progressBar) render(now time.Time) string {
+	filled := progressBarWidth
+	if bar.total > 0 && bar.sent < bar.total {
+		filled = int(int64(progressBarWidth) * bar.sent / bar.total)
+	}
+
+	meter := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		meter += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("[%s] %s", meter, bar.summary(now))
+}
+
+func (bar *progressBar) summary(now time.Time) string {
+	elapsed := now.Sub(bar.started)
+	rate := bar.rate(elapsed)
+
+	eta := "--"
+	if rate > 0 && bar.total >= bar.sent {
+		eta = roundDuration(time.Duration(uint64(bar.total-bar.sent)/rate) * time.Second).String()
+	}
+
+	return fmt.Sprintf("%3d%%  %s of %s  %s/s  ETA %s",
+		bar.percent(),
+		formatters.ByteSize(uint64(bar.sent)),
+		formatters.ByteSize(uint64(bar.total)),
+		formatters.ByteSize(rate),
+		eta)
+}
+
+func (bar *progressBar) percent() int64 {
+	if bar.total <= 0 || bar.sent >= bar.total {
+		return 100
+	}
+	return 100 * bar.sent / bar.total
+}
+
+// rate is the average number of bytes sent per second.
+func (bar *progressBar) rate(elapsed time.Duration) uint64 {
+	if elapsed < time.Second {
+		return uint64(bar.sent)
+	}
+	return uint64(float64(bar.sent) / elapsed.Seconds())
+}
+
+func roundDuration(duration time.Duration) time.Duration {
+	return (duration + time.Second/2) / time.Second * time.Second
+}
@@ -0,0 +1,90 @@
+package terminal_test
+
+import (
+	"bytes"
+	"cf/errors"
+	. "cf/terminal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	testassert "testhelpers/assert"
+	testterm "testhelpers/terminal"
+)
+
+var _ = Describe("progress bar", func() {
+	var ui *testterm.FakeUI
+
+	BeforeEach(func() {
+		ui = &testterm.FakeUI{}
+	})
+
+	Context("when writing to a terminal", func() {
+		var tty *bytes.Buffer
+
+		BeforeEach(func() {
+			tty = &bytes.Buffer{}
+		})
+
+		It("draws the bar in place with the bytes sent, rate and ETA", func() {
+			bar := NewProgressBar(ui, tty)
+			bar.Update(512, 2048)
+
+			Expect(tty.String()).To(HavePrefix("\r[=======>"))
+			Expect(tty.String()).To(ContainSubstring(" 25%  512 of 2K  512/s  ETA 3s"))
+			Expect(ui.Outputs).To(BeEmpty())
+		})
+
+		It("draws the finished bar on its own line", func() {
+			bar := NewProgressBar(ui, tty)
+			bar.Update(512, 2048)
+			bar.Update(2048, 2048)
+			bar.Finish(nil)
+
+			Expect(tty.String()).To(ContainSubstring("[==============================] 100%  2K of 2K"))
+			Expect(tty.String()).To(HaveSuffix("\n"))
+		})
+	})
+
+	Context("when not writing to a terminal", func() {
+		It("says how much was uploaded when it finishes", func() {
+			bar := NewProgressBar(ui, nil)
+			bar.Update(1024, 2048)
+			bar.Update(2048, 2048)
+			bar.Finish(nil)
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Uploaded 2K in 0s"},
+			})
+		})
+	})
+
+	Context("when the upload fails", func() {
+		It("says how much was sent instead of that it was uploaded", func() {
+			bar := NewProgressBar(ui, nil)
+			bar.Update(1024, 2048)
+			bar.Finish(errors.New("connection reset"))
+
+			testassert.SliceContains(ui.Outputs, testassert.Lines{
+				{"Upload failed after sending 1K of 2K in 0s"},
+			})
+			testassert.SliceDoesNotContain(ui.Outputs, testassert.Lines{
+				{"Uploaded"},
+			})
+		})
+
+		It("ends the line of the bar on a terminal", func() {
+			tty := &bytes.Buffer{}
+			bar := NewProgressBar(ui, tty)
+			bar.Update(1024, 2048)
+			bar.Finish(errors.New("connection reset"))
+
+			Expect(tty.String()).To(HaveSuffix("\n"))
+		})
+	})
+
+	It("does not say anything when nothing was uploaded", func() {
+		bar := NewProgressBar(ui, nil)
+		bar.Finish(nil)
+
+		Expect(ui.Outputs).To(BeEmpty())
+	})
+})
@@ -3,20 +3,26 @@ package terminal
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 type Table interface {
 	Print(rows [][]string)
 }
 
+// Sayer is where a table prints its lines, such as a UI or a LiveView.
+type Sayer interface {
+	Say(message string, args ...interface{})
+}
+
 type PrintableTable struct {
-	ui            UI
+	ui            Sayer
 	header        []string
 	headerPrinted bool
 	maxSizes      []int
 }
 
-func NewTable(ui UI, header []string) Table {
+func NewTable(ui Sayer, header []string) Table {
 	return &PrintableTable{
 		ui:       ui,
 		header:   header,
@@ -41,7 +47,7 @@ func (t *PrintableTable) Print(rows [][]string) {
 
 func (t *PrintableTable) calculateMaxSize(row []string) {
 	for index, value := range row {
-		cellLength := len(decolorize(value))
+		cellLength := utf8.RuneCountInString(decolorize(value))
 		if t.maxSizes[index] < cellLength {
 			t.maxSizes[index] = cellLength
 		}
@@ -71,7 +77,7 @@ func (t *PrintableTable) printRow(row []string) {
 func (t *PrintableTable) cellValue(col int, value string) string {
 	padding := ""
 	if col < len(t.header)-1 {
-		padding = strings.Repeat(" ", t.maxSizes[col]-len(decolorize(value)))
+		padding = strings.Repeat(" ", t.maxSizes[col]-utf8.RuneCountInString(decolorize(value)))
 	}
 	return fmt.Sprintf("%s%s   ", value, padding)
 }
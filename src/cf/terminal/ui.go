@@ -35,6 +35,8 @@ type UI interface {
 	LoadingIndication()
 	Wait(duration time.Duration)
 	Table(headers []string) Table
+	ProgressBar() ProgressBar
+	LiveView() LiveView
 }
 
 type terminalUI struct {
@@ -186,6 +188,20 @@ func (ui terminalUI) Table(headers []string) Table {
 	return NewTable(ui, headers)
 }
 
+func (ui terminalUI) ProgressBar() ProgressBar {
+	if isTerminal() {
+		return NewProgressBar(ui, os.Stdout)
+	}
+	return NewProgressBar(ui, nil)
+}
+
+func (ui terminalUI) LiveView() LiveView {
+	if isTerminal() && OsSupportsColors {
+		return NewLiveView(ui, os.Stdout)
+	}
+	return NewLiveView(ui, nil)
+}
+
 func tableColoringFunc(value string, row int, col int) string {
 	switch {
 	case row == 0:
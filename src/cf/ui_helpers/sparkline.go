@@ -0,0 +1,31 @@
+package ui_helpers
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a row of bars scaled between the smallest and
+// largest of them.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	low, high := values[0], values[0]
+	for _, value := range values {
+		if value < low {
+			low = value
+		}
+		if value > high {
+			high = value
+		}
+	}
+
+	bars := make([]rune, len(values))
+	for index, value := range values {
+		level := 0
+		if high > low {
+			level = int((value - low) / (high - low) * float64(len(sparkBars)-1))
+		}
+		bars[index] = sparkBars[level]
+	}
+	return string(bars)
+}
@@ -12,6 +12,7 @@ import (
 //  - `?` matches a single char in a single path component
 //  - `*` matches zero or more chars in a single path component
 //  - `**` matches zero or more chars in zero or more components
+//  - `**/` matches zero or more whole components
 //  - any other sequence matches itself
 type Glob struct {
 	pattern string         // original glob pattern
@@ -30,15 +31,24 @@ var globRe = mustBuildRe(`(` + charPat + `|[\*\?])`)
 //  - `?` matches a single char in a single path component
 //  - `*` matches zero or more chars in a single path component
 //  - `**` matches zero or more chars in zero or more components
+//  - `**/` matches zero or more whole components, so `a/**/b` matches `a/b`
 func translateGlob(pat string) (string, error) {
 	if !globRe.MatchString(pat) {
 		return "", GlobError(pat)
 	}
 
-	outs := make([]string, len(pat))
+	runes := []rune(pat)
+	outs := make([]string, len(runes))
 	i, double := 0, false
-	for _, c := range pat {
+	for _, c := range runes {
 		switch c {
+		case '/':
+			if i >= 2 && runes[i-1] == '*' && runes[i-2] == '*' && (i == 2 || runes[i-3] == '/') {
+				outs[i-2] = `(.*/)?`
+			} else {
+				outs[i] = "/"
+			}
+			double = false
 		default:
 			outs[i] = string(c)
 			double = false
@@ -20,7 +20,10 @@ var globs = [][]string{
 	{"/a*a/b", `^/a[^/]*a/b$`},
 	{"/*a*/b", `^/[^/]*a[^/]*/b$`},
 	{"/**", `^/.*$`},
-	{"/**/a", `^/.*/a$`},
+	{"/**/a", `^/(.*/)?a$`},
+	{"a/**/b", `^a/(.*/)?b$`},
+	{"a/**", `^a/.*$`},
+	{"a**/b", `^a.*/b$`},
 }
 
 var matches = [][]string{
@@ -30,6 +33,9 @@ var matches = [][]string{
 	{"/a**", "/a", "/ab", "/abc", "/a/", "/a/b", "/ab/c"},
 	{`c:\a\b\.d`, `c:\a\b\.d`},
 	{`c:\**\.d`, `c:\a\b\.d`},
+	{"/**/a", "/a", "/b/a", "/b/c/a"},
+	{"a/**/b", "a/b", "a/c/b", "a/c/d/b"},
+	{"a/**", "a/b", "a/b/c"},
 }
 
 var nonMatches = [][]string{
@@ -37,6 +43,9 @@ var nonMatches = [][]string{
 	{"/a?", "/", "/abc", "/a", "/a/"},
 	{"/a*", "/", "/a/", "/ba"},
 	{"/a**", "/", "/ba"},
+	{"/**/a", "/ba", "/b/ca"},
+	{"a/**/b", "ab", "a/cb", "c/a/b"},
+	{"a/**", "a", "ab"},
 }
 
 var _ = Describe("Glob", func() {
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"github.com/codegangsta/cli"
 	"os"
+	"runtime"
 	"runtime/debug"
 	"strings"
 )
@@ -52,6 +53,10 @@ func setupDependencies() (deps *cliDependencies) {
 func main() {
 	defer handlePanics()
 
+	// Go before 1.5 runs goroutines on one CPU unless told otherwise, which
+	// would leave the workers that hash and compress app files taking turns.
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
 	deps := setupDependencies()
 	defer deps.configRepo.Close()
 
@@ -94,6 +99,7 @@ ENVIRONMENT VARIABLES:
    CF_HOME=path/to/config/ override default config directory
    CF_STAGING_TIMEOUT=15 max wait time for buildpack staging, in minutes
    CF_STARTUP_TIMEOUT=5 max wait time for app instance startup, in minutes
+   CF_UPLOAD_RETRIES=3 times to retry an app upload after a network or server error
    CF_TRACE=true - print API request diagnostics to stdout
    CF_TRACE=path/to/trace.log - append API request diagnostics to a log file
    HTTP_PROXY=http://proxy.example.com:8080 - enable HTTP proxying for API requests
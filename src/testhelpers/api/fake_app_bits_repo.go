@@ -1,22 +1,40 @@
 package api
 
 import (
+	"cf/api"
+	"cf/app_files"
 	"cf/errors"
+	"cf/models"
 )
 
 type FakeApplicationBitsRepository struct {
 	UploadedAppGuid string
 	UploadedDir     string
 	UploadAppErr    bool
+	UploadOptions   api.UploadOptions
 
 	CallbackPath      string
 	CallbackZipSize   uint64
 	CallbackFileCount uint64
+
+	MatchedDir         string
+	MatchedOptions     api.MatchOptions
+	MatchFilesToUpload []models.AppFileFields
+	MatchFilesErr      error
+
+	IgnoredFilesDir     string
+	IgnoredFilesOptions app_files.IgnoreOptions
+	IgnoredFilesReturns []app_files.IgnoredFile
+
+	CopiedSourceAppGuid string
+	CopiedTargetAppGuid string
+	CopyBitsErr         error
 }
 
-func (repo *FakeApplicationBitsRepository) UploadApp(appGuid, dir string, cb func(path string, zipSize, fileCount uint64)) (apiErr error) {
+func (repo *FakeApplicationBitsRepository) UploadApp(appGuid, dir string, opts api.UploadOptions, cb func(path string, zipSize, fileCount uint64)) (apiErr error) {
 	repo.UploadedDir = dir
 	repo.UploadedAppGuid = appGuid
+	repo.UploadOptions = opts
 
 	if repo.UploadAppErr {
 		apiErr = errors.New("Error uploading app")
@@ -27,3 +45,21 @@ func (repo *FakeApplicationBitsRepository) UploadApp(appGuid, dir string, cb fun
 
 	return
 }
+
+func (repo *FakeApplicationBitsRepository) MatchFiles(dir string, opts api.MatchOptions) (appFilesToUpload []models.AppFileFields, apiErr error) {
+	repo.MatchedDir = dir
+	repo.MatchedOptions = opts
+	return repo.MatchFilesToUpload, repo.MatchFilesErr
+}
+
+func (repo *FakeApplicationBitsRepository) IgnoredFiles(dir string, ignore app_files.IgnoreOptions) (ignoredFiles []app_files.IgnoredFile, apiErr error) {
+	repo.IgnoredFilesDir = dir
+	repo.IgnoredFilesOptions = ignore
+	return repo.IgnoredFilesReturns, nil
+}
+
+func (repo *FakeApplicationBitsRepository) CopyBits(sourceAppGuid, targetAppGuid string) (apiErr error) {
+	repo.CopiedSourceAppGuid = sourceAppGuid
+	repo.CopiedTargetAppGuid = targetAppGuid
+	return repo.CopyBitsErr
+}
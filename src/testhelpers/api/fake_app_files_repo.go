@@ -1,9 +1,27 @@
 package api
 
+import (
+	"cf/api"
+	"cf/models"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
 type FakeAppFilesRepo struct {
 	AppGuid  string
 	Path     string
 	FileList string
+
+	// InstanceFiles are the contents of the files in an app instance by
+	// their path, such as "app/logs/app.log".
+	InstanceFiles    map[string]string
+	Instances        []int
+	ListedDirs       []string
+	DownloadedPaths  []string
+	DownloadResumes  []bool
+	DownloadFileErrs map[string]error
 }
 
 func (repo *FakeAppFilesRepo) ListFiles(appGuid, path string) (files string, apiErr error) {
@@ -14,3 +32,79 @@ func (repo *FakeAppFilesRepo) ListFiles(appGuid, path string) (files string, api
 
 	return
 }
+
+func (repo *FakeAppFilesRepo) ListInstanceDir(appGuid string, instance int, dir string) (entries []models.AppInstanceFile, apiErr error) {
+	repo.AppGuid = appGuid
+	repo.Instances = append(repo.Instances, instance)
+	repo.ListedDirs = append(repo.ListedDirs, dir)
+
+	prefix := strings.Trim(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	dirs := map[string]bool{}
+	names := []string{}
+	for path := range repo.InstanceFiles {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(path, prefix)
+		if slash := strings.Index(name, "/"); slash >= 0 {
+			name = name[:slash]
+			if dirs[name] {
+				continue
+			}
+			dirs[name] = true
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entries = append(entries, models.AppInstanceFile{Name: name, IsDir: dirs[name]})
+	}
+	return
+}
+
+func (repo *FakeAppFilesRepo) DownloadFile(appGuid string, instance int, path, localPath string, resume bool) (written int64, resumed bool, apiErr error) {
+	repo.AppGuid = appGuid
+	repo.Instances = append(repo.Instances, instance)
+	repo.DownloadedPaths = append(repo.DownloadedPaths, path)
+	repo.DownloadResumes = append(repo.DownloadResumes, resume)
+
+	apiErr = repo.DownloadFileErrs[path]
+	if apiErr != nil {
+		return
+	}
+
+	contents := repo.InstanceFiles[strings.Trim(path, "/")]
+	partPath := localPath + api.PartialDownloadSuffix
+	if resume {
+		existing, err := ioutil.ReadFile(partPath)
+		if err == nil && len(existing) <= len(contents) {
+			contents = contents[len(existing):]
+			resumed = true
+		}
+	}
+
+	file, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		apiErr = err
+		return
+	}
+	if !resumed {
+		file.Truncate(0)
+	}
+	n, err := file.WriteString(contents)
+	file.Close()
+	if err != nil {
+		apiErr = err
+		return
+	}
+	written = int64(n)
+
+	apiErr = os.Rename(partPath, localPath)
+	return
+}
@@ -10,6 +10,10 @@ type FakeAppInstancesRepo struct {
 	GetInstancesAppGuid    string
 	GetInstancesResponses  [][]models.AppInstanceFields
 	GetInstancesErrorCodes []string
+
+	DeleteInstanceAppGuid string
+	DeleteInstanceIndexes []int
+	DeleteInstanceErr     error
 }
 
 func (repo *FakeAppInstancesRepo) GetInstances(appGuid string) (instances []models.AppInstanceFields, apiErr error) {
@@ -31,3 +35,9 @@ func (repo *FakeAppInstancesRepo) GetInstances(appGuid string) (instances []mode
 
 	return
 }
+
+func (repo *FakeAppInstancesRepo) DeleteInstance(appGuid string, index int) (apiErr error) {
+	repo.DeleteInstanceAppGuid = appGuid
+	repo.DeleteInstanceIndexes = append(repo.DeleteInstanceIndexes, index)
+	return repo.DeleteInstanceErr
+}
@@ -15,22 +15,66 @@ type FakeApplicationRepository struct {
 		App   models.Application
 		Error error
 	}
+	// ReadResponses, when there are any, are returned by successive calls to
+	// Read instead of ReadReturns.App.
+	ReadResponses []models.Application
+	// ReadAppsByName, when it is not nil, is where Read looks apps up by
+	// name, failing with a ModelNotFoundError for the ones it does not hold.
+	ReadAppsByName map[string]models.Application
+
+	ReadFromSpaceArgs struct {
+		Name      string
+		SpaceGuid string
+	}
+	ReadFromSpaceReturns struct {
+		App   models.Application
+		Error error
+	}
 
 	CreateAppParams []models.AppParams
 
 	UpdateParams    models.AppParams
+	AllUpdateParams []models.AppParams
 	UpdateAppGuid   string
+	UpdateAppGuids  []string
 	UpdateAppResult models.Application
 	UpdateErr       bool
+	// UpdateErrorsByGuid makes updates of only some apps fail.
+	UpdateErrorsByGuid map[string]error
+
+	RestageAppGuid   string
+	RestageAppResult models.Application
+	RestageErr       error
 
-	DeletedAppGuid string
+	DeletedAppGuid     string
+	DeletedAppGuids    []string
+	DeleteErrorsByGuid map[string]error
 }
 
 func (repo *FakeApplicationRepository) Read(name string) (app models.Application, apiErr error) {
 	repo.ReadArgs.Name = name
+	if repo.ReadAppsByName != nil {
+		var found bool
+		app, found = repo.ReadAppsByName[name]
+		if !found {
+			apiErr = errors.NewModelNotFoundError("App", name)
+		}
+		return
+	}
+	if len(repo.ReadResponses) > 0 {
+		app = repo.ReadResponses[0]
+		repo.ReadResponses = repo.ReadResponses[1:]
+		return app, repo.ReadReturns.Error
+	}
 	return repo.ReadReturns.App, repo.ReadReturns.Error
 }
 
+func (repo *FakeApplicationRepository) ReadFromSpace(name string, spaceGuid string) (app models.Application, apiErr error) {
+	repo.ReadFromSpaceArgs.Name = name
+	repo.ReadFromSpaceArgs.SpaceGuid = spaceGuid
+	return repo.ReadFromSpaceReturns.App, repo.ReadFromSpaceReturns.Error
+}
+
 func (repo *FakeApplicationRepository) CreatedAppParams() (params models.AppParams) {
 	if len(repo.CreateAppParams) > 0 {
 		params = repo.CreateAppParams[0]
@@ -77,15 +121,26 @@ func (repo *FakeApplicationRepository) Create(params models.AppParams) (resultAp
 
 func (repo *FakeApplicationRepository) Update(appGuid string, params models.AppParams) (updatedApp models.Application, apiErr error) {
 	repo.UpdateAppGuid = appGuid
+	repo.UpdateAppGuids = append(repo.UpdateAppGuids, appGuid)
 	repo.UpdateParams = params
+	repo.AllUpdateParams = append(repo.AllUpdateParams, params)
 	updatedApp = repo.UpdateAppResult
 	if repo.UpdateErr {
 		apiErr = errors.New("Error updating app.")
 	}
+	if err, found := repo.UpdateErrorsByGuid[appGuid]; found {
+		apiErr = err
+	}
 	return
 }
 
+func (repo *FakeApplicationRepository) Restage(appGuid string) (restagedApp models.Application, apiErr error) {
+	repo.RestageAppGuid = appGuid
+	return repo.RestageAppResult, repo.RestageErr
+}
+
 func (repo *FakeApplicationRepository) Delete(appGuid string) (apiErr error) {
 	repo.DeletedAppGuid = appGuid
-	return
+	repo.DeletedAppGuids = append(repo.DeletedAppGuids, appGuid)
+	return repo.DeleteErrorsByGuid[appGuid]
 }
@@ -3,19 +3,22 @@ package api
 import (
 	"cf/errors"
 	"cf/models"
+	"cf/net"
 )
 
 type FakeBuildpackBitsRepository struct {
 	UploadBuildpackErr         bool
 	UploadBuildpackApiResponse error
 	UploadBuildpackPath        string
+	UploadBuildpackProgress    net.ProgressCallback
 }
 
-func (repo *FakeBuildpackBitsRepository) UploadBuildpack(buildpack models.Buildpack, dir string) error {
+func (repo *FakeBuildpackBitsRepository) UploadBuildpack(buildpack models.Buildpack, dir string, progress net.ProgressCallback) error {
 	if repo.UploadBuildpackErr {
 		return errors.New("Invalid buildpack")
 	}
 
 	repo.UploadBuildpackPath = dir
+	repo.UploadBuildpackProgress = progress
 	return repo.UploadBuildpackApiResponse
 }
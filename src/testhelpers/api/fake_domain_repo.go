@@ -14,6 +14,7 @@ type FakeDomainRepository struct {
 	FindByNameInOrgGuid        string
 	FindByNameInOrgDomain      models.DomainFields
 	FindByNameInOrgApiResponse error
+	FindByNameInOrgDomainsMap  map[string]models.DomainFields
 
 	FindByNameName     string
 	FindByNameDomain   models.DomainFields
@@ -59,6 +60,14 @@ func (repo *FakeDomainRepository) FindByNameInOrg(name string, owningOrgGuid str
 	repo.FindByNameInOrgGuid = owningOrgGuid
 	domain = repo.FindByNameInOrgDomain
 	apiErr = repo.FindByNameInOrgApiResponse
+
+	if repo.FindByNameInOrgDomainsMap != nil {
+		var found bool
+		domain, found = repo.FindByNameInOrgDomainsMap[name]
+		if !found {
+			apiErr = errors.NewModelNotFoundError("Domain", name)
+		}
+	}
 	return
 }
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"cf/api"
 	"github.com/cloudfoundry/loggregatorlib/logmessage"
 	"time"
 )
@@ -14,6 +15,8 @@ type FakeLogsRepository struct {
 	TailLogErr      error
 
 	TailLogStopCalled bool
+
+	Clones int
 }
 
 func (l *FakeLogsRepository) RecentLogsFor(appGuid string) ([]*logmessage.LogMessage, error) {
@@ -42,6 +45,13 @@ func (l *FakeLogsRepository) Close() {
 	l.TailLogStopCalled = true
 }
 
+// Clone counts the clones, and returns the repository itself so that tests
+// can look at what its clones were asked to do.
+func (l *FakeLogsRepository) Clone() api.LogsRepository {
+	l.Clones++
+	return l
+}
+
 func (l *FakeLogsRepository) logsFor(appGuid string, logMessages []*logmessage.LogMessage, onConnect func(), logChan chan *logmessage.LogMessage, stopLoggingChan chan bool) {
 	l.AppLoggedGuid = appGuid
 	onConnect()
@@ -12,9 +12,11 @@ type FakeRouteRepository struct {
 	FindByHostAndDomainErr      bool
 	FindByHostAndDomainNotFound bool
 
-	CreatedHost       string
-	CreatedDomainGuid string
-	CreatedRoute      models.Route
+	CreatedHost        string
+	CreatedDomainGuid  string
+	CreatedRoute       models.Route
+	CreatedHosts       []string
+	CreatedDomainGuids []string
 
 	CreateInSpaceHost         string
 	CreateInSpaceDomainGuid   string
@@ -22,12 +24,14 @@ type FakeRouteRepository struct {
 	CreateInSpaceCreatedRoute models.Route
 	CreateInSpaceErr          bool
 
-	BindErr        error
-	BoundRouteGuid string
-	BoundAppGuid   string
+	BindErr         error
+	BoundRouteGuid  string
+	BoundAppGuid    string
+	BoundRouteGuids []string
 
-	UnboundRouteGuid string
-	UnboundAppGuid   string
+	UnboundRouteGuid  string
+	UnboundAppGuid    string
+	UnboundRouteGuids []string
 
 	ListErr bool
 	Routes  []models.Route
@@ -68,6 +72,8 @@ func (repo *FakeRouteRepository) FindByHostAndDomain(host, domain string) (route
 func (repo *FakeRouteRepository) Create(host, domainGuid string) (createdRoute models.Route, apiErr error) {
 	repo.CreatedHost = host
 	repo.CreatedDomainGuid = domainGuid
+	repo.CreatedHosts = append(repo.CreatedHosts, host)
+	repo.CreatedDomainGuids = append(repo.CreatedDomainGuids, domainGuid)
 
 	createdRoute.Guid = host + "-route-guid"
 
@@ -91,12 +97,14 @@ func (repo *FakeRouteRepository) CreateInSpace(host, domainGuid, spaceGuid strin
 func (repo *FakeRouteRepository) Bind(routeGuid, appGuid string) (apiErr error) {
 	repo.BoundRouteGuid = routeGuid
 	repo.BoundAppGuid = appGuid
+	repo.BoundRouteGuids = append(repo.BoundRouteGuids, routeGuid)
 	return repo.BindErr
 }
 
 func (repo *FakeRouteRepository) Unbind(routeGuid, appGuid string) (apiErr error) {
 	repo.UnboundRouteGuid = routeGuid
 	repo.UnboundAppGuid = appGuid
+	repo.UnboundRouteGuids = append(repo.UnboundRouteGuids, routeGuid)
 	return
 }
 
@@ -6,6 +6,10 @@ type FakeStackRepository struct {
 	FindByNameStack models.Stack
 	FindByNameName  string
 
+	FindByGuidGuid  string
+	FindByGuidStack models.Stack
+	FindByGuidErr   error
+
 	FindAllStacks []models.Stack
 }
 
@@ -16,6 +20,11 @@ func (repo *FakeStackRepository) FindByName(name string) (stack models.Stack, ap
 	return
 }
 
+func (repo *FakeStackRepository) FindByGuid(guid string) (stack models.Stack, apiErr error) {
+	repo.FindByGuidGuid = guid
+	return repo.FindByGuidStack, repo.FindByGuidErr
+}
+
 func (repo *FakeStackRepository) FindAll() (stacks []models.Stack, apiErr error) {
 	stacks = repo.FindAllStacks
 	return
@@ -0,0 +1,26 @@
+package clock
+
+import (
+	"os"
+	"time"
+)
+
+type FakeClock struct {
+	Time           time.Time
+	TickerInterval time.Duration
+	Ticks          chan time.Time
+	Signals        chan os.Signal
+}
+
+func (clock *FakeClock) Now() time.Time {
+	return clock.Time
+}
+
+func (clock *FakeClock) Ticker(interval time.Duration) (<-chan time.Time, func()) {
+	clock.TickerInterval = interval
+	return clock.Ticks, func() {}
+}
+
+func (clock *FakeClock) Interrupts() (<-chan os.Signal, func()) {
+	return clock.Signals, func() {}
+}
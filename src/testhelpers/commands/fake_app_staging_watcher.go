@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"cf/models"
+)
+
+type FakeAppStagingWatcher struct {
+	StagingLogPath string
+	WatchedApp     models.Application
+	WatchErr       error
+}
+
+func (watcher *FakeAppStagingWatcher) ApplicationWatchStaging(app models.Application, start func(app models.Application) (models.Application, error)) (updatedApp models.Application, err error) {
+	watcher.WatchedApp = app
+	updatedApp, err = start(app)
+	if err == nil {
+		err = watcher.WatchErr
+	}
+	return
+}
+
+func (watcher *FakeAppStagingWatcher) SetStagingLogPath(path string) {
+	watcher.StagingLogPath = path
+}
@@ -5,13 +5,27 @@ import (
 )
 
 type FakeAppStarter struct {
-	AppToStart models.Application
-	Timeout    int
+	StagingLogPath string
+	AppToStart     models.Application
+	Timeout        int
+	StartErr       error
+
+	// StartErrors, when there are any, are returned by successive calls
+	// instead of StartErr.
+	StartErrors []error
+	StartedApps []models.Application
 }
 
 func (starter *FakeAppStarter) ApplicationStart(appToStart models.Application) (startedApp models.Application, err error) {
 	starter.AppToStart = appToStart
+	starter.StartedApps = append(starter.StartedApps, appToStart)
 	startedApp = appToStart
+	err = starter.StartErr
+
+	if len(starter.StartErrors) > 0 {
+		err = starter.StartErrors[0]
+		starter.StartErrors = starter.StartErrors[1:]
+	}
 	return
 }
 
@@ -24,3 +38,7 @@ func (starter *FakeAppStarter) ApplicationStartWithBuildpack(app models.Applicat
 	startedApp = app
 	return
 }
+
+func (starter *FakeAppStarter) SetStagingLogPath(path string) {
+	starter.StagingLogPath = path
+}
@@ -0,0 +1,22 @@
+package hooks
+
+type FakeRunner struct {
+	Commands []string
+	Dirs     []string
+	Envs     []map[string]string
+
+	// Output is what each command writes, and Errors are what they fail with
+	Output map[string][]string
+	Errors map[string]error
+}
+
+func (runner *FakeRunner) Run(command, dir string, env map[string]string, onOutput func(line string)) error {
+	runner.Commands = append(runner.Commands, command)
+	runner.Dirs = append(runner.Dirs, dir)
+	runner.Envs = append(runner.Envs, env)
+
+	for _, line := range runner.Output[command] {
+		onOutput(line)
+	}
+	return runner.Errors[command]
+}
@@ -12,6 +12,20 @@ type FakeManifestRepository struct {
 		Manifest *manifest.Manifest
 		Error    error
 	}
+
+	WriteManifestArgs struct {
+		Manifest *manifest.Manifest
+	}
+	WriteManifestReturns struct {
+		Error error
+	}
+
+	ValidateManifestArgs struct {
+		Path string
+	}
+	ValidateManifestReturns struct {
+		Errors []error
+	}
 }
 
 func (repo *FakeManifestRepository) ReadManifest(inputPath string) (m *manifest.Manifest, err error) {
@@ -25,3 +39,13 @@ func (repo *FakeManifestRepository) ReadManifest(inputPath string) (m *manifest.
 	err = repo.ReadManifestReturns.Error
 	return
 }
+
+func (repo *FakeManifestRepository) WriteManifest(m *manifest.Manifest) error {
+	repo.WriteManifestArgs.Manifest = m
+	return repo.WriteManifestReturns.Error
+}
+
+func (repo *FakeManifestRepository) ValidateManifest(inputPath string) []error {
+	repo.ValidateManifestArgs.Path = inputPath
+	return repo.ValidateManifestReturns.Errors
+}
@@ -20,6 +20,8 @@ type FakeUI struct {
 	FailedWithUsage            bool
 	FailedWithUsageCommandName string
 	ShowConfigurationCalled    bool
+	ProgressBars               []*FakeProgressBar
+	LiveViews                  []*FakeLiveView
 }
 
 func (ui *FakeUI) PrintPaginator(rows []string, err error) {
@@ -136,3 +138,58 @@ func (c FakeUI) Wait(duration time.Duration) {
 func (ui *FakeUI) Table(headers []string) term.Table {
 	return term.NewTable(ui, headers)
 }
+
+func (ui *FakeUI) ProgressBar() term.ProgressBar {
+	bar := &FakeProgressBar{}
+	ui.ProgressBars = append(ui.ProgressBars, bar)
+	return bar
+}
+
+func (ui *FakeUI) LiveView() term.LiveView {
+	view := &FakeLiveView{}
+	ui.LiveViews = append(ui.LiveViews, view)
+	return view
+}
+
+type FakeLiveView struct {
+	Frames [][]string
+	Closed bool
+	lines  []string
+}
+
+func (view *FakeLiveView) Say(message string, args ...interface{}) {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	view.lines = append(view.lines, strings.Split(message, "\n")...)
+}
+
+func (view *FakeLiveView) Table(headers []string) term.Table {
+	return term.NewTable(view, headers)
+}
+
+func (view *FakeLiveView) Draw() {
+	view.Frames = append(view.Frames, view.lines)
+	view.lines = nil
+}
+
+func (view *FakeLiveView) Close() {
+	view.Closed = true
+}
+
+type FakeProgressBar struct {
+	Sent      []int64
+	Total     int64
+	Finished  bool
+	FinishErr error
+}
+
+func (bar *FakeProgressBar) Update(sent, total int64) {
+	bar.Sent = append(bar.Sent, sent)
+	bar.Total = total
+}
+
+func (bar *FakeProgressBar) Finish(err error) {
+	bar.Finished = true
+	bar.FinishErr = err
+}